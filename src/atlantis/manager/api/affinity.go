@@ -0,0 +1,58 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	. "atlantis/manager/rpc/types"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func ListAffinityRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerAffinityRuleArg{ManagerAuthArg: auth, App: vars["App"]}
+	var reply ManagerAffinityRuleReply
+	err := manager.ListAffinityRules(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Rules": reply.Rules}, err))
+}
+
+func SetAffinityRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerAffinityRuleArg{ManagerAuthArg: auth, App: vars["App"], Rule: AffinityRule{App: vars["With"]}}
+	for name, value := range map[string]*bool{"Anti": &arg.Rule.Anti, "Hard": &arg.Rule.Hard} {
+		if r.FormValue(name) == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(r.FormValue(name))
+		if err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+		*value = parsed
+	}
+	var reply ManagerAffinityRuleReply
+	err := manager.SetAffinityRule(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Rules": reply.Rules}, err))
+}
+
+func RemoveAffinityRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerAffinityRuleArg{auth, vars["App"], AffinityRule{App: vars["With"]}, true}
+	var reply ManagerAffinityRuleReply
+	err := manager.SetAffinityRule(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Rules": reply.Rules}, err))
+}
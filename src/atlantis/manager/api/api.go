@@ -71,6 +71,7 @@ func Init(listenAddr string) error {
 	// Task Management
 	gmux.HandleFunc("/tasks", ListTaskIDs).Methods("GET")
 	gmux.HandleFunc("/tasks/{ID}", GetTaskStatus).Methods("GET")
+	gmux.HandleFunc("/tasks/{ID}", CancelTask).Methods("DELETE")
 
 	// Manager Management
 	gmux.HandleFunc("/health", Health).Methods("GET")
@@ -89,6 +90,7 @@ func Init(listenAddr string) error {
 	gmux.HandleFunc("/supervisors", ListSupervisors).Methods("GET")
 	gmux.HandleFunc("/supervisors/{Host}", RegisterSupervisor).Methods("PUT")
 	gmux.HandleFunc("/supervisors/{Host}", UnregisterSupervisor).Methods("DELETE")
+	gmux.HandleFunc("/supervisors/{Host}/labels", LabelSupervisor).Methods("PUT")
 
 	// Router Management
 	gmux.HandleFunc("/routers", ListRouters).Methods("GET")
@@ -112,6 +114,15 @@ func Init(listenAddr string) error {
 	gmux.HandleFunc("/apps/{App}/depender/{Depender}/env/{Env}", AddDependerEnvDataForDependerApp).Methods("PUT")
 	gmux.HandleFunc("/apps/{App}/depender/{Depender}/env/{Env}", GetDependerEnvDataForDependerApp).Methods("GET")
 	gmux.HandleFunc("/apps/{App}/depender/{Depender}/env/{Env}", RemoveDependerEnvDataForDependerApp).Methods("DELETE")
+	gmux.HandleFunc("/apps/{App}/envs/{Env}/canary", CanarySetWeight).Methods("PUT")
+	gmux.HandleFunc("/apps/{App}/envs/{Env}/history", ListDeployHistory).Methods("GET")
+	gmux.HandleFunc("/apps/{App}/envs/{Env}/rollback", Rollback).Methods("POST")
+	gmux.HandleFunc("/apps/{App}/envs/{Env}/promote", Promote).Methods("POST")
+	gmux.HandleFunc("/apps/{App}/envs/{Env}/promotion_rule", SetPromotionRule).Methods("PUT")
+	gmux.HandleFunc("/apps/{App}/promotion_rules", ListPromotionRules).Methods("GET")
+	gmux.HandleFunc("/apps/{App}/affinity_rules", ListAffinityRules).Methods("GET")
+	gmux.HandleFunc("/apps/{App}/affinity_rules/{With}", SetAffinityRule).Methods("PUT")
+	gmux.HandleFunc("/apps/{App}/affinity_rules/{With}", RemoveAffinityRule).Methods("DELETE")
 
 	// Container Health
 	gmux.HandleFunc("/healthz", ContainerHealthzGet).Methods("GET")
@@ -145,6 +156,10 @@ func Init(listenAddr string) error {
 	// Instance Management
 	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}/envs/{Env}/containers", ListContainers).Methods("GET")
 	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}/envs/{Env}/containers", Deploy).Methods("POST")
+	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}/envs/{Env}/manifest", DeployManifest).Methods("POST")
+	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}/envs/{Env}/schedule", ScheduleDeploy).Methods("POST")
+	gmux.HandleFunc("/instances/envs/{Env}/release", ReleaseBundle).Methods("POST")
+	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}/envs/{Env}/scale", Scale).Methods("PUT")
 	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}/envs/{Env}", Teardown).Methods("DELETE")
 	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}/envs", DeployListEnvs).Methods("GET")
 	gmux.HandleFunc("/instances/apps/{App}/shas/{Sha}", Teardown).Methods("DELETE")
@@ -158,6 +173,11 @@ func Init(listenAddr string) error {
 	gmux.HandleFunc("/instances/{ID}", TeardownContainerID).Methods("DELETE")
 	gmux.HandleFunc("/instances", ListContainers).Methods("GET")
 	gmux.HandleFunc("/instances", TeardownContainers).Methods("DELETE")
+	gmux.HandleFunc("/deploy_requests/{ID}/approve", ApproveDeploy).Methods("POST")
+	gmux.HandleFunc("/deploy_requests/{ID}/deny", DenyDeploy).Methods("POST")
+	gmux.HandleFunc("/deploy_requests", ListDeployRequests).Methods("GET")
+	gmux.HandleFunc("/scheduled_deploys/{ID}", CancelScheduledDeploy).Methods("DELETE")
+	gmux.HandleFunc("/scheduled_deploys", ListScheduledDeploys).Methods("GET")
 
 	// LDAP Management
 	gmux.HandleFunc("/users/{User}", GetPermissions).Methods("GET")
@@ -176,8 +196,17 @@ func Init(listenAddr string) error {
 	gmux.HandleFunc("/teams/{Team}", DeleteTeam).Methods("DELETE")
 	gmux.HandleFunc("/teams", ListTeams).Methods("GET")
 
+	// Webhooks
+	gmux.HandleFunc("/webhooks/{ID}", DeleteWebhook).Methods("DELETE")
+	gmux.HandleFunc("/webhooks", ListWebhooks).Methods("GET")
+	gmux.HandleFunc("/webhooks", AddWebhook).Methods("POST")
+
 	// Environment Management
 	gmux.HandleFunc("/envs/{Env}/app/{App}/resolve/{DepNames}", ResolveDeps).Methods("GET")
+	gmux.HandleFunc("/envs/{Env}/protected", ProtectEnv).Methods("PUT")
+	gmux.HandleFunc("/envs/{Env}/freezes", ListFreezes).Methods("GET")
+	gmux.HandleFunc("/envs/{Env}/freezes/{Name}", AddFreeze).Methods("PUT")
+	gmux.HandleFunc("/envs/{Env}/freezes/{Name}", DeleteFreeze).Methods("DELETE")
 	gmux.HandleFunc("/envs/{Env}", UpdateEnv).Methods("PUT")
 	gmux.HandleFunc("/envs/{Env}", DeleteEnv).Methods("DELETE")
 	gmux.HandleFunc("/envs", ListEnvs).Methods("GET")
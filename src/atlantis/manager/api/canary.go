@@ -0,0 +1,40 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	. "atlantis/manager/rpc/types"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func CanarySetWeight(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	weight, err := strconv.ParseUint(r.FormValue("Weight"), 10, 0)
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	arg := ManagerCanaryArg{
+		ManagerAuthArg: auth,
+		App:            vars["App"],
+		Sha:            r.FormValue("Sha"),
+		Env:            vars["Env"],
+		Weight:         uint(weight),
+	}
+	var reply ManagerCanaryReply
+	err = manager.CanarySetWeight(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Canary": reply.Canary}, err))
+}
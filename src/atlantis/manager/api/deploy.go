@@ -13,9 +13,12 @@ package api
 
 import (
 	. "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
 	. "atlantis/manager/rpc/types"
 	"fmt"
 	"github.com/gorilla/mux"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -35,30 +38,83 @@ func ResolveDeps(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Deps": reply.Deps}, err))
 }
 
-func Deploy(w http.ResponseWriter, r *http.Request) {
+// parses the deploy in the url and form of r. shared by Deploy and ScheduleDeploy.
+func deployArg(r *http.Request) (ManagerDeployArg, error) {
 	vars := mux.Vars(r)
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
 	cpushares, err := strconv.ParseUint(r.FormValue("CPUShares"), 10, 0)
 	if err != nil {
-		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
-		return
+		return ManagerDeployArg{}, err
 	}
 	memlimit, err := strconv.ParseUint(r.FormValue("MemoryLimit"), 10, 0)
 	if err != nil {
-		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
-		return
+		return ManagerDeployArg{}, err
 	}
 	instances, err := strconv.ParseUint(r.FormValue("Instances"), 10, 0)
 	if err != nil {
-		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
-		return
+		return ManagerDeployArg{}, err
 	}
 	dev, err := strconv.ParseBool(r.FormValue("Dev"))
 	if err != nil {
-		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
-		return
+		return ManagerDeployArg{}, err
+	}
+	replace := false
+	if r.FormValue("Replace") != "" {
+		if replace, err = strconv.ParseBool(r.FormValue("Replace")); err != nil {
+			return ManagerDeployArg{}, err
+		}
+	}
+	drainTime := uint64(DefaultDrainTime)
+	if r.FormValue("DrainTime") != "" {
+		if drainTime, err = strconv.ParseUint(r.FormValue("DrainTime"), 10, 0); err != nil {
+			return ManagerDeployArg{}, err
+		}
+	}
+	watchTime := uint64(0)
+	if r.FormValue("WatchTime") != "" {
+		if watchTime, err = strconv.ParseUint(r.FormValue("WatchTime"), 10, 0); err != nil {
+			return ManagerDeployArg{}, err
+		}
+	}
+	dryRun := false
+	if r.FormValue("DryRun") != "" {
+		if dryRun, err = strconv.ParseBool(r.FormValue("DryRun")); err != nil {
+			return ManagerDeployArg{}, err
+		}
+	}
+	maxFailPct := uint64(DefaultMaxFailPct)
+	if r.FormValue("MaxFailPct") != "" {
+		if maxFailPct, err = strconv.ParseUint(r.FormValue("MaxFailPct"), 10, 0); err != nil {
+			return ManagerDeployArg{}, err
+		}
 	}
-	dArg := ManagerDeployArg{
+	zones, err := helper.ParseZoneInstances([]string{r.FormValue("Zones")})
+	if err != nil {
+		return ManagerDeployArg{}, err
+	}
+	minZones := uint64(0)
+	if r.FormValue("MinZones") != "" {
+		if minZones, err = strconv.ParseUint(r.FormValue("MinZones"), 10, 0); err != nil {
+			return ManagerDeployArg{}, err
+		}
+	}
+	requiredZones := []string{}
+	if r.FormValue("RequiredZones") != "" {
+		requiredZones = strings.Split(r.FormValue("RequiredZones"), ",")
+	}
+	onlyMissing := false
+	if r.FormValue("OnlyMissing") != "" {
+		if onlyMissing, err = strconv.ParseBool(r.FormValue("OnlyMissing")); err != nil {
+			return ManagerDeployArg{}, err
+		}
+	}
+	rebuild := false
+	if r.FormValue("Rebuild") != "" {
+		if rebuild, err = strconv.ParseBool(r.FormValue("Rebuild")); err != nil {
+			return ManagerDeployArg{}, err
+		}
+	}
+	return ManagerDeployArg{
 		ManagerAuthArg: auth,
 		App:            vars["App"],
 		Sha:            vars["Sha"],
@@ -67,12 +123,175 @@ func Deploy(w http.ResponseWriter, r *http.Request) {
 		CPUShares:      uint(cpushares),
 		MemoryLimit:    uint(memlimit),
 		Dev:            bool(dev),
+		Replace:        replace,
+		DrainTime:      uint(drainTime),
+		WatchTime:      uint(watchTime),
+		MaxFailPct:     uint(maxFailPct),
+		DryRun:         dryRun,
+		Zones:          zones,
+		MinZones:       uint(minZones),
+		RequiredZones:  requiredZones,
+		OnlyMissing:    onlyMissing,
+		Rebuild:        rebuild,
+		OverrideFreeze: r.FormValue("OverrideFreeze"),
+		Placement:      r.FormValue("Placement"),
+		Selector:       r.FormValue("Selector"),
+	}, nil
+}
+
+func Deploy(w http.ResponseWriter, r *http.Request) {
+	dArg, err := deployArg(r)
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
 	}
 	var reply AsyncReply
 	err = manager.Deploy(dArg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
 }
 
+// Time is the unix time to start the deploy at
+func ScheduleDeploy(w http.ResponseWriter, r *http.Request) {
+	dArg, err := deployArg(r)
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	at, err := strconv.ParseInt(r.FormValue("Time"), 10, 64)
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	var reply ManagerScheduledDeployReply
+	err = manager.ScheduleDeploy(ManagerScheduleDeployArg{dArg, at}, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Schedule": reply.Schedule}, err))
+}
+
+func CancelScheduledDeploy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerScheduledDeployArg{auth, vars["ID"]}
+	var reply ManagerScheduledDeployReply
+	err := manager.CancelScheduledDeploy(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Schedule": reply.Schedule}, err))
+}
+
+func ListScheduledDeploys(w http.ResponseWriter, r *http.Request) {
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	all := false
+	var err error
+	if r.FormValue("All") != "" {
+		if all, err = strconv.ParseBool(r.FormValue("All")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	arg := ManagerListScheduledDeploysArg{ManagerAuthArg: auth, App: r.FormValue("App"), Env: r.FormValue("Env"),
+		All: all}
+	var reply ManagerListScheduledDeploysReply
+	err = manager.ListScheduledDeploys(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Schedules": reply.Schedules},
+		err))
+}
+
+func ApproveDeploy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerDeployRequestArg{ManagerAuthArg: auth, ID: vars["ID"]}
+	var reply AsyncReply
+	err := manager.ApproveDeploy(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
+}
+
+func DenyDeploy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerDeployRequestArg{ManagerAuthArg: auth, ID: vars["ID"], Reason: r.FormValue("Reason")}
+	var reply ManagerDeployRequestReply
+	err := manager.DenyDeploy(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Request": reply.Request}, err))
+}
+
+func ListDeployRequests(w http.ResponseWriter, r *http.Request) {
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	all := false
+	var err error
+	if r.FormValue("All") != "" {
+		if all, err = strconv.ParseBool(r.FormValue("All")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	arg := ManagerListDeployRequestsArg{ManagerAuthArg: auth, App: r.FormValue("App"), Env: r.FormValue("Env"),
+		All: all}
+	var reply ManagerListDeployRequestsReply
+	err = manager.ListDeployRequests(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Requests": reply.Requests}, err))
+}
+
+// the manifest is the body of the request, everything else comes from the url
+func DeployManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	auth := ManagerAuthArg{query.Get("User"), "", query.Get("Secret")}
+	manifest, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	instances := uint64(0)
+	if query.Get("Instances") != "" {
+		if instances, err = strconv.ParseUint(query.Get("Instances"), 10, 0); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	zones, err := helper.ParseZoneInstances([]string{query.Get("Zones")})
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	replace := false
+	if query.Get("Replace") != "" {
+		if replace, err = strconv.ParseBool(query.Get("Replace")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	drainTime := uint64(DefaultDrainTime)
+	if query.Get("DrainTime") != "" {
+		if drainTime, err = strconv.ParseUint(query.Get("DrainTime"), 10, 0); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	dryRun := false
+	if query.Get("DryRun") != "" {
+		if dryRun, err = strconv.ParseBool(query.Get("DryRun")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	arg := ManagerDeployManifestArg{
+		ManagerDeployArg: ManagerDeployArg{
+			ManagerAuthArg: auth,
+			App:            vars["App"],
+			Sha:            vars["Sha"],
+			Env:            vars["Env"],
+			Instances:      uint(instances),
+			Replace:        replace,
+			DrainTime:      uint(drainTime),
+			DryRun:         dryRun,
+			Zones:          zones,
+			OverrideFreeze: query.Get("OverrideFreeze"),
+		},
+		Manifest: string(manifest),
+	}
+	var reply AsyncReply
+	err = manager.DeployManifest(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
+}
+
 func DeployContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
@@ -81,8 +300,15 @@ func DeployContainer(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
 		return
 	}
+	dryRun := false
+	if r.FormValue("DryRun") != "" {
+		if dryRun, err = strconv.ParseBool(r.FormValue("DryRun")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
 	ccArg := ManagerDeployContainerArg{ManagerAuthArg: auth, Instances: uint(instances),
-		ContainerID: vars["ID"]}
+		ContainerID: vars["ID"], DryRun: dryRun, OverrideFreeze: r.FormValue("OverrideFreeze")}
 	var reply AsyncReply
 	err = manager.DeployContainer(ccArg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
@@ -95,21 +321,80 @@ func CopyContainer(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		postcopy = 0 // default to noop
 	}
+	dryRun := false
+	if r.FormValue("DryRun") != "" {
+		if dryRun, err = strconv.ParseBool(r.FormValue("DryRun")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
 	ccArg := ManagerCopyContainerArg{
 		ManagerAuthArg: auth,
 		ContainerID:    vars["ID"],
 		ToHost:         r.FormValue("ToHost"),
 		PostCopy:       postcopy,
+		DryRun:         dryRun,
+		OverrideFreeze: r.FormValue("OverrideFreeze"),
 	}
 	var reply AsyncReply
 	err = manager.CopyContainer(ccArg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
 }
 
+func Rollback(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	teardown, err := strconv.ParseBool(r.FormValue("Teardown"))
+	if err != nil {
+		teardown = false // default to leaving the current sha up
+	}
+	arg := ManagerRollbackArg{
+		ManagerAuthArg: auth,
+		App:            vars["App"],
+		Env:            vars["Env"],
+		Sha:            r.FormValue("Sha"),
+		Teardown:       teardown,
+		OverrideFreeze: r.FormValue("OverrideFreeze"),
+	}
+	var reply AsyncReply
+	err = manager.Rollback(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
+}
+
+func Scale(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	instances := uint64(0)
+	var err error
+	if r.FormValue("Instances") != "" {
+		if instances, err = strconv.ParseUint(r.FormValue("Instances"), 10, 0); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	zones, err := helper.ParseZoneInstances([]string{r.FormValue("Zones")})
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	arg := ManagerScaleArg{
+		ManagerAuthArg: auth,
+		App:            vars["App"],
+		Sha:            vars["Sha"],
+		Env:            vars["Env"],
+		Instances:      uint(instances),
+		Zones:          zones,
+		OverrideFreeze: r.FormValue("OverrideFreeze"),
+	}
+	var reply AsyncReply
+	err = manager.Scale(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
+}
+
 func Teardown(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
-	arg := ManagerTeardownArg{auth, vars["App"], vars["Sha"], vars["Env"], "", false}
+	arg := ManagerTeardownArg{auth, vars["App"], vars["Sha"], vars["Env"], "", false, r.FormValue("OverrideFreeze")}
 	var reply AsyncReply
 	err := manager.Teardown(arg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
@@ -118,7 +403,7 @@ func Teardown(w http.ResponseWriter, r *http.Request) {
 func TeardownContainerID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
-	cArg := ManagerTeardownArg{auth, "", "", "", vars["ID"], false}
+	cArg := ManagerTeardownArg{auth, "", "", "", vars["ID"], false, r.FormValue("OverrideFreeze")}
 	var reply AsyncReply
 	err := manager.Teardown(cArg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
@@ -131,7 +416,8 @@ func TeardownContainers(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
 		return
 	}
-	tArg := ManagerTeardownArg{auth, r.FormValue("App"), r.FormValue("Sha"), r.FormValue("Env"), r.FormValue("ContainerID"), all}
+	tArg := ManagerTeardownArg{auth, r.FormValue("App"), r.FormValue("Sha"), r.FormValue("Env"), r.FormValue("ContainerID"), all,
+		r.FormValue("OverrideFreeze")}
 	var reply AsyncReply
 	err = manager.Teardown(tArg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
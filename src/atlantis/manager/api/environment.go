@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"github.com/gorilla/mux"
 	"net/http"
+	"strconv"
 )
 
 func ListEnvs(w http.ResponseWriter, r *http.Request) {
@@ -43,3 +44,64 @@ func DeleteEnv(w http.ResponseWriter, r *http.Request) {
 	err := manager.DeleteEnv(dArg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status}, err))
 }
+
+func ListFreezes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerFreezeArg{ManagerAuthArg: auth, Env: vars["Env"]}
+	var reply ManagerFreezeReply
+	err := manager.ListFreezes(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Freezes": reply.Freezes, "Active": reply.Active,
+		"Status": reply.Status}, err))
+}
+
+func AddFreeze(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	freeze := Freeze{
+		Name:        vars["Name"],
+		Reason:      r.FormValue("Reason"),
+		WeeklyStart: r.FormValue("WeeklyStart"),
+		WeeklyEnd:   r.FormValue("WeeklyEnd"),
+	}
+	var err error
+	if r.FormValue("Start") != "" {
+		if freeze.Start, err = strconv.ParseInt(r.FormValue("Start"), 10, 64); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	if r.FormValue("End") != "" {
+		if freeze.End, err = strconv.ParseInt(r.FormValue("End"), 10, 64); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	arg := ManagerFreezeArg{auth, vars["Env"], freeze}
+	var reply ManagerFreezeReply
+	err = manager.AddFreeze(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Freezes": reply.Freezes, "Status": reply.Status}, err))
+}
+
+func DeleteFreeze(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerFreezeArg{auth, vars["Env"], Freeze{Name: vars["Name"]}}
+	var reply ManagerFreezeReply
+	err := manager.DeleteFreeze(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Freezes": reply.Freezes, "Status": reply.Status}, err))
+}
+
+func ProtectEnv(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	protected, err := strconv.ParseBool(r.FormValue("Protected"))
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	arg := ManagerProtectEnvArg{auth, vars["Env"], protected}
+	var reply ManagerEnvReply
+	err = manager.ProtectEnv(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status}, err))
+}
@@ -0,0 +1,37 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	. "atlantis/manager/rpc/types"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func ListDeployHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	limit := uint64(0)
+	if r.FormValue("Limit") != "" {
+		var err error
+		if limit, err = strconv.ParseUint(r.FormValue("Limit"), 10, 0); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	arg := ManagerListDeployHistoryArg{ManagerAuthArg: auth, App: vars["App"], Env: vars["Env"], Limit: uint(limit)}
+	var reply ManagerListDeployHistoryReply
+	err := manager.ListDeployHistory(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"History": reply.History, "Status": reply.Status}, err))
+}
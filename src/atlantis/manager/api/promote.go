@@ -0,0 +1,80 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/rpc/types"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func Promote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	replace := false
+	var err error
+	if r.FormValue("Replace") != "" {
+		if replace, err = strconv.ParseBool(r.FormValue("Replace")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	dryRun := false
+	if r.FormValue("DryRun") != "" {
+		if dryRun, err = strconv.ParseBool(r.FormValue("DryRun")); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	arg := ManagerPromoteArg{
+		ManagerAuthArg: auth,
+		App:            vars["App"],
+		From:           r.FormValue("From"),
+		To:             vars["Env"],
+		Replace:        replace,
+		DryRun:         dryRun,
+		OverrideFreeze: r.FormValue("OverrideFreeze"),
+	}
+	var reply AsyncReply
+	err = manager.Promote(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
+}
+
+func ListPromotionRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerPromotionRuleArg{ManagerAuthArg: auth, App: vars["App"]}
+	var reply ManagerPromotionRuleReply
+	err := manager.ListPromotionRules(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Rules": reply.Rules}, err))
+}
+
+// MinTime is in seconds. an empty From deletes the rule.
+func SetPromotionRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	minTime := int64(0)
+	var err error
+	if r.FormValue("MinTime") != "" {
+		if minTime, err = strconv.ParseInt(r.FormValue("MinTime"), 10, 64); err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+	}
+	arg := ManagerPromotionRuleArg{auth, vars["App"], vars["Env"], PromotionRule{r.FormValue("From"), minTime}}
+	var reply ManagerPromotionRuleReply
+	err = manager.SetPromotionRule(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Rules": reply.Rules}, err))
+}
@@ -13,11 +13,13 @@ package api
 
 import (
 	. "atlantis/common"
+	"atlantis/manager/helper"
 	. "atlantis/manager/rpc/types"
 	"fmt"
 	"github.com/gorilla/mux"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 func ListRouters(w http.ResponseWriter, r *http.Request) {
@@ -113,13 +115,15 @@ func RegisterApp(w http.ResponseWriter, r *http.Request) {
 	nonAtlantis, _ := strconv.ParseBool(r.FormValue("NonAtlantis"))
 	internal, _ := strconv.ParseBool(r.FormValue("Internal"))
 	arg := ManagerRegisterAppArg{
-		ManagerAuthArg: auth,
-		NonAtlantis:    nonAtlantis,
-		Internal:       internal,
-		Name:           vars["App"],
-		Repo:           r.FormValue("Repo"),
-		Root:           r.FormValue("Root"),
-		Email:          r.FormValue("Email"),
+		ManagerAuthArg:     auth,
+		NonAtlantis:        nonAtlantis,
+		Internal:           internal,
+		Name:               vars["App"],
+		Repo:               r.FormValue("Repo"),
+		Root:               r.FormValue("Root"),
+		Email:              r.FormValue("Email"),
+		Placement:          r.FormValue("Placement"),
+		SupervisorSelector: r.FormValue("SupervisorSelector"),
 	}
 	var reply ManagerRegisterAppReply
 	err := manager.RegisterApp(arg, &reply)
@@ -132,13 +136,15 @@ func UpdateApp(w http.ResponseWriter, r *http.Request) {
 	nonAtlantis, _ := strconv.ParseBool(r.FormValue("NonAtlantis"))
 	internal, _ := strconv.ParseBool(r.FormValue("Internal"))
 	arg := ManagerRegisterAppArg{
-		ManagerAuthArg: auth,
-		NonAtlantis:    nonAtlantis,
-		Internal:       internal,
-		Name:           vars["App"],
-		Repo:           r.FormValue("Repo"),
-		Root:           r.FormValue("Root"),
-		Email:          r.FormValue("Email"),
+		ManagerAuthArg:     auth,
+		NonAtlantis:        nonAtlantis,
+		Internal:           internal,
+		Name:               vars["App"],
+		Repo:               r.FormValue("Repo"),
+		Root:               r.FormValue("Root"),
+		Email:              r.FormValue("Email"),
+		Placement:          r.FormValue("Placement"),
+		SupervisorSelector: r.FormValue("SupervisorSelector"),
 	}
 	var reply ManagerRegisterAppReply
 	err := manager.UpdateApp(arg, &reply)
@@ -165,30 +171,58 @@ func GetApp(w http.ResponseWriter, r *http.Request) {
 
 func ListSupervisors(w http.ResponseWriter, r *http.Request) {
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
-	arg := ManagerListSupervisorsArg{auth}
+	arg := ManagerListSupervisorsArg{auth, r.FormValue("Selector")}
 	var reply ManagerListSupervisorsReply
 	err := manager.ListSupervisors(arg, &reply)
-	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Supervisors": reply.Supervisors, "Status": reply.Status}, err))
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Supervisors": reply.Supervisors, "Labels": reply.Labels,
+		"Inventory": reply.Inventory, "Status": reply.Status}, err))
 }
 
+// Labels is key=value,...
 func RegisterSupervisor(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
-	arg := ManagerRegisterSupervisorArg{auth, vars["Host"]}
+	labels, err := helper.ParseLabels([]string{r.FormValue("Labels")})
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	arg := ManagerRegisterSupervisorArg{auth, vars["Host"], labels}
 	var reply AsyncReply
-	err := manager.RegisterSupervisor(arg, &reply)
+	err = manager.RegisterSupervisor(arg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
 }
 
 func UnregisterSupervisor(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
-	arg := ManagerRegisterSupervisorArg{auth, vars["Host"]}
+	arg := ManagerRegisterSupervisorArg{auth, vars["Host"], nil}
 	var reply AsyncReply
 	err := manager.UnregisterSupervisor(arg, &reply)
 	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
 }
 
+// Labels is key=value,... to set and Remove is key,... to remove
+func LabelSupervisor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	labels, err := helper.ParseLabels([]string{r.FormValue("Labels")})
+	if err != nil {
+		fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+		return
+	}
+	remove := []string{}
+	for _, key := range strings.Split(r.FormValue("Remove"), ",") {
+		if key != "" {
+			remove = append(remove, key)
+		}
+	}
+	arg := ManagerLabelSupervisorArg{auth, vars["Host"], labels, remove}
+	var reply ManagerLabelSupervisorReply
+	err = manager.LabelSupervisor(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status, "Labels": reply.Labels}, err))
+}
+
 func ListManagers(w http.ResponseWriter, r *http.Request) {
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
 	arg := ManagerListManagersArg{auth}
@@ -0,0 +1,77 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/constant"
+	. "atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Deploys is app:sha,app:sha,... Instances, CPUShares and MemoryLimit default to the manifests'.
+func ReleaseBundle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerReleaseArg{ManagerDeployArg: ManagerDeployArg{
+		ManagerAuthArg: auth,
+		Env:            vars["Env"],
+		DrainTime:      DefaultDrainTime,
+		OverrideFreeze: r.FormValue("OverrideFreeze"),
+		Placement:      r.FormValue("Placement"),
+		Selector:       r.FormValue("Selector"),
+	}}
+	for _, appSha := range strings.Split(r.FormValue("Deploys"), ",") {
+		parts := strings.SplitN(appSha, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			err := errors.New(fmt.Sprintf("Invalid deploy %s. Should be app:sha", appSha))
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+		arg.Deploys = append(arg.Deploys, ReleaseDeploy{parts[0], parts[1]})
+	}
+	for name, value := range map[string]*uint{
+		"Instances":   &arg.Instances,
+		"CPUShares":   &arg.CPUShares,
+		"MemoryLimit": &arg.MemoryLimit,
+		"DrainTime":   &arg.DrainTime,
+	} {
+		if r.FormValue(name) == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(r.FormValue(name), 10, 0)
+		if err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+		*value = uint(parsed)
+	}
+	for name, value := range map[string]*bool{"Replace": &arg.Replace, "DryRun": &arg.DryRun} {
+		if r.FormValue(name) == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(r.FormValue(name))
+		if err != nil {
+			fmt.Fprintf(w, "{\"error\": \"%s\"}", err.Error())
+			return
+		}
+		*value = parsed
+	}
+	var reply AsyncReply
+	err := manager.Release(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"ID": reply.ID}, err))
+}
@@ -14,6 +14,7 @@ package api
 import (
 	. "atlantis/common"
 	. "atlantis/manager/rpc/types"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"net/http"
@@ -34,10 +35,37 @@ func GetTaskStatus(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "%s", Output(output, err))
 		return
 	}
-	if statusReply.Name == "Deploy" {
+	if statusReply.Name == "Deploy" || statusReply.Name == "DeployManifest" || statusReply.Name == "Rollback" ||
+		statusReply.Name == "ApproveDeploy" || statusReply.Name == "Promote" {
 		var reply ManagerDeployReply
 		err = manager.DeployResult(vars["ID"], &reply)
+		if err == nil && reply.Error != "" {
+			err = errors.New(reply.Error)
+		}
 		output["Containers"] = reply.Containers
+		output["RolledBack"] = reply.RolledBack
+		if reply.Plan != nil {
+			output["Plan"] = reply.Plan
+		}
+		if reply.Zones != nil {
+			output["Zones"] = reply.Zones
+		}
+		if reply.PendingRequest != "" {
+			output["PendingRequest"] = reply.PendingRequest
+		}
+	} else if statusReply.Name == "Release" {
+		var reply ManagerReleaseReply
+		err = manager.ReleaseResult(vars["ID"], &reply)
+		if err == nil && reply.Error != "" {
+			err = errors.New(reply.Error)
+		}
+		output["Order"] = reply.Order
+		output["Deploys"] = reply.Deploys
+	} else if statusReply.Name == "Scale" {
+		var reply ManagerScaleReply
+		err = manager.ScaleResult(vars["ID"], &reply)
+		output["Added"] = reply.Added
+		output["Removed"] = reply.Removed
 	} else if statusReply.Name == "Teardown" {
 		var reply ManagerTeardownReply
 		err = manager.TeardownResult(vars["ID"], &reply)
@@ -67,6 +95,15 @@ func GetTaskStatus(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "%s", Output(output, err))
 }
 
+func CancelTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerCancelTaskArg{auth, vars["ID"]}
+	var reply ManagerCancelTaskReply
+	err := manager.CancelTask(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status}, err))
+}
+
 func ListTaskIDs(w http.ResponseWriter, r *http.Request) {
 	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
 	var ids []string
@@ -0,0 +1,44 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	. "atlantis/manager/rpc/types"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerListWebhooksArg{auth, r.FormValue("App")}
+	var reply ManagerListWebhooksReply
+	err := manager.ListWebhooks(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Webhooks": reply.Webhooks, "Status": reply.Status}, err))
+}
+
+func AddWebhook(w http.ResponseWriter, r *http.Request) {
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerWebhookArg{auth, Webhook{URL: r.FormValue("URL"), App: r.FormValue("App")}}
+	var reply ManagerWebhookReply
+	err := manager.AddWebhook(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Webhook": reply.Webhook, "Status": reply.Status}, err))
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	auth := ManagerAuthArg{r.FormValue("User"), "", r.FormValue("Secret")}
+	arg := ManagerWebhookArg{auth, Webhook{ID: vars["ID"]}}
+	var reply ManagerWebhookReply
+	err := manager.DeleteWebhook(arg, &reply)
+	fmt.Fprintf(w, "%s", Output(map[string]interface{}{"Status": reply.Status}, err))
+}
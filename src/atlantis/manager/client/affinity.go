@@ -0,0 +1,72 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package client
+
+import (
+	. "atlantis/manager/rpc/types"
+)
+
+func OutputAffinityRuleReply(reply *ManagerAffinityRuleReply) error {
+	Log("-> status: %s", reply.Status)
+	Log("-> affinity rules:")
+	for app, rule := range reply.Rules {
+		kind := "affinity"
+		if rule.Anti {
+			kind = "anti-affinity"
+		}
+		strength := "soft"
+		if rule.Hard {
+			strength = "hard"
+		}
+		Log("->   %s: %s %s", app, strength, kind)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "rules": reply.Rules}, reply.Rules, nil)
+}
+
+type SetAffinityRuleCommand struct {
+	App    string `short:"a" long:"app" description:"the app of the rule"`
+	With   string `short:"w" long:"with" description:"the app to place the app's containers next to (or away from)"`
+	Anti   bool   `long:"anti" description:"keep the containers away from the other app's instead"`
+	Hard   bool   `long:"hard" description:"never break the rule instead of only preferring not to"`
+	Remove bool   `long:"remove" description:"remove the rule with the other app instead"`
+}
+
+func (c *SetAffinityRuleCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Set Affinity Rule...")
+	arg := ManagerAffinityRuleArg{dummyAuthArg, c.App, AffinityRule{c.With, c.Anti, c.Hard}, c.Remove}
+	var reply ManagerAffinityRuleReply
+	if err := rpcClient.CallAuthed("SetAffinityRule", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputAffinityRuleReply(&reply)
+}
+
+type ListAffinityRulesCommand struct {
+	App string `short:"a" long:"app" description:"the app to list the affinity rules of"`
+}
+
+func (c *ListAffinityRulesCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.App}, args)
+	Log("List Affinity Rules...")
+	arg := ManagerAffinityRuleArg{ManagerAuthArg: dummyAuthArg, App: c.App}
+	var reply ManagerAffinityRuleReply
+	if err := rpcClient.CallAuthed("ListAffinityRules", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputAffinityRuleReply(&reply)
+}
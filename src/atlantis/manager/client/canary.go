@@ -0,0 +1,49 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package client
+
+import (
+	. "atlantis/manager/rpc/types"
+	"errors"
+	"strconv"
+)
+
+type CanarySetWeightCommand struct {
+	App    string `short:"a" long:"app" description:"the app of the canary"`
+	Env    string `short:"e" long:"env" description:"the environment of the canary"`
+	Sha    string `short:"s" long:"sha" description:"the sha to send traffic to"`
+	Weight string `short:"w" long:"weight" description:"the percent of traffic to send to the sha (0 ends the canary)"`
+}
+
+func (c *CanarySetWeightCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.App, &c.Env, &c.Sha, &c.Weight}, args)
+	weight, err := strconv.ParseUint(c.Weight, 10, 0)
+	if err != nil || weight > 100 {
+		return OutputError(errors.New("Please specify a weight between 0 and 100"))
+	}
+	Log("Canary Set Weight...")
+	arg := ManagerCanaryArg{ManagerAuthArg: dummyAuthArg, App: c.App, Sha: c.Sha, Env: c.Env, Weight: uint(weight)}
+	var reply ManagerCanaryReply
+	if err := rpcClient.CallAuthed("CanarySetWeight", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	if reply.Canary != nil {
+		Log("-> canary: %s @ %d%%", reply.Canary.Sha, reply.Canary.Weight)
+	} else {
+		Log("-> canary: none")
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "canary": reply.Canary}, reply.Canary, nil)
+}
@@ -246,6 +246,7 @@ func New() *ManagerClient {
 	o.AddCommand("register-supervisor", "register an supervisor", "", &RegisterSupervisorCommand{})
 	o.AddCommand("unregister-supervisor", "unregister an supervisor", "", &UnregisterSupervisorCommand{})
 	o.AddCommand("list-supervisors", "list available supervisors", "", &ListSupervisorsCommand{})
+	o.AddCommand("label-supervisor", "set or remove the labels of a supervisor", "", &LabelSupervisorCommand{})
 
 	// Router Management
 	o.AddCommand("register-router", "[async] register an router", "", &RegisterRouterCommand{})
@@ -281,15 +282,48 @@ func New() *ManagerClient {
 	o.AddCommand("delete-env", "delete a environment", "", &DeleteEnvCommand{})
 	o.AddCommand("list-envs", "list evironments (available or deployed)", "",
 		&ListEnvsCommand{})
+	o.AddCommand("add-freeze", "freeze deploys and teardowns in an environment", "", &AddFreezeCommand{})
+	o.AddCommand("delete-freeze", "delete a freeze from an environment", "", &DeleteFreezeCommand{})
+	o.AddCommand("list-freezes", "list the freezes of an environment", "", &ListFreezesCommand{})
+	o.AddCommand("protect-env", "require approval for deploys to an environment", "", &ProtectEnvCommand{})
+
+	// Webhooks
+	o.AddCommand("add-webhook", "POST task start/finish/fail events to a URL", "", &AddWebhookCommand{})
+	o.AddCommand("delete-webhook", "delete a webhook", "", &DeleteWebhookCommand{})
+	o.AddCommand("list-webhooks", "list webhooks", "", &ListWebhooksCommand{})
 
 	// Container Management
 	o.AddCommand("list-containers", "list deployed containers", "", &ListContainersCommand{})
 	o.AddCommand("list-shas", "list deployed shas", "", &ListShasCommand{})
 	o.AddCommand("list-apps", "list deployed apps", "", &ListAppsCommand{})
 	o.AddCommand("deploy", "[async] deploy something", "", &DeployCommand{})
+	o.AddCommand("approve-deploy", "[async] approve a deploy request to a protected environment", "",
+		&ApproveDeployCommand{})
+	o.AddCommand("deny-deploy", "deny a deploy request to a protected environment", "", &DenyDeployCommand{})
+	o.AddCommand("list-deploy-requests", "list the deploy requests waiting for approval", "",
+		&ListDeployRequestsCommand{})
+	o.AddCommand("schedule-deploy", "deploy something at a later time", "", &ScheduleDeployCommand{})
+	o.AddCommand("cancel-scheduled-deploy", "cancel a scheduled deploy that hasn't started", "",
+		&CancelScheduledDeployCommand{})
+	o.AddCommand("list-scheduled-deploys", "list the deploys scheduled for later", "",
+		&ListScheduledDeploysCommand{})
+	o.AddCommand("deploy-manifest", "[async] deploy a manifest without building it", "", &DeployManifestCommand{})
 	o.AddCommand("deploy-container", "[async] deploy by replicating a container", "", &DeployContainerCommand{})
 	o.AddCommand("copy-container", "[async] deploy by copying a single container to a specific host", "", &CopyContainerCommand{})
 	o.AddCommand("teardown", "[async] teardown something", "", &TeardownCommand{})
+	o.AddCommand("scale", "[async] add or remove containers of an app+sha+env in every zone", "", &ScaleCommand{})
+	o.AddCommand("rollback", "[async] swap an app+env back to a previous release", "", &RollbackCommand{})
+	o.AddCommand("promote", "[async] deploy the sha serving one environment to another", "", &PromoteCommand{})
+	o.AddCommand("set-promotion-rule", "only let shas that have served an environment long enough be promoted", "",
+		&SetPromotionRuleCommand{})
+	o.AddCommand("list-promotion-rules", "list the promotion rules of an app", "", &ListPromotionRulesCommand{})
+	o.AddCommand("set-affinity-rule", "place an app's containers next to (or away from) another app's", "",
+		&SetAffinityRuleCommand{})
+	o.AddCommand("list-affinity-rules", "list the affinity rules of an app", "", &ListAffinityRulesCommand{})
+	o.AddCommand("release", "[async] deploy app:sha ... to an env in the order their dependencies need", "",
+		&ReleaseCommand{})
+	o.AddCommand("deploy-history", "list the deploys and teardowns of an app in an environment", "", &DeployHistoryCommand{})
+	o.AddCommand("canary-set-weight", "send a percent of an app+env's traffic to a sha", "", &CanarySetWeightCommand{})
 	o.AddCommand("get-container", "get a container", "", &GetContainerCommand{})
 
 	// Router Config Management
@@ -346,7 +380,10 @@ func New() *ManagerClient {
 	o.AddCommand("status", "get the status of an async command", "", &StatusCommand{})
 	o.AddCommand("result", "get the result of an async command", "", &ResultCommand{})
 	o.AddCommand("wait", "get the wait of an async command", "", &WaitCommand{})
+	o.AddCommand("cancel", "stop an async command at its next safe point", "", &CancelCommand{})
 	o.AddCommand("deploy-result", "get the result of an async deploy", "", &DeployResultCommand{})
+	o.AddCommand("release-result", "get the result of an async release", "", &ReleaseResultCommand{})
+	o.AddCommand("scale-result", "get the result of an async scale", "", &ScaleResultCommand{})
 	o.AddCommand("teardown-result", "get the result of an async teardown", "", &TeardownResultCommand{})
 
 	return o
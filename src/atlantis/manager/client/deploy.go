@@ -13,11 +13,17 @@ package client
 
 import (
 	atlantis "atlantis/common"
+	"atlantis/manager/helper"
 	. "atlantis/manager/rpc/types"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
 )
 
-type DeployCommand struct {
+// the options shared by deploy and schedule-deploy
+type DeployOpts struct {
 	App         string `short:"a" long:"app" description:"the app to deploy"`
 	Sha         string `short:"s" long:"sha" description:"the sha to deploy"`
 	Env         string `short:"e" long:"env" description:"the environment to deploy"`
@@ -25,14 +31,25 @@ type DeployCommand struct {
 	CPUShares   uint   `short:"c" long:"cpu-shares" default:"0" description:"the number of CPU shares per instance"`
 	MemoryLimit uint   `short:"m" long:"memory-limit" default:"0" description:"the MBytes of memory per instance"`
 	Dev         bool   `long:"dev" description:"only deploy 1 instance in 1 AZ"`
-	Wait        bool   `long:"wait" description:"wait until the deploy is done before exiting"`
+	Replace     bool   `long:"replace" description:"swap traffic to this sha and tear down the other shas in the env"`
+	DrainTime   uint   `long:"drain-time" default:"30" description:"seconds to wait before tearing down the replaced shas"`
+	WatchTime   uint   `long:"watch-time" default:"0" description:"seconds to watch the new containers and roll back if they fail"`
+	MaxFailPct  uint   `long:"max-fail-pct" default:"50" description:"roll back if more than this percent of containers fail while watching"`
+	Zones       string `long:"zones" description:"zone:instances,... to deploy in those zones instead of every AZ"`
+	MinZones    uint   `long:"min-zones" default:"0" description:"keep the zones that succeed if at least this many do (0 for all, or only the required ones)"`
+	NeedZones   string `long:"required-zones" description:"zone,... that have to succeed no matter what --min-zones is"`
+	OnlyMissing bool   `long:"only-missing" description:"only deploy what the zones are missing (e.g. the zones that failed)"`
+	Rebuild     bool   `long:"rebuild" description:"build the sha even if its manifest is cached"`
+	Override    string `long:"override-freeze" description:"the reason to deploy while the env is frozen (superusers only)"`
+	Placement   string `long:"placement" description:"spread, binpack or cost to override how the app's containers are placed"`
+	Selector    string `long:"selector" description:"the supervisor labels to deploy to on top of the app's, e.g. tier=batch"`
 }
 
-func (c *DeployCommand) Execute(args []string) error {
-	if err := Init(); err != nil {
-		return OutputError(err)
+func (c *DeployOpts) deployArg() (ManagerDeployArg, error) {
+	zones, err := helper.ParseZoneInstances([]string{c.Zones})
+	if err != nil {
+		return ManagerDeployArg{}, err
 	}
-	Log("Deploy...")
 	arg := ManagerDeployArg{
 		ManagerAuthArg: dummyAuthArg,
 		App:            c.App,
@@ -42,7 +59,40 @@ func (c *DeployCommand) Execute(args []string) error {
 		CPUShares:      c.CPUShares,
 		MemoryLimit:    c.MemoryLimit,
 		Dev:            c.Dev,
+		Replace:        c.Replace,
+		DrainTime:      c.DrainTime,
+		WatchTime:      c.WatchTime,
+		MaxFailPct:     c.MaxFailPct,
+		Zones:          zones,
+		MinZones:       c.MinZones,
+		OnlyMissing:    c.OnlyMissing,
+		Rebuild:        c.Rebuild,
+		OverrideFreeze: c.Override,
+		Placement:      c.Placement,
+		Selector:       c.Selector,
+	}
+	if c.NeedZones != "" {
+		arg.RequiredZones = strings.Split(c.NeedZones, ",")
+	}
+	return arg, nil
+}
+
+type DeployCommand struct {
+	DeployOpts
+	DryRun bool `long:"dry-run" description:"only show where the containers would go"`
+	Wait   bool `long:"wait" description:"wait until the deploy is done before exiting"`
+}
+
+func (c *DeployCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Deploy...")
+	arg, err := c.deployArg()
+	if err != nil {
+		return OutputError(err)
 	}
+	arg.DryRun = c.DryRun
 	var reply atlantis.AsyncReply
 	if err := rpcClient.CallAuthed("Deploy", &arg, &reply); err != nil {
 		return OutputError(err)
@@ -54,9 +104,233 @@ func (c *DeployCommand) Execute(args []string) error {
 	return (&WaitCommand{reply.ID}).Execute(args)
 }
 
+type DeployManifestCommand struct {
+	File      string `short:"f" long:"file" description:"the manifest to deploy"`
+	Sha       string `short:"s" long:"sha" description:"the sha the manifest was built from"`
+	Env       string `short:"e" long:"env" description:"the environment to deploy"`
+	Instances uint   `short:"i" long:"instances" default:"0" description:"the number of instances to deploy in each AZ (defaults to the manifest's)"`
+	Zones     string `long:"zones" description:"zone:instances,... to deploy in those zones instead of every AZ"`
+	Replace   bool   `long:"replace" description:"swap traffic to this sha and tear down the other shas in the env"`
+	DryRun    bool   `long:"dry-run" description:"only show where the containers would go"`
+	Override  string `long:"override-freeze" description:"the reason to deploy while the env is frozen (superusers only)"`
+	Wait      bool   `long:"wait" description:"wait until the deploy is done before exiting"`
+}
+
+func (c *DeployManifestCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.File, &c.Sha, &c.Env}, args)
+	Log("Deploy Manifest...")
+	manifest, err := ioutil.ReadFile(c.File)
+	if err != nil {
+		return OutputError(err)
+	}
+	zones, err := helper.ParseZoneInstances([]string{c.Zones})
+	if err != nil {
+		return OutputError(err)
+	}
+	arg := ManagerDeployManifestArg{
+		ManagerDeployArg: ManagerDeployArg{
+			ManagerAuthArg: dummyAuthArg,
+			Sha:            c.Sha,
+			Env:            c.Env,
+			Instances:      c.Instances,
+			Replace:        c.Replace,
+			DryRun:         c.DryRun,
+			Zones:          zones,
+			OverrideFreeze: c.Override,
+		},
+		Manifest: string(manifest),
+	}
+	var reply atlantis.AsyncReply
+	if err := rpcClient.CallAuthed("DeployManifest", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> ID: %s", reply.ID)
+	if !c.Wait {
+		return Output(map[string]interface{}{"id": reply.ID}, reply.ID, nil)
+	}
+	return (&WaitCommand{reply.ID}).Execute(args)
+}
+
+type ApproveDeployCommand struct {
+	ID   string `short:"i" long:"id" description:"the deploy request to approve"`
+	Wait bool   `long:"wait" description:"wait until the deploy is done before exiting"`
+}
+
+func (c *ApproveDeployCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.ID}, args)
+	Log("Approve Deploy...")
+	arg := ManagerDeployRequestArg{ManagerAuthArg: dummyAuthArg, ID: c.ID}
+	var reply atlantis.AsyncReply
+	if err := rpcClient.CallAuthed("ApproveDeploy", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> ID: %s", reply.ID)
+	if !c.Wait {
+		return Output(map[string]interface{}{"id": reply.ID}, reply.ID, nil)
+	}
+	return (&WaitCommand{reply.ID}).Execute(args)
+}
+
+type DenyDeployCommand struct {
+	ID     string `short:"i" long:"id" description:"the deploy request to deny"`
+	Reason string `short:"r" long:"reason" description:"why the deploy is denied"`
+}
+
+func (c *DenyDeployCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.ID}, args)
+	Log("Deny Deploy...")
+	arg := ManagerDeployRequestArg{ManagerAuthArg: dummyAuthArg, ID: c.ID, Reason: c.Reason}
+	var reply ManagerDeployRequestReply
+	if err := rpcClient.CallAuthed("DenyDeploy", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	return Output(map[string]interface{}{"status": reply.Status, "request": reply.Request}, reply.Request, nil)
+}
+
+type ListDeployRequestsCommand struct {
+	App string `short:"a" long:"app" description:"only list the requests for this app"`
+	Env string `short:"e" long:"env" description:"only list the requests for this environment"`
+	All bool   `long:"all" description:"also list the approved and denied requests"`
+}
+
+func (c *ListDeployRequestsCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("List Deploy Requests...")
+	arg := ManagerListDeployRequestsArg{ManagerAuthArg: dummyAuthArg, App: c.App, Env: c.Env, All: c.All}
+	var reply ManagerListDeployRequestsReply
+	if err := rpcClient.CallAuthed("ListDeployRequests", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	Log("-> requests:")
+	for _, request := range reply.Requests {
+		Log("->   %s: %s @ %s in %s by %s (%s)", request.ID, request.Arg.App, request.Arg.Sha, request.Arg.Env,
+			request.User, request.Status)
+		if request.DecidedBy != "" {
+			Log("->     decided by %s %s", request.DecidedBy, request.Reason)
+		}
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "requests": reply.Requests}, reply.Requests, nil)
+}
+
+func OutputScheduledDeploy(schedule *ScheduledDeploy) {
+	Log("->   %s: %s @ %s in %s at %s by %s (%s)", schedule.ID, schedule.Arg.App, schedule.Arg.Sha,
+		schedule.Arg.Env, time.Unix(schedule.Time, 0).UTC().Format(time.RFC3339), schedule.User, schedule.Status)
+	if schedule.TaskID != "" {
+		Log("->     task %s on %s", schedule.TaskID, schedule.Manager)
+	}
+	if schedule.Error != "" {
+		Log("->     error: %s", schedule.Error)
+	}
+	if schedule.CancelledBy != "" {
+		Log("->     cancelled by %s", schedule.CancelledBy)
+	}
+}
+
+type ScheduleDeployCommand struct {
+	DeployOpts
+	At string `long:"at" description:"when to deploy (RFC3339, e.g. 2014-06-01T03:00:00-07:00)"`
+	In string `long:"in" description:"how long from now to deploy (e.g. 8h)"`
+}
+
+func (c *ScheduleDeployCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Schedule Deploy...")
+	var at time.Time
+	if c.At != "" && c.In != "" {
+		return OutputError(errors.New("Please specify only one of --at and --in"))
+	} else if c.At != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, c.At); err != nil {
+			return OutputError(err)
+		}
+	} else if c.In != "" {
+		in, err := time.ParseDuration(c.In)
+		if err != nil {
+			return OutputError(err)
+		}
+		at = time.Now().Add(in)
+	} else {
+		return OutputError(errors.New("Please specify when to deploy with --at or --in"))
+	}
+	deployArg, err := c.deployArg()
+	if err != nil {
+		return OutputError(err)
+	}
+	arg := ManagerScheduleDeployArg{deployArg, at.Unix()}
+	var reply ManagerScheduledDeployReply
+	if err := rpcClient.CallAuthed("ScheduleDeploy", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	OutputScheduledDeploy(reply.Schedule)
+	return Output(map[string]interface{}{"status": reply.Status, "schedule": reply.Schedule}, reply.Schedule.ID,
+		nil)
+}
+
+type CancelScheduledDeployCommand struct {
+	ID string `short:"i" long:"id" description:"the scheduled deploy to cancel"`
+}
+
+func (c *CancelScheduledDeployCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.ID}, args)
+	Log("Cancel Scheduled Deploy...")
+	arg := ManagerScheduledDeployArg{dummyAuthArg, c.ID}
+	var reply ManagerScheduledDeployReply
+	if err := rpcClient.CallAuthed("CancelScheduledDeploy", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	return Output(map[string]interface{}{"status": reply.Status, "schedule": reply.Schedule}, reply.Schedule, nil)
+}
+
+type ListScheduledDeploysCommand struct {
+	App string `short:"a" long:"app" description:"only list the scheduled deploys of this app"`
+	Env string `short:"e" long:"env" description:"only list the scheduled deploys to this environment"`
+	All bool   `long:"all" description:"also list the deploys that were started or cancelled"`
+}
+
+func (c *ListScheduledDeploysCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("List Scheduled Deploys...")
+	arg := ManagerListScheduledDeploysArg{ManagerAuthArg: dummyAuthArg, App: c.App, Env: c.Env, All: c.All}
+	var reply ManagerListScheduledDeploysReply
+	if err := rpcClient.CallAuthed("ListScheduledDeploys", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	Log("-> scheduled deploys:")
+	for _, schedule := range reply.Schedules {
+		OutputScheduledDeploy(schedule)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "schedules": reply.Schedules}, reply.Schedules,
+		nil)
+}
+
 type DeployContainerCommand struct {
 	ContainerID string `short:"c" long:"container" description:"the id of the container to replicate"`
 	Instances   uint   `short:"i" long:"instances" default:"1" description:"the number of instances to deploy in each AZ"`
+	DryRun      bool   `long:"dry-run" description:"only show where the containers would go"`
+	Override    string `long:"override-freeze" description:"the reason to deploy while the env is frozen (superusers only)"`
 	Wait        bool   `long:"wait" description:"wait until the deploy is done before exiting"`
 }
 
@@ -65,7 +339,8 @@ func (c *DeployContainerCommand) Execute(args []string) error {
 		return OutputError(err)
 	}
 	Log("DeployContainer...")
-	arg := ManagerDeployContainerArg{ManagerAuthArg: dummyAuthArg, ContainerID: c.ContainerID, Instances: c.Instances}
+	arg := ManagerDeployContainerArg{ManagerAuthArg: dummyAuthArg, ContainerID: c.ContainerID, Instances: c.Instances,
+		DryRun: c.DryRun, OverrideFreeze: c.Override}
 	var reply atlantis.AsyncReply
 	if err := rpcClient.CallAuthed("DeployContainer", &arg, &reply); err != nil {
 		return OutputError(err)
@@ -81,6 +356,8 @@ type CopyContainerCommand struct {
 	ContainerID string `short:"c" long:"container" description:"the id of the container to copy"`
 	ToHost      string `short:"H" long:"host" description:"the host to copy to"`
 	PostCopy    int    `short:"p" long:"post" description:"what to do after the copy. (0 = nothing, 1 = cleanup datamodel only, 2 = teardown)"`
+	DryRun      bool   `long:"dry-run" description:"only check that the host has room for the copy"`
+	Override    string `long:"override-freeze" description:"the reason to copy while the env is frozen (superusers only)"`
 	Wait        bool   `long:"wait" description:"wait until the deploy is done before exiting"`
 }
 
@@ -94,6 +371,8 @@ func (c *CopyContainerCommand) Execute(args []string) error {
 		ContainerID:    c.ContainerID,
 		ToHost:         c.ToHost,
 		PostCopy:       c.PostCopy,
+		DryRun:         c.DryRun,
+		OverrideFreeze: c.Override,
 	}
 	var reply atlantis.AsyncReply
 	if err := rpcClient.CallAuthed("CopyContainer", &arg, &reply); err != nil {
@@ -106,16 +385,76 @@ func (c *CopyContainerCommand) Execute(args []string) error {
 	return (&WaitCommand{reply.ID}).Execute(args)
 }
 
+type RollbackCommand struct {
+	App      string `short:"a" long:"app" description:"the app to roll back"`
+	Env      string `short:"e" long:"env" description:"the environment to roll back"`
+	Sha      string `short:"s" long:"sha" description:"the sha to roll back to (defaults to the previous release)"`
+	Teardown bool   `long:"teardown" description:"tear down the current sha once traffic is swapped"`
+	Override string `long:"override-freeze" description:"the reason to roll back while the env is frozen (superusers only)"`
+	Wait     bool   `long:"wait" description:"wait until the rollback is done before exiting"`
+}
+
+func (c *RollbackCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.App, &c.Env}, args)
+	Log("Rollback...")
+	arg := ManagerRollbackArg{ManagerAuthArg: dummyAuthArg, App: c.App, Env: c.Env, Sha: c.Sha, Teardown: c.Teardown,
+		OverrideFreeze: c.Override}
+	var reply atlantis.AsyncReply
+	if err := rpcClient.CallAuthed("Rollback", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> ID: %s", reply.ID)
+	if !c.Wait {
+		return Output(map[string]interface{}{"id": reply.ID}, reply.ID, nil)
+	}
+	return (&WaitCommand{reply.ID}).Execute(args)
+}
+
 func OutputDeployReply(reply *ManagerDeployReply) error {
 	Log("-> Status: %s", reply.Status)
+	if reply.RolledBack {
+		Log("-> Rolled Back: true")
+	}
+	if reply.PendingRequest != "" {
+		Log("-> Waiting for Approval: %s", reply.PendingRequest)
+		return Output(map[string]interface{}{"status": reply.Status, "pendingRequest": reply.PendingRequest},
+			reply.PendingRequest, nil)
+	}
+	if reply.Plan != nil {
+		Log("-> Plan:")
+		for zone, hosts := range reply.Plan {
+			Log("->   %s:", zone)
+			for _, host := range hosts {
+				Log("->     %s x%d (free: %d, weight: %.2f)", host.Host, host.Instances, host.Free, host.Weight)
+			}
+		}
+		return Output(map[string]interface{}{"status": reply.Status, "plan": reply.Plan}, reply.Plan, nil)
+	}
+	if len(reply.Zones) > 0 {
+		Log("-> Zones:")
+		for zone, zoneStatus := range reply.Zones {
+			if zoneStatus.Error != "" {
+				Log("->   %s: %s (%s)", zone, zoneStatus.Status, zoneStatus.Error)
+			} else {
+				Log("->   %s: %s x%d", zone, zoneStatus.Status, zoneStatus.Containers)
+			}
+		}
+	}
 	Log("-> Deployed Containers:")
 	quietContainerIDs := make([]string, len(reply.Containers))
 	for i, cont := range reply.Containers {
 		Log("->   %s", cont.String())
 		quietContainerIDs[i] = cont.ID
 	}
-	return Output(map[string]interface{}{"status": reply.Status, "containers": reply.Containers},
-		quietContainerIDs, nil)
+	var err error
+	if reply.Error != "" {
+		err = errors.New(reply.Error)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "containers": reply.Containers,
+		"rolledBack": reply.RolledBack, "zones": reply.Zones}, quietContainerIDs, err)
 }
 
 type DeployResultCommand struct {
@@ -136,12 +475,78 @@ func (c *DeployResultCommand) Execute(args []string) error {
 	return OutputDeployReply(&reply)
 }
 
+type ScaleCommand struct {
+	App       string `short:"a" long:"app" description:"the app to scale"`
+	Sha       string `short:"s" long:"sha" description:"the sha to scale"`
+	Env       string `short:"e" long:"env" description:"the environment to scale"`
+	Instances uint   `short:"i" long:"instances" description:"the number of containers to have in each zone"`
+	Zones     string `long:"zones" description:"zone:instances,... to have in those zones instead (0 removes a zone)"`
+	Override  string `long:"override-freeze" description:"the reason to scale while the env is frozen (superusers only)"`
+	Wait      bool   `long:"wait" description:"wait until the scale is done before exiting"`
+}
+
+func (c *ScaleCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.App, &c.Sha, &c.Env}, args)
+	Log("Scale...")
+	zones, err := helper.ParseZoneInstances([]string{c.Zones})
+	if err != nil {
+		return OutputError(err)
+	}
+	arg := ManagerScaleArg{ManagerAuthArg: dummyAuthArg, App: c.App, Sha: c.Sha, Env: c.Env, Instances: c.Instances,
+		Zones: zones, OverrideFreeze: c.Override}
+	var reply atlantis.AsyncReply
+	if err := rpcClient.CallAuthed("Scale", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> ID: %s", reply.ID)
+	if !c.Wait {
+		return Output(map[string]interface{}{"id": reply.ID}, reply.ID, nil)
+	}
+	return (&WaitCommand{reply.ID}).Execute(args)
+}
+
+func OutputScaleReply(reply *ManagerScaleReply) error {
+	Log("-> Status: %s", reply.Status)
+	Log("-> Added Containers:")
+	for _, cont := range reply.Added {
+		Log("->   %s", cont.String())
+	}
+	Log("-> Removed Containers:")
+	for _, cid := range reply.Removed {
+		Log("->   %s", cid)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "added": reply.Added, "removed": reply.Removed},
+		reply.Status, nil)
+}
+
+type ScaleResultCommand struct {
+	ID string `short:"i" long:"id" description:"the task ID to fetch the result for"`
+}
+
+func (c *ScaleResultCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.ID}, args)
+	Log("Scale Result...")
+	arg := c.ID
+	var reply ManagerScaleReply
+	if err := rpcClient.Call("ScaleResult", arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputScaleReply(&reply)
+}
+
 type TeardownCommand struct {
 	App       string `short:"a" long:"app" description:"the app to teardown"`
 	Sha       string `short:"s" long:"sha" description:"the sha to teardown"`
 	Env       string `short:"e" long:"env" description:"the environment to teardown"`
 	Container string `short:"c" long:"container" description:"the container to teardown"`
 	All       bool   `long:"all" description:"teardown all containers in every supervisor"`
+	Override  string `long:"override-freeze" description:"the reason to teardown while the env is frozen (superusers only)"`
 	Wait      bool   `long:"wait" description:"wait until the teardown is done before exiting"`
 }
 
@@ -150,7 +555,7 @@ func (c *TeardownCommand) Execute(args []string) error {
 		return OutputError(err)
 	}
 	Log("Teardown...")
-	arg := ManagerTeardownArg{dummyAuthArg, c.App, c.Sha, c.Env, c.Container, c.All}
+	arg := ManagerTeardownArg{dummyAuthArg, c.App, c.Sha, c.Env, c.Container, c.All, c.Override}
 	var reply atlantis.AsyncReply
 	if err := rpcClient.CallAuthed("Teardown", &arg, &reply); err != nil {
 		return OutputError(err)
@@ -13,6 +13,8 @@ package client
 
 import (
 	. "atlantis/manager/rpc/types"
+	"fmt"
+	"time"
 )
 
 type UpdateDepCommand struct {
@@ -138,3 +140,112 @@ func (c *DeleteEnvCommand) Execute(args []string) error {
 	Log("-> status: %s", reply.Status)
 	return Output(map[string]interface{}{"status": reply.Status}, nil, nil)
 }
+
+func OutputFreezeReply(reply *ManagerFreezeReply) error {
+	Log("-> status: %s", reply.Status)
+	Log("-> freezes:")
+	for _, freeze := range reply.Freezes {
+		window := "always"
+		if freeze.WeeklyStart != "" {
+			window = fmt.Sprintf("weekly %s - %s", freeze.WeeklyStart, freeze.WeeklyEnd)
+		}
+		if freeze.Start > 0 {
+			window += fmt.Sprintf(" from %s", time.Unix(freeze.Start, 0).UTC().Format(time.RFC3339))
+		}
+		if freeze.End > 0 {
+			window += fmt.Sprintf(" until %s", time.Unix(freeze.End, 0).UTC().Format(time.RFC3339))
+		}
+		Log("->   %s (%s by %s): %s", freeze.Name, window, freeze.User, freeze.Reason)
+	}
+	if reply.Active != nil {
+		Log("-> active: %v", reply.Active)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "freezes": reply.Freezes, "active": reply.Active},
+		reply.Freezes, nil)
+}
+
+type AddFreezeCommand struct {
+	Env         string `short:"e" long:"env" description:"the environment to freeze"`
+	Name        string `short:"n" long:"name" description:"the name of the freeze (an existing freeze with this name is replaced)"`
+	Reason      string `short:"r" long:"reason" description:"why the environment is frozen"`
+	Duration    string `short:"d" long:"duration" description:"freeze from now for this long (e.g. 4h) instead of until the freeze is deleted"`
+	WeeklyStart string `long:"weekly-start" description:"when the freeze starts each week, e.g. \"Fri 16:00\" (UTC)"`
+	WeeklyEnd   string `long:"weekly-end" description:"when the freeze ends each week, e.g. \"Mon 09:00\" (UTC)"`
+}
+
+func (c *AddFreezeCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Add Freeze...")
+	freeze := Freeze{Name: c.Name, Reason: c.Reason, WeeklyStart: c.WeeklyStart, WeeklyEnd: c.WeeklyEnd}
+	if c.Duration != "" {
+		duration, err := time.ParseDuration(c.Duration)
+		if err != nil {
+			return OutputError(err)
+		}
+		now := time.Now()
+		freeze.Start = now.Unix()
+		freeze.End = now.Add(duration).Unix()
+	}
+	arg := ManagerFreezeArg{dummyAuthArg, c.Env, freeze}
+	var reply ManagerFreezeReply
+	if err := rpcClient.CallAuthed("AddFreeze", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputFreezeReply(&reply)
+}
+
+type DeleteFreezeCommand struct {
+	Env  string `short:"e" long:"env" description:"the environment of the freeze"`
+	Name string `short:"n" long:"name" description:"the name of the freeze"`
+}
+
+func (c *DeleteFreezeCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Delete Freeze...")
+	arg := ManagerFreezeArg{dummyAuthArg, c.Env, Freeze{Name: c.Name}}
+	var reply ManagerFreezeReply
+	if err := rpcClient.CallAuthed("DeleteFreeze", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputFreezeReply(&reply)
+}
+
+type ListFreezesCommand struct {
+	Env string `short:"e" long:"env" description:"the environment to list the freezes of"`
+}
+
+func (c *ListFreezesCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("List Freezes...")
+	arg := ManagerFreezeArg{ManagerAuthArg: dummyAuthArg, Env: c.Env}
+	var reply ManagerFreezeReply
+	if err := rpcClient.CallAuthed("ListFreezes", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputFreezeReply(&reply)
+}
+
+type ProtectEnvCommand struct {
+	Env string `short:"e" long:"env" description:"the environment to protect"`
+	Off bool   `long:"off" description:"stop requiring approval for deploys to the environment"`
+}
+
+func (c *ProtectEnvCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Protect Env...")
+	arg := ManagerProtectEnvArg{dummyAuthArg, c.Env, !c.Off}
+	var reply ManagerEnvReply
+	if err := rpcClient.CallAuthed("ProtectEnv", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	return Output(map[string]interface{}{"status": reply.Status}, nil, nil)
+}
@@ -0,0 +1,51 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package client
+
+import (
+	. "atlantis/manager/rpc/types"
+	"time"
+)
+
+type DeployHistoryCommand struct {
+	App   string `short:"a" long:"app" description:"the app to list the history of"`
+	Env   string `short:"e" long:"env" description:"the environment to list the history of"`
+	Limit uint   `short:"l" long:"limit" default:"20" description:"the number of records to show (0 for all)"`
+}
+
+func (c *DeployHistoryCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.App, &c.Env}, args)
+	Log("Deploy History...")
+	arg := ManagerListDeployHistoryArg{ManagerAuthArg: dummyAuthArg, App: c.App, Env: c.Env, Limit: c.Limit}
+	var reply ManagerListDeployHistoryReply
+	if err := rpcClient.CallAuthed("ListDeployHistory", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	Log("-> history:")
+	for _, record := range reply.History {
+		Log("->   %s %s %s @ %s by %s [%s] (task %s)", time.Unix(record.Time, 0).Format(time.RFC3339),
+			record.Action, record.App, record.Sha, record.User, record.Status, record.TaskID)
+		Log("->     instances: %d, cpu shares: %d, memory limit: %d", record.Instances, record.CPUShares,
+			record.MemoryLimit)
+		if len(record.Zones) > 0 {
+			Log("->     zones: %v", record.Zones)
+		}
+		if record.Error != "" {
+			Log("->     error: %s", record.Error)
+		}
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "history": reply.History}, reply.History, nil)
+}
@@ -0,0 +1,105 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package client
+
+import (
+	atlantis "atlantis/common"
+	. "atlantis/manager/rpc/types"
+	"time"
+)
+
+type PromoteCommand struct {
+	App      string `short:"a" long:"app" description:"the app to promote"`
+	From     string `short:"f" long:"from" description:"the environment whose current sha is promoted"`
+	To       string `short:"t" long:"to" description:"the environment to deploy the sha to"`
+	Replace  bool   `long:"replace" description:"swap traffic to the sha and tear down the other shas in the env"`
+	DryRun   bool   `long:"dry-run" description:"only show where the containers would go"`
+	Override string `long:"override-freeze" description:"the reason to deploy while the env is frozen (superusers only)"`
+	Wait     bool   `long:"wait" description:"wait until the promotion is done before exiting"`
+}
+
+func (c *PromoteCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.App}, args)
+	Log("Promote...")
+	arg := ManagerPromoteArg{
+		ManagerAuthArg: dummyAuthArg,
+		App:            c.App,
+		From:           c.From,
+		To:             c.To,
+		Replace:        c.Replace,
+		DryRun:         c.DryRun,
+		OverrideFreeze: c.Override,
+	}
+	var reply atlantis.AsyncReply
+	if err := rpcClient.CallAuthed("Promote", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> ID: %s", reply.ID)
+	if !c.Wait {
+		return Output(map[string]interface{}{"id": reply.ID}, reply.ID, nil)
+	}
+	return (&WaitCommand{reply.ID}).Execute(args)
+}
+
+func OutputPromotionRuleReply(reply *ManagerPromotionRuleReply) error {
+	Log("-> status: %s", reply.Status)
+	Log("-> promotion rules:")
+	for env, rule := range reply.Rules {
+		Log("->   %s: from %s after %s", env, rule.From, time.Duration(rule.MinTime)*time.Second)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "rules": reply.Rules}, reply.Rules, nil)
+}
+
+type SetPromotionRuleCommand struct {
+	App     string `short:"a" long:"app" description:"the app of the rule"`
+	Env     string `short:"e" long:"env" description:"the environment the rule guards"`
+	From    string `short:"f" long:"from" description:"the only environment shas may be promoted from (empty to delete the rule)"`
+	MinTime string `long:"min-time" default:"0s" description:"how long a sha has to have served --from first (e.g. 1h)"`
+}
+
+func (c *SetPromotionRuleCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Set Promotion Rule...")
+	minTime, err := time.ParseDuration(c.MinTime)
+	if err != nil {
+		return OutputError(err)
+	}
+	arg := ManagerPromotionRuleArg{dummyAuthArg, c.App, c.Env, PromotionRule{c.From, int64(minTime.Seconds())}}
+	var reply ManagerPromotionRuleReply
+	if err := rpcClient.CallAuthed("SetPromotionRule", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputPromotionRuleReply(&reply)
+}
+
+type ListPromotionRulesCommand struct {
+	App string `short:"a" long:"app" description:"the app to list the promotion rules of"`
+}
+
+func (c *ListPromotionRulesCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.App}, args)
+	Log("List Promotion Rules...")
+	arg := ManagerPromotionRuleArg{ManagerAuthArg: dummyAuthArg, App: c.App}
+	var reply ManagerPromotionRuleReply
+	if err := rpcClient.CallAuthed("ListPromotionRules", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	return OutputPromotionRuleReply(&reply)
+}
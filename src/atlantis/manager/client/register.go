@@ -13,7 +13,13 @@ package client
 
 import (
 	atlantis "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
 	. "atlantis/manager/rpc/types"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
 type RegisterRouterCommand struct {
@@ -193,6 +199,8 @@ type RegisterAppCommand struct {
 	Repo        string `short:"g" long:"git" description:"the app's git repository"`
 	Root        string `short:"r" long:"root" description:"the app's root within the repo"`
 	Email       string `short:"e" long:"email" description"the email of the app's owner"`
+	Placement   string `long:"placement" description:"how to place containers on supervisors: spread (default), binpack or cost"`
+	Selector    string `long:"supervisor-selector" description:"the supervisor labels to run on, e.g. ssd=true,tier!=batch"`
 }
 
 func (c *RegisterAppCommand) Execute(args []string) error {
@@ -203,13 +211,15 @@ func (c *RegisterAppCommand) Execute(args []string) error {
 	Log("Register App...")
 	args = ExtractArgs([]*string{&c.App, &c.Repo, &c.Root}, args)
 	arg := ManagerRegisterAppArg{
-		ManagerAuthArg: dummyAuthArg,
-		NonAtlantis:    c.NonAtlantis,
-		Internal:       c.Internal,
-		Name:           c.App,
-		Repo:           c.Repo,
-		Root:           c.Root,
-		Email:          c.Email,
+		ManagerAuthArg:     dummyAuthArg,
+		NonAtlantis:        c.NonAtlantis,
+		Internal:           c.Internal,
+		Name:               c.App,
+		Repo:               c.Repo,
+		Root:               c.Root,
+		Email:              c.Email,
+		Placement:          c.Placement,
+		SupervisorSelector: c.Selector,
 	}
 	var reply ManagerRegisterAppReply
 	err = rpcClient.CallAuthed("RegisterApp", &arg, &reply)
@@ -227,6 +237,8 @@ type UpdateAppCommand struct {
 	Repo        string `short:"g" long:"git" description:"the app's git repository (or host:port for non-atlantis apps)"`
 	Root        string `short:"r" long:"root" description:"the app's root within the repo"`
 	Email       string `short:"e" long:"email" description"the email of the app's owner"`
+	Placement   string `long:"placement" description:"how to place containers on supervisors: spread (default), binpack or cost"`
+	Selector    string `long:"supervisor-selector" description:"the supervisor labels to run on, e.g. ssd=true,tier!=batch"`
 }
 
 func (c *UpdateAppCommand) Execute(args []string) error {
@@ -237,13 +249,15 @@ func (c *UpdateAppCommand) Execute(args []string) error {
 	Log("Update App...")
 	args = ExtractArgs([]*string{&c.App, &c.Repo, &c.Root}, args)
 	arg := ManagerRegisterAppArg{
-		ManagerAuthArg: dummyAuthArg,
-		NonAtlantis:    c.NonAtlantis,
-		Internal:       c.Internal,
-		Name:           c.App,
-		Repo:           c.Repo,
-		Root:           c.Root,
-		Email:          c.Email,
+		ManagerAuthArg:     dummyAuthArg,
+		NonAtlantis:        c.NonAtlantis,
+		Internal:           c.Internal,
+		Name:               c.App,
+		Repo:               c.Repo,
+		Root:               c.Root,
+		Email:              c.Email,
+		Placement:          c.Placement,
+		SupervisorSelector: c.Selector,
 	}
 	var reply ManagerRegisterAppReply
 	err = rpcClient.CallAuthed("UpdateApp", &arg, &reply)
@@ -299,6 +313,12 @@ func LogApp(app *App) {
 	Log("-> Repo:  %s", app.Repo)
 	Log("-> Root:  %s", app.Root)
 	Log("-> Email: %s", app.Email)
+	if app.Placement != "" {
+		Log("-> Placement: %s", app.Placement)
+	}
+	if app.SupervisorSelector != "" {
+		Log("-> SupervisorSelector: %s", app.SupervisorSelector)
+	}
 	Log("-> DependerEnvData:")
 	for env, envData := range app.DependerEnvData {
 		Log("->   %s:", env)
@@ -309,6 +329,10 @@ func LogApp(app *App) {
 		Log("->   %s:", app)
 		LogDependerAppData("    ", appData)
 	}
+	Log("-> Canaries:")
+	for env, canary := range app.Canaries {
+		Log("->   %s: %s @ %d%%", env, canary.Sha, canary.Weight)
+	}
 }
 
 type GetAppCommand struct {
@@ -586,8 +610,9 @@ func (c *GetSelfCommand) Execute(args []string) error {
 }
 
 type RegisterSupervisorCommand struct {
-	Wait bool   `long:"wait" description:"wait until done before exiting"`
-	Host string `short:"H" long:"host" description:"the supervisor host to register"`
+	Wait   bool   `long:"wait" description:"wait until done before exiting"`
+	Host   string `short:"H" long:"host" description:"the supervisor host to register"`
+	Labels string `short:"l" long:"labels" description:"key=value,... labels to give the supervisor"`
 }
 
 func (c *RegisterSupervisorCommand) Execute(args []string) error {
@@ -597,7 +622,11 @@ func (c *RegisterSupervisorCommand) Execute(args []string) error {
 	}
 	Log("Register Supervisor...")
 	args = ExtractArgs([]*string{&c.Host}, args)
-	arg := ManagerRegisterSupervisorArg{dummyAuthArg, c.Host}
+	labels, err := helper.ParseLabels([]string{c.Labels})
+	if err != nil {
+		return OutputError(err)
+	}
+	arg := ManagerRegisterSupervisorArg{dummyAuthArg, c.Host, labels}
 	var reply atlantis.AsyncReply
 	err = rpcClient.CallAuthed("RegisterSupervisor", &arg, &reply)
 	if err != nil {
@@ -622,7 +651,7 @@ func (c *UnregisterSupervisorCommand) Execute(args []string) error {
 	}
 	Log("Unregister Supervisor...")
 	args = ExtractArgs([]*string{&c.Host}, args)
-	arg := ManagerRegisterSupervisorArg{dummyAuthArg, c.Host}
+	arg := ManagerRegisterSupervisorArg{dummyAuthArg, c.Host, nil}
 	var reply atlantis.AsyncReply
 	err = rpcClient.CallAuthed("UnregisterSupervisor", &arg, &reply)
 	if err != nil {
@@ -677,6 +706,8 @@ func (c *UnregisterSupervisorResultCommand) Execute(args []string) error {
 }
 
 type ListSupervisorsCommand struct {
+	Selector string `short:"s" long:"selector" description:"only list the supervisors with these labels, e.g. ssd=true,tier!=batch"`
+	Labels   bool   `short:"l" long:"labels" description:"show the labels of the supervisors"`
 }
 
 func (c *ListSupervisorsCommand) Execute(args []string) error {
@@ -685,7 +716,7 @@ func (c *ListSupervisorsCommand) Execute(args []string) error {
 		return OutputError(err)
 	}
 	Log("List Supervisors..")
-	arg := ManagerListSupervisorsArg{dummyAuthArg}
+	arg := ManagerListSupervisorsArg{dummyAuthArg, c.Selector}
 	var reply ManagerListSupervisorsReply
 	err = rpcClient.CallAuthed("ListSupervisors", &arg, &reply)
 	if err != nil {
@@ -693,8 +724,69 @@ func (c *ListSupervisorsCommand) Execute(args []string) error {
 	}
 	Log("-> status: %s", reply.Status)
 	for _, supervisor := range reply.Supervisors {
-		Log("->   %s", supervisor)
+		line := supervisor
+		if c.Labels && len(reply.Labels[supervisor]) > 0 {
+			line += " " + labelString(reply.Labels[supervisor])
+		}
+		if inventory := reply.Inventory[supervisor]; inventory == nil {
+			line += " [no heartbeat yet]"
+		} else if inventory.State == SupervisorDown {
+			lastSeen := "never"
+			if inventory.LastSeen > 0 {
+				lastSeen = time.Unix(inventory.LastSeen, 0).Format(time.RFC3339)
+			}
+			line += fmt.Sprintf(" [down, last seen %s: %s]", lastSeen, inventory.LastError)
+		}
+		Log("->   %s", line)
+	}
+	if c.Labels {
+		return Output(map[string]interface{}{"status": reply.Status, "supervisors": reply.Supervisors,
+			"labels": reply.Labels, "inventory": reply.Inventory}, reply.Supervisors, nil)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "supervisors": reply.Supervisors,
+		"inventory": reply.Inventory}, reply.Supervisors, nil)
+}
+
+// labels as key=value,... sorted by key
+func labelString(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+type LabelSupervisorCommand struct {
+	Host   string `short:"H" long:"host" description:"the supervisor host to label"`
+	Remove string `short:"r" long:"remove" description:"key,... of the labels to remove"`
+}
+
+// the positional args after the host are the key=value labels to set
+func (c *LabelSupervisorCommand) Execute(args []string) error {
+	err := Init()
+	if err != nil {
+		return OutputError(err)
 	}
-	return Output(map[string]interface{}{"status": reply.Status, "supervisors": reply.Supervisors}, reply.Supervisors,
-		nil)
+	Log("Label Supervisor...")
+	args = ExtractArgs([]*string{&c.Host}, args)
+	labels, err := helper.ParseLabels(args)
+	if err != nil {
+		return OutputError(err)
+	}
+	remove := []string{}
+	for _, key := range strings.Split(c.Remove, ",") {
+		if key != "" {
+			remove = append(remove, key)
+		}
+	}
+	arg := ManagerLabelSupervisorArg{dummyAuthArg, c.Host, labels, remove}
+	var reply ManagerLabelSupervisorReply
+	err = rpcClient.CallAuthed("LabelSupervisor", &arg, &reply)
+	if err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	Log("-> labels: %s", labelString(reply.Labels))
+	return Output(map[string]interface{}{"status": reply.Status, "labels": reply.Labels}, reply.Labels, nil)
 }
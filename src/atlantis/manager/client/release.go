@@ -0,0 +1,108 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package client
+
+import (
+	atlantis "atlantis/common"
+	. "atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ReleaseCommand struct {
+	Env         string `short:"e" long:"env" description:"the environment to release to"`
+	Instances   uint   `short:"i" long:"instances" default:"0" description:"the number of instances of each app in each AZ (0 for the manifest's)"`
+	CPUShares   uint   `short:"c" long:"cpu-shares" default:"0" description:"the number of CPU shares per instance"`
+	MemoryLimit uint   `short:"m" long:"memory-limit" default:"0" description:"the MBytes of memory per instance"`
+	Replace     bool   `long:"replace" description:"swap traffic to the new shas and tear down the other shas in the env"`
+	DrainTime   uint   `long:"drain-time" default:"30" description:"seconds to wait before tearing down the replaced shas"`
+	DryRun      bool   `long:"dry-run" description:"only plan the deploys"`
+	Override    string `long:"override-freeze" description:"the reason to deploy while the env is frozen (superusers only)"`
+	Placement   string `long:"placement" description:"spread, binpack or cost to override how each app's containers are placed"`
+	Selector    string `long:"selector" description:"the supervisor labels to deploy to on top of each app's, e.g. tier=batch"`
+	Wait        bool   `long:"wait" description:"wait until every app is deployed before exiting"`
+}
+
+// the positional args are the app:sha pairs to release
+func (c *ReleaseCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Release...")
+	deploys := []ReleaseDeploy{}
+	for _, appSha := range args {
+		parts := strings.SplitN(appSha, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return OutputError(errors.New(fmt.Sprintf("Invalid deploy %s. Should be app:sha", appSha)))
+		}
+		deploys = append(deploys, ReleaseDeploy{parts[0], parts[1]})
+	}
+	arg := ManagerReleaseArg{
+		ManagerDeployArg: ManagerDeployArg{
+			ManagerAuthArg: dummyAuthArg,
+			Env:            c.Env,
+			Instances:      c.Instances,
+			CPUShares:      c.CPUShares,
+			MemoryLimit:    c.MemoryLimit,
+			Replace:        c.Replace,
+			DrainTime:      c.DrainTime,
+			DryRun:         c.DryRun,
+			OverrideFreeze: c.Override,
+			Placement:      c.Placement,
+			Selector:       c.Selector,
+		},
+		Deploys: deploys,
+	}
+	var reply atlantis.AsyncReply
+	if err := rpcClient.CallAuthed("Release", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> ID: %s", reply.ID)
+	if !c.Wait {
+		return Output(map[string]interface{}{"id": reply.ID}, reply.ID, nil)
+	}
+	return (&WaitCommand{reply.ID}).Execute(nil)
+}
+
+type ReleaseResultCommand struct {
+	ID string `short:"i" long:"id" description:"the task ID to fetch the result for"`
+}
+
+func (c *ReleaseResultCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.ID}, args)
+	Log("Release Result...")
+	var reply ManagerReleaseReply
+	if err := rpcClient.Call("ReleaseResult", c.ID, &reply); err != nil {
+		return OutputError(err)
+	}
+	var err error
+	if reply.Error != "" {
+		err = errors.New(reply.Error)
+	}
+	Log("-> Status: %s", reply.Status)
+	Log("-> Order: %s", strings.Join(reply.Order, ", "))
+	Log("-> Deploys:")
+	for _, deploy := range reply.Deploys {
+		if deploy.Error != "" {
+			Log("->   %s @ %s (task %s): %s (%s)", deploy.App, deploy.Sha, deploy.TaskID, deploy.Status, deploy.Error)
+		} else {
+			Log("->   %s @ %s (task %s): %s x%d", deploy.App, deploy.Sha, deploy.TaskID, deploy.Status,
+				len(deploy.Containers))
+		}
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "order": reply.Order, "deploys": reply.Deploys},
+		reply.Deploys, err)
+}
@@ -13,6 +13,7 @@ package client
 
 import (
 	. "atlantis/common"
+	. "atlantis/manager/rpc/types"
 	"errors"
 	"time"
 )
@@ -61,6 +62,14 @@ func (c *ResultCommand) Execute(args []string) error {
 		return (&DeployResultCommand{c.ID}).Execute(args)
 	case "Deploy":
 		return (&DeployResultCommand{c.ID}).Execute(args)
+	case "DeployManifest", "ApproveDeploy", "Promote":
+		return (&DeployResultCommand{c.ID}).Execute(args)
+	case "Rollback":
+		return (&DeployResultCommand{c.ID}).Execute(args)
+	case "Release":
+		return (&ReleaseResultCommand{c.ID}).Execute(args)
+	case "Scale":
+		return (&ScaleResultCommand{c.ID}).Execute(args)
 	case "Teardown":
 		return (&TeardownResultCommand{c.ID}).Execute(args)
 	case "RegisterManager":
@@ -124,3 +133,26 @@ func (c *ListTaskIDsCommand) Execute(args []string) error {
 	}
 	return Output(map[string]interface{}{"ids": ids}, ids, nil)
 }
+
+type CancelCommand struct {
+	ID   string `short:"i" long:"id" description:"the task ID to cancel"`
+	Wait bool   `long:"wait" description:"wait until the task has stopped before exiting"`
+}
+
+func (c *CancelCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	args = ExtractArgs([]*string{&c.ID}, args)
+	Log("Cancel...")
+	arg := ManagerCancelTaskArg{ManagerAuthArg: dummyAuthArg, ID: c.ID}
+	var reply ManagerCancelTaskReply
+	if err := rpcClient.CallAuthed("CancelTask", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> Status: %s", reply.Status)
+	if !c.Wait {
+		return Output(map[string]interface{}{"status": reply.Status}, reply.Status, nil)
+	}
+	return (&WaitCommand{c.ID}).Execute(args)
+}
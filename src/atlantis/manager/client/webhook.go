@@ -0,0 +1,84 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package client
+
+import (
+	. "atlantis/manager/rpc/types"
+)
+
+func OutputWebhook(webhook *Webhook) {
+	app := webhook.App
+	if app == "" {
+		app = "every app"
+	}
+	Log("->   %s: %s (%s, added by %s)", webhook.ID, webhook.URL, app, webhook.User)
+}
+
+type AddWebhookCommand struct {
+	URL string `short:"u" long:"url" description:"the URL to POST task events to"`
+	App string `short:"a" long:"app" description:"only send events for this app's tasks (default: every task)"`
+}
+
+func (c *AddWebhookCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Add Webhook...")
+	arg := ManagerWebhookArg{dummyAuthArg, Webhook{URL: c.URL, App: c.App}}
+	var reply ManagerWebhookReply
+	if err := rpcClient.CallAuthed("AddWebhook", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	OutputWebhook(reply.Webhook)
+	return Output(map[string]interface{}{"status": reply.Status, "webhook": reply.Webhook}, reply.Webhook.ID, nil)
+}
+
+type DeleteWebhookCommand struct {
+	ID string `short:"i" long:"id" description:"the ID of the webhook"`
+}
+
+func (c *DeleteWebhookCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("Delete Webhook...")
+	arg := ManagerWebhookArg{dummyAuthArg, Webhook{ID: c.ID}}
+	var reply ManagerWebhookReply
+	if err := rpcClient.CallAuthed("DeleteWebhook", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	return Output(map[string]interface{}{"status": reply.Status}, nil, nil)
+}
+
+type ListWebhooksCommand struct {
+	App string `short:"a" long:"app" description:"only list this app's webhooks (default: every webhook)"`
+}
+
+func (c *ListWebhooksCommand) Execute(args []string) error {
+	if err := Init(); err != nil {
+		return OutputError(err)
+	}
+	Log("List Webhooks...")
+	arg := ManagerListWebhooksArg{dummyAuthArg, c.App}
+	var reply ManagerListWebhooksReply
+	if err := rpcClient.CallAuthed("ListWebhooks", &arg, &reply); err != nil {
+		return OutputError(err)
+	}
+	Log("-> status: %s", reply.Status)
+	Log("-> webhooks:")
+	for _, webhook := range reply.Webhooks {
+		OutputWebhook(webhook)
+	}
+	return Output(map[string]interface{}{"status": reply.Status, "webhooks": reply.Webhooks}, reply.Webhooks, nil)
+}
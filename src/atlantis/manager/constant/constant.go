@@ -38,4 +38,31 @@ const (
 	DefaultSuperUserOnlyCheckInterval = "5s"
 	DefaultMinRouterPort              = uint16(49152)
 	DefaultMaxRouterPort              = uint16(65535)
+	DefaultHealthzTimeout             = "0"
+	DefaultMinHealthyPercent          = uint(100)
+	DefaultDeployHostTimeout          = "5m"
+	DefaultDeployHostRetries          = uint(3)
+	DefaultDeployRetryBackoff         = "5s"
+	DefaultWebhookRetries             = uint(3)
+	DefaultWebhookRetryBackoff        = "5s"
+	StatusCancelled                   = "CANCELLED"
+	DeployRequestPending              = "PENDING"
+	DeployRequestApproved             = "APPROVED"
+	DeployRequestDenied               = "DENIED"
+	ScheduledDeployPending            = "PENDING"
+	ScheduledDeployStarted            = "STARTED"
+	ScheduledDeployFailed             = "FAILED"
+	ScheduledDeployCancelled          = "CANCELLED"
+	DefaultScheduleCheckInterval      = "30s"
+	PlacementSpread                   = "spread"
+	PlacementBinpack                  = "binpack"
+	PlacementCost                     = "cost"
+	DefaultPlacement                  = PlacementSpread
+	SupervisorUp                      = "up"
+	SupervisorDown                    = "down"
+	SupervisorUnknown                 = "unknown" // no heartbeat recorded yet
+	DefaultHeartbeatInterval          = "15s"
+	DefaultMaxMissedHeartbeats        = uint(3)
+	DefaultDrainTime                  = uint(30)
+	DefaultMaxFailPct                 = uint(50)
 )
@@ -0,0 +1,112 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/rpc/types"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// What the affinity rules of app say about a supervisor. counts is app -> # of containers the supervisor has in
+// the env being deployed to, and own is how many of them count for a rule of app with itself (only the ones of the
+// sha being deployed for a replace deploy, since it tears the other shas down). returns the weight to add for the
+// soft rules, whether only one more container of app may go on the supervisor, and why a hard rule rules it out
+// ("" if none does).
+func checkAffinity(app string, rules map[string]*types.AffinityRule, counts map[string]int,
+	own int) (weight float64, onlyOne bool, violation string) {
+	others := make([]string, 0, len(rules))
+	for other := range rules {
+		others = append(others, other)
+	}
+	sort.Strings(others)
+	violations := []string{}
+	for _, other := range others {
+		rule := rules[other]
+		count := counts[other]
+		if other == app {
+			count = own
+		}
+		switch {
+		case rule.Anti && rule.Hard:
+			if count > 0 {
+				violations = append(violations, fmt.Sprintf("it already has %d of %s (anti-affinity)", count,
+					other))
+			}
+			onlyOne = onlyOne || other == app
+		case rule.Anti:
+			// the same as spread weighs the app's own containers
+			weight += float64(2 * count)
+		case rule.Hard:
+			if count == 0 {
+				violations = append(violations, fmt.Sprintf("it has none of %s (affinity)", other))
+			}
+		default:
+			if count > 0 {
+				weight -= 2
+			}
+		}
+	}
+	return weight, onlyOne, strings.Join(violations, ", ")
+}
+
+// other app -> the rule it has with app, for the other apps a supervisor has (counts as for checkAffinity). cache
+// keeps the rules of the apps looked up so far.
+func rulesWithApp(app string, counts map[string]int,
+	cache map[string]map[string]*types.AffinityRule) map[string]*types.AffinityRule {
+	withApp := map[string]*types.AffinityRule{}
+	for other, count := range counts {
+		if other == app || count == 0 {
+			continue
+		}
+		otherRules, ok := cache[other]
+		if !ok {
+			if zkOther, err := GetApp(other); err == nil {
+				otherRules = zkOther.Affinities
+			}
+			cache[other] = otherRules
+		}
+		if rule := otherRules[app]; rule != nil {
+			withApp[other] = rule
+		}
+	}
+	return withApp
+}
+
+// What the rules that the other apps on a supervisor have with app say about a container of app going on it.
+// rules is other app -> its rule with app and counts is as for checkAffinity. returns the weight to add for the soft
+// rules and why a hard anti-affinity rule rules the supervisor out ("" if none does). a hard affinity rule only
+// says where the other app goes, so it doesn't rule anything out for app.
+func checkReverseAffinity(app string, rules map[string]*types.AffinityRule,
+	counts map[string]int) (weight float64, violation string) {
+	others := make([]string, 0, len(rules))
+	for other := range rules {
+		others = append(others, other)
+	}
+	sort.Strings(others)
+	violations := []string{}
+	for _, other := range others {
+		rule := rules[other]
+		switch {
+		case rule.Anti && rule.Hard:
+			violations = append(violations, fmt.Sprintf("it has %d of %s, which has an anti-affinity rule with %s",
+				counts[other], other, app))
+		case rule.Anti:
+			weight += float64(2 * counts[other])
+		case rule.Hard:
+		default:
+			weight -= 2
+		}
+	}
+	return weight, strings.Join(violations, ", ")
+}
@@ -0,0 +1,88 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	. "launchpad.net/gocheck"
+)
+
+func (s *DatamodelSuite) TestCheckAffinity(c *C) {
+	rules := map[string]*types.AffinityRule{
+		"payments": &types.AffinityRule{App: "payments", Anti: true, Hard: true},
+		"api":      &types.AffinityRule{App: "api", Hard: true},
+	}
+	// a supervisor with api on it and only payments @ another sha, which a replace deploy doesn't count
+	weight, onlyOne, violation := checkAffinity("payments", rules, map[string]int{"api": 1, "payments": 1}, 0)
+	c.Assert(weight, Equals, float64(0))
+	c.Assert(onlyOne, Equals, true)
+	c.Assert(violation, Equals, "")
+	_, _, violation = checkAffinity("payments", rules, map[string]int{"payments": 2}, 2)
+	c.Assert(violation, Equals, "it has none of api (affinity), it already has 2 of payments (anti-affinity)")
+
+	soft := map[string]*types.AffinityRule{
+		"batch": &types.AffinityRule{App: "batch", Anti: true},
+		"api":   &types.AffinityRule{App: "api"},
+	}
+	weight, onlyOne, violation = checkAffinity("cache-sidecar", soft, map[string]int{"api": 3, "batch": 2}, 0)
+	c.Assert(weight, Equals, float64(2))
+	c.Assert(onlyOne, Equals, false)
+	c.Assert(violation, Equals, "")
+	weight, _, _ = checkAffinity("cache-sidecar", soft, map[string]int{"api": 1}, 0)
+	c.Assert(weight, Equals, float64(-2))
+}
+
+func (s *DatamodelSuite) TestCheckReverseAffinity(c *C) {
+	// the rules that the apps on a supervisor have with api
+	rules := map[string]*types.AffinityRule{
+		"payments": &types.AffinityRule{App: "api", Anti: true, Hard: true},
+		"batch":    &types.AffinityRule{App: "api", Anti: true},
+		"frontend": &types.AffinityRule{App: "api", Hard: true},
+	}
+	weight, violation := checkReverseAffinity("api", rules, map[string]int{"payments": 1, "batch": 2, "frontend": 1})
+	c.Assert(weight, Equals, float64(4))
+	c.Assert(violation, Equals, "it has 1 of payments, which has an anti-affinity rule with api")
+	weight, violation = checkReverseAffinity("api", map[string]*types.AffinityRule{
+		"cache": &types.AffinityRule{App: "api"}}, map[string]int{"cache": 1})
+	c.Assert(weight, Equals, float64(-2))
+	c.Assert(violation, Equals, "")
+}
+
+func (s *DatamodelSuite) TestAffinityRules(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseAppPath())
+	zkApp, err := CreateOrUpdateApp(false, true, app, repo, root, "jigish@ooyala.com")
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.SetAffinityRule(&types.AffinityRule{App: app}), Not(IsNil))
+	c.Assert(zkApp.SetAffinityRule(&types.AffinityRule{App: app, Anti: true, Hard: true}), IsNil)
+	c.Assert(zkApp.SetAffinityRule(&types.AffinityRule{App: "unregistered"}), Not(IsNil))
+	_, err = CreateOrUpdateApp(false, true, "other-app", repo, root, "jigish@ooyala.com")
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.SetAffinityRule(&types.AffinityRule{App: "other-app"}), IsNil)
+	// other-app is told about the rule app has with it when it is placed next to app
+	cache := map[string]map[string]*types.AffinityRule{}
+	withOther := rulesWithApp("other-app", map[string]int{app: 1, "unknown-app": 1}, cache)
+	c.Assert(len(withOther), Equals, 1)
+	c.Assert(withOther[app].App, Equals, "other-app")
+	c.Assert(len(rulesWithApp("other-app", map[string]int{app: 0}, cache)), Equals, 0)
+	zkApp, err = GetApp(app)
+	c.Assert(err, IsNil)
+	c.Assert(len(zkApp.Affinities), Equals, 2)
+	c.Assert(zkApp.Affinities[app].Hard, Equals, true)
+	c.Assert(zkApp.OnePerSupervisor(), Equals, true)
+	c.Assert(zkApp.RemoveAffinityRule(app), IsNil)
+	zkApp, err = GetApp(app)
+	c.Assert(err, IsNil)
+	c.Assert(len(zkApp.Affinities), Equals, 1)
+	c.Assert(zkApp.OnePerSupervisor(), Equals, false)
+	c.Assert(zkApp.Affinities["other-app"].Anti, Equals, false)
+}
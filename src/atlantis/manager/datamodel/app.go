@@ -76,6 +76,8 @@ func (za *ZkApp) Delete() error {
 			return err
 		}
 	}
+	// the cached manifests are only good for this registration
+	Zk.RecursiveDelete(helper.GetBaseManifestPath(za.Name))
 	return Zk.RecursiveDelete(za.path())
 }
 
@@ -209,6 +211,103 @@ func (za *ZkApp) GetDependerEnvDataForDependerApp(app, env string, decrypt bool)
 	return ded
 }
 
+func (za *ZkApp) SetCanary(env, sha string, weight uint) error {
+	if za.Canaries == nil {
+		za.Canaries = map[string]*types.Canary{}
+	}
+	za.Canaries[env] = &types.Canary{Sha: sha, Weight: weight}
+	return za.Save()
+}
+
+func (za *ZkApp) RemoveCanary(env string) error {
+	if za.Canaries == nil || za.Canaries[env] == nil {
+		return nil
+	}
+	delete(za.Canaries, env)
+	return za.Save()
+}
+
+func (za *ZkApp) GetCanary(env string) *types.Canary {
+	if za.Canaries == nil {
+		return nil
+	}
+	return za.Canaries[env]
+}
+
+func (za *ZkApp) SetPromotionRule(env string, rule *types.PromotionRule) error {
+	if za.Promotions == nil {
+		za.Promotions = map[string]*types.PromotionRule{}
+	}
+	if _, err := GetEnv(env); err != nil {
+		return err
+	}
+	if _, err := GetEnv(rule.From); err != nil {
+		return err
+	}
+	za.Promotions[env] = rule
+	return za.Save()
+}
+
+func (za *ZkApp) RemovePromotionRule(env string) error {
+	if za.Promotions == nil || za.Promotions[env] == nil {
+		return nil
+	}
+	delete(za.Promotions, env)
+	return za.Save()
+}
+
+func (za *ZkApp) GetPromotionRule(env string) *types.PromotionRule {
+	if za.Promotions == nil {
+		return nil
+	}
+	return za.Promotions[env]
+}
+
+func (za *ZkApp) SetAffinityRule(rule *types.AffinityRule) error {
+	if rule.App == za.Name {
+		if !rule.Anti {
+			return errors.New("an app can only have an anti-affinity rule with itself")
+		}
+	} else if _, err := GetApp(rule.App); err != nil {
+		return errors.New("app " + rule.App + " is not registered")
+	}
+	if za.Affinities == nil {
+		za.Affinities = map[string]*types.AffinityRule{}
+	}
+	za.Affinities[rule.App] = rule
+	return za.Save()
+}
+
+// true if a hard anti-affinity rule of the app with itself keeps each of its containers on its own supervisor
+func (za *ZkApp) OnePerSupervisor() bool {
+	rule := za.Affinities[za.Name]
+	return rule != nil && rule.Anti && rule.Hard
+}
+
+func (za *ZkApp) RemoveAffinityRule(app string) error {
+	if za.Affinities == nil || za.Affinities[app] == nil {
+		return nil
+	}
+	delete(za.Affinities, app)
+	return za.Save()
+}
+
+func (za *ZkApp) SetPlacement(placement string) error {
+	if _, err := GetPlacementStrategy(placement); err != nil {
+		return err
+	}
+	za.Placement = placement
+	return za.Save()
+}
+
+func (za *ZkApp) SetSupervisorSelector(selector string) error {
+	if _, err := helper.ParseLabelSelector(selector); err != nil {
+		return err
+	}
+	za.SupervisorSelector = selector
+	return za.Save()
+}
+
 func ListRegisteredApps() (apps []string, err error) {
 	apps, _, err = Zk.VisibleChildren(helper.GetBaseAppPath())
 	if err != nil {
@@ -125,3 +125,28 @@ func (s *DatamodelSuite) TestApp(c *C) {
 	c.Assert(prodEnvData, Not(IsNil))
 	c.Assert(prodEnvData.DataMap, Not(IsNil))
 }
+
+func (s *DatamodelSuite) TestPromotionRules(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseAppPath())
+	Zk.RecursiveDelete(helper.GetBaseEnvPath())
+	Env("prod").Save()
+	Env("staging").Save()
+	zkApp, err := CreateOrUpdateApp(false, true, app, repo, root, "jigish@ooyala.com")
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.GetPromotionRule("prod"), IsNil)
+	c.Assert(zkApp.SetPromotionRule("prod", &types.PromotionRule{From: "nonexistent", MinTime: 3600}), Not(IsNil))
+	c.Assert(zkApp.SetPromotionRule("nonexistent", &types.PromotionRule{From: "staging"}), Not(IsNil))
+	c.Assert(zkApp.SetPromotionRule("prod", &types.PromotionRule{From: "staging", MinTime: 3600}), IsNil)
+	zkApp, err = GetApp(app)
+	c.Assert(err, IsNil)
+	rule := zkApp.GetPromotionRule("prod")
+	c.Assert(rule, Not(IsNil))
+	c.Assert(rule.From, Equals, "staging")
+	c.Assert(rule.MinTime, Equals, int64(3600))
+	c.Assert(zkApp.GetPromotionRule("staging"), IsNil)
+	c.Assert(zkApp.RemovePromotionRule("prod"), IsNil)
+	zkApp, err = GetApp(app)
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.GetPromotionRule("prod"), IsNil)
+	c.Assert(zkApp.RemovePromotionRule("prod"), IsNil)
+}
@@ -42,8 +42,10 @@ func CreateRouterPaths() {
 
 func CreateLockPaths() {
 	Zk.Touch(helper.GetBaseLockPath("deploy"))
+	Zk.Touch(helper.GetBaseLockPath("scheduled_deploy"))
 	Zk.Touch(helper.GetBaseLockPath("router_ports_internal"))
 	Zk.Touch(helper.GetBaseLockPath("router_ports_external"))
+	Zk.Touch(helper.GetBaseLockPath("supervisor_heartbeat"))
 }
 
 func CreateAppPath() {
@@ -59,6 +61,10 @@ func CreateSupervisorPath() {
 	Zk.Touch(helper.GetBaseSupervisorPath())
 }
 
+func CreateSupervisorInventoryPath() {
+	Zk.Touch(helper.GetBaseSupervisorInventoryPath())
+}
+
 func CreateManagerPath() {
 	Zk.Touch(helper.GetBaseManagerPath())
 }
@@ -67,6 +73,30 @@ func CreateEnvPath() {
 	Zk.Touch(helper.GetBaseEnvPath())
 }
 
+func CreateDeployHistoryPath() {
+	Zk.Touch(helper.GetBaseDeployHistoryPath())
+}
+
+func CreateReleasePath() {
+	Zk.Touch(helper.GetBaseReleasePath())
+}
+
+func CreateManifestPath() {
+	Zk.Touch(helper.GetBaseManifestPath())
+}
+
+func CreateDeployRequestPath() {
+	Zk.Touch(helper.GetBaseDeployRequestPath())
+}
+
+func CreateWebhookPath() {
+	Zk.Touch(helper.GetBaseWebhookPath())
+}
+
+func CreateScheduledDeployPath() {
+	Zk.Touch(helper.GetBaseScheduledDeployPath())
+}
+
 func CreatePaths() {
 	CreateRouterPortsPaths()
 	CreateRouterPaths()
@@ -74,8 +104,15 @@ func CreatePaths() {
 	CreateInstancePaths()
 	CreateAppPath()
 	CreateSupervisorPath()
+	CreateSupervisorInventoryPath()
 	CreateManagerPath()
 	CreateEnvPath()
+	CreateDeployHistoryPath()
+	CreateReleasePath()
+	CreateManifestPath()
+	CreateDeployRequestPath()
+	CreateWebhookPath()
+	CreateScheduledDeployPath()
 }
 
 func Init(zkUri string) {
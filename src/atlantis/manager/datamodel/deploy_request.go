@@ -0,0 +1,81 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+)
+
+// the number of approved and denied requests kept. pending requests are never pruned.
+var MaxDeployRequests = 100
+
+type ZkDeployRequest types.DeployRequest
+
+// Saves a new pending request, giving it an ID that sorts by time, and prunes the oldest decided requests.
+func AddDeployRequest(request *types.DeployRequest) error {
+	zr := ZkDeployRequest(*request)
+	zr.ID = newRecordID()
+	zr.Status = DeployRequestPending
+	if err := zr.Save(); err != nil {
+		return err
+	}
+	request.ID = zr.ID
+	request.Status = zr.Status
+	pruneQueuedDeploys(helper.GetBaseDeployRequestPath(), MaxDeployRequests, readDeployRequest)
+	return nil
+}
+
+func GetDeployRequest(id string) (*types.DeployRequest, error) {
+	request := &types.DeployRequest{}
+	if err := getJson(helper.GetBaseDeployRequestPath(id), request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Saves the decision (and task) of a request
+func UpdateDeployRequest(request *types.DeployRequest) error {
+	zr := ZkDeployRequest(*request)
+	return zr.Save()
+}
+
+func (zr *ZkDeployRequest) Save() error {
+	return setJson(zr.path(), zr)
+}
+
+func (zr *ZkDeployRequest) path() string {
+	return helper.GetBaseDeployRequestPath(zr.ID)
+}
+
+func (zr *ZkDeployRequest) deployArg() *types.ManagerDeployArg {
+	return &zr.Arg
+}
+
+func (zr *ZkDeployRequest) pending() bool {
+	return zr.Status == DeployRequestPending
+}
+
+func readDeployRequest(id string) (queuedDeploy, error) {
+	zr := &ZkDeployRequest{}
+	return zr, getJson(helper.GetBaseDeployRequestPath(id), zr)
+}
+
+// Returns the requests for app and env (empty for any), oldest first. only pending requests unless all is set.
+func ListDeployRequests(app, env string, all bool) ([]*types.DeployRequest, error) {
+	requests := []*types.DeployRequest{}
+	for _, queued := range listQueuedDeploys(helper.GetBaseDeployRequestPath(), app, env, all, readDeployRequest) {
+		requests = append(requests, (*types.DeployRequest)(queued.(*ZkDeployRequest)))
+	}
+	return requests, nil
+}
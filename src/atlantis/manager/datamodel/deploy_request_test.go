@@ -0,0 +1,72 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	. "launchpad.net/gocheck"
+)
+
+func (s *DatamodelSuite) TestDeployRequests(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseDeployRequestPath())
+	CreateDeployRequestPath()
+	requests, err := ListDeployRequests("", "", true)
+	c.Assert(err, IsNil)
+	c.Assert(len(requests), Equals, 0)
+
+	MaxDeployRequests = 1
+	ids := []string{}
+	for _, theSha := range []string{"sha1", "sha2", "sha3"} {
+		request := &types.DeployRequest{User: "user", Arg: types.ManagerDeployArg{App: app, Sha: theSha, Env: env}}
+		c.Assert(AddDeployRequest(request), IsNil)
+		c.Assert(request.ID, Not(Equals), "")
+		c.Assert(request.Status, Equals, DeployRequestPending)
+		ids = append(ids, request.ID)
+	}
+	requests, err = ListDeployRequests(app, env, false)
+	c.Assert(err, IsNil)
+	c.Assert(len(requests), Equals, 3)
+	c.Assert(requests[0].Arg.Sha, Equals, "sha1")
+	requests, err = ListDeployRequests(app, "otherenv", false)
+	c.Assert(err, IsNil)
+	c.Assert(len(requests), Equals, 0)
+
+	// decided requests are only listed with all
+	request, err := GetDeployRequest(ids[0])
+	c.Assert(err, IsNil)
+	request.Status = DeployRequestDenied
+	request.DecidedBy = "admin"
+	c.Assert(UpdateDeployRequest(request), IsNil)
+	request, err = GetDeployRequest(ids[1])
+	c.Assert(err, IsNil)
+	request.Status = DeployRequestApproved
+	c.Assert(UpdateDeployRequest(request), IsNil)
+	requests, err = ListDeployRequests("", "", false)
+	c.Assert(err, IsNil)
+	c.Assert(len(requests), Equals, 1)
+	c.Assert(requests[0].Arg.Sha, Equals, "sha3")
+	requests, err = ListDeployRequests("", "", true)
+	c.Assert(err, IsNil)
+	c.Assert(len(requests), Equals, 3)
+	c.Assert(requests[0].DecidedBy, Equals, "admin")
+
+	// only the newest decided request is kept once another is added
+	c.Assert(AddDeployRequest(&types.DeployRequest{User: "user", Arg: types.ManagerDeployArg{App: app,
+		Sha: "sha4", Env: env}}), IsNil)
+	requests, err = ListDeployRequests("", "", true)
+	c.Assert(err, IsNil)
+	c.Assert(len(requests), Equals, 3)
+	c.Assert(requests[0].Arg.Sha, Equals, "sha2")
+	MaxDeployRequests = 100
+}
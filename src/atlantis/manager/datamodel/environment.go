@@ -13,20 +13,25 @@ package datamodel
 
 import (
 	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
 )
 
 type ZkEnv struct {
-	Name string
+	Name      string
+	Freezes   []*types.Freeze
+	Protected bool // deploys have to be approved by a team admin of the app
 }
 
 func GetEnv(name string) (*ZkEnv, error) {
-	e := &ZkEnv{name}
+	e := &ZkEnv{Name: name}
 	err := e.Get()
 	return e, err
 }
 
 func Env(name string) *ZkEnv {
-	return &ZkEnv{name}
+	return &ZkEnv{Name: name}
 }
 
 func (e *ZkEnv) Save() error {
@@ -47,6 +52,32 @@ func (e *ZkEnv) Get() error {
 	return getJson(e.path(), e)
 }
 
+// Adds freeze to the env, replacing the freeze with the same name if there is one.
+func (e *ZkEnv) AddFreeze(freeze *types.Freeze) error {
+	freezes := []*types.Freeze{}
+	for _, old := range e.Freezes {
+		if old.Name != freeze.Name {
+			freezes = append(freezes, old)
+		}
+	}
+	e.Freezes = append(freezes, freeze)
+	return e.Save()
+}
+
+func (e *ZkEnv) DeleteFreeze(name string) error {
+	freezes := []*types.Freeze{}
+	for _, old := range e.Freezes {
+		if old.Name != name {
+			freezes = append(freezes, old)
+		}
+	}
+	if len(freezes) == len(e.Freezes) {
+		return errors.New(fmt.Sprintf("%s has no freeze named %s", e.Name, name))
+	}
+	e.Freezes = freezes
+	return e.Save()
+}
+
 func (e *ZkEnv) path() string {
 	return helper.GetBaseEnvPath(e.Name)
 }
@@ -13,6 +13,7 @@ package datamodel
 
 import (
 	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
 	. "launchpad.net/gocheck"
 )
 
@@ -34,3 +35,25 @@ func (s *DatamodelSuite) TestEnvironment(c *C) {
 	c.Assert(gRoot.Get(), IsNil)
 	c.Assert(gRoot, DeepEquals, root)
 }
+
+func (s *DatamodelSuite) TestEnvironmentFreezes(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseEnvPath())
+	root := Env("root")
+	c.Assert(root.Save(), IsNil)
+	c.Assert(root.AddFreeze(&types.Freeze{Name: "weekend", Reason: "no deploys on weekends",
+		WeeklyStart: "Fri 16:00", WeeklyEnd: "Mon 09:00"}), IsNil)
+	c.Assert(root.AddFreeze(&types.Freeze{Name: "incident", Reason: "outage", Start: 100}), IsNil)
+	c.Assert(root.AddFreeze(&types.Freeze{Name: "incident", Reason: "still an outage", Start: 100}), IsNil)
+	gRoot, err := GetEnv("root")
+	c.Assert(err, IsNil)
+	c.Assert(len(gRoot.Freezes), Equals, 2)
+	c.Assert(gRoot.Freezes[0].Name, Equals, "weekend")
+	c.Assert(gRoot.Freezes[1].Reason, Equals, "still an outage")
+
+	c.Assert(gRoot.DeleteFreeze("weekend"), IsNil)
+	c.Assert(gRoot.DeleteFreeze("weekend"), Not(IsNil))
+	gRoot, err = GetEnv("root")
+	c.Assert(err, IsNil)
+	c.Assert(len(gRoot.Freezes), Equals, 1)
+	c.Assert(gRoot.Freezes[0].Name, Equals, "incident")
+}
@@ -0,0 +1,110 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+)
+
+// the number of records kept per app+env. older records are deleted when new ones are added.
+var MaxDeployHistory = 100
+
+type ZkDeployRecord types.DeployRecord
+
+// Saves record under its app+env, giving it an ID that sorts by time, and prunes the oldest records.
+func AddDeployRecord(record *types.DeployRecord) error {
+	zr := ZkDeployRecord(*record)
+	zr.ID = newRecordID()
+	if err := zr.Save(); err != nil {
+		return err
+	}
+	record.ID = zr.ID
+	ids := listRecordIDs(helper.GetBaseDeployHistoryPath(zr.App, zr.Env))
+	for len(ids) > MaxDeployHistory {
+		Zk.RecursiveDelete(helper.GetBaseDeployHistoryPath(zr.App, zr.Env, ids[0]))
+		ids = ids[1:]
+	}
+	return nil
+}
+
+func (zr *ZkDeployRecord) Save() error {
+	return setJson(zr.path(), zr)
+}
+
+func (zr *ZkDeployRecord) path() string {
+	return helper.GetBaseDeployHistoryPath(zr.App, zr.Env, zr.ID)
+}
+
+// Returns up to limit (0 for all) records for app+env, newest first.
+func ListDeployHistory(app, env string, limit int) ([]*types.DeployRecord, error) {
+	ids := listRecordIDs(helper.GetBaseDeployHistoryPath(app, env))
+	history := []*types.DeployRecord{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		if limit > 0 && len(history) >= limit {
+			break
+		}
+		record := &types.DeployRecord{}
+		if err := getJson(helper.GetBaseDeployHistoryPath(app, env, ids[i]), record); err != nil {
+			continue // deleted out from under us
+		}
+		history = append(history, record)
+	}
+	return history, nil
+}
+
+// the number of releases kept per app+env for rollbacks
+var MaxReleases = 5
+
+// the shas that most recently served an app+env, newest first
+type ZkReleases struct {
+	App      string
+	Env      string
+	Releases []*types.Release
+}
+
+func GetReleases(app, env string) (*ZkReleases, error) {
+	zr := &ZkReleases{App: app, Env: env}
+	if err := getJson(zr.path(), zr); err != nil {
+		return zr, err
+	}
+	return zr, nil
+}
+
+// Makes release the current release of app+env. any older release of the same sha is dropped. if the sha already
+// is the current release (e.g. it was redeployed) it keeps the time it started serving.
+func AddRelease(app, env string, release *types.Release) error {
+	zr, err := GetReleases(app, env)
+	if err != nil {
+		// no releases yet
+		zr = &ZkReleases{App: app, Env: env}
+	}
+	if len(zr.Releases) > 0 && zr.Releases[0].Sha == release.Sha {
+		release.Time = zr.Releases[0].Time
+	}
+	releases := []*types.Release{release}
+	for _, old := range zr.Releases {
+		if old.Sha != release.Sha && len(releases) < MaxReleases {
+			releases = append(releases, old)
+		}
+	}
+	zr.Releases = releases
+	return zr.Save()
+}
+
+func (zr *ZkReleases) Save() error {
+	return setJson(zr.path(), zr)
+}
+
+func (zr *ZkReleases) path() string {
+	return helper.GetBaseReleasePath(zr.App, zr.Env)
+}
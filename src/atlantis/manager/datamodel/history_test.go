@@ -0,0 +1,80 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	stypes "atlantis/supervisor/rpc/types"
+	. "launchpad.net/gocheck"
+)
+
+func (s *DatamodelSuite) TestDeployHistory(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseDeployHistoryPath())
+	CreateDeployHistoryPath()
+	history, err := ListDeployHistory(app, env, 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(history), Equals, 0)
+
+	MaxDeployHistory = 3
+	for _, theSha := range []string{"sha1", "sha2", "sha3", "sha4"} {
+		record := &types.DeployRecord{TaskID: "task-" + theSha, Action: "Deploy", User: "user", App: app,
+			Sha: theSha, Env: env, Instances: 2, Status: "OK"}
+		c.Assert(AddDeployRecord(record), IsNil)
+		c.Assert(record.ID, Not(Equals), "")
+	}
+	history, err = ListDeployHistory(app, env, 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(history), Equals, 3)
+	c.Assert(history[0].Sha, Equals, "sha4")
+	c.Assert(history[1].Sha, Equals, "sha3")
+	c.Assert(history[2].Sha, Equals, "sha2")
+	c.Assert(history[0].TaskID, Equals, "task-sha4")
+	c.Assert(history[0].Instances, Equals, uint(2))
+
+	history, err = ListDeployHistory(app, env, 1)
+	c.Assert(err, IsNil)
+	c.Assert(len(history), Equals, 1)
+	c.Assert(history[0].Sha, Equals, "sha4")
+
+	history, err = ListDeployHistory(app, "otherenv", 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(history), Equals, 0)
+	MaxDeployHistory = 100
+}
+
+func (s *DatamodelSuite) TestReleases(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseReleasePath())
+	CreateReleasePath()
+	_, err := GetReleases(app, env)
+	c.Assert(err, Not(IsNil))
+
+	MaxReleases = 3
+	for _, theSha := range []string{"sha1", "sha2", "sha3", "sha2", "sha4"} {
+		release := &types.Release{Sha: theSha, Manifest: &stypes.Manifest{Name: app, Instances: 2}}
+		c.Assert(AddRelease(app, env, release), IsNil)
+	}
+	zr, err := GetReleases(app, env)
+	c.Assert(err, IsNil)
+	c.Assert(len(zr.Releases), Equals, 3)
+	c.Assert(zr.Releases[0].Sha, Equals, "sha4")
+	c.Assert(zr.Releases[1].Sha, Equals, "sha2")
+	c.Assert(zr.Releases[2].Sha, Equals, "sha3")
+	c.Assert(zr.Releases[0].Manifest.Instances, Equals, uint(2))
+	// redeploying the current release keeps the time it started serving
+	c.Assert(AddRelease(app, env, &types.Release{Sha: "sha5", Time: 100}), IsNil)
+	c.Assert(AddRelease(app, env, &types.Release{Sha: "sha5", Time: 200}), IsNil)
+	zr, err = GetReleases(app, env)
+	c.Assert(err, IsNil)
+	c.Assert(zr.Releases[0].Time, Equals, int64(100))
+	MaxReleases = 5
+}
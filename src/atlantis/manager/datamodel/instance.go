@@ -25,6 +25,7 @@ type ZkInstance struct {
 	Host     string
 	Port     uint16
 	Manifest *types.Manifest
+	Zones    map[string]uint // zone -> # of containers requested for app+sha+env
 }
 
 func InstanceExists(id string) bool {
@@ -117,6 +118,11 @@ func (zi *ZkInstance) SetManifest(m *types.Manifest) error {
 	return setJson(zi.dataPath(), zi)
 }
 
+func (zi *ZkInstance) SetZones(zones map[string]uint) error {
+	zi.Zones = zones
+	return setJson(zi.dataPath(), zi)
+}
+
 func (zi *ZkInstance) path() string {
 	return helper.GetBaseInstancePath(zi.App, zi.Sha, zi.Env, zi.ID)
 }
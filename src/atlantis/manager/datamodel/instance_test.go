@@ -27,8 +27,9 @@ func (s *DatamodelSuite) TestInstance(c *C) {
 	c.Assert(externalInst.Sha, Equals, sha)
 	c.Assert(externalInst.Host, Equals, host)
 	c.Assert(externalInst.SetPort(uint16(1337)), IsNil)
+	c.Assert(externalInst.SetZones(map[string]uint{"a": 4, "b": 2}), IsNil)
 	otherExtInst, err := GetInstance(externalInst.ID)
-	c.Assert(*otherExtInst, Equals, *externalInst)
+	c.Assert(*otherExtInst, DeepEquals, *externalInst)
 	last, err := externalInst.Delete()
 	c.Assert(last, Equals, true)
 	c.Assert(err, IsNil)
@@ -0,0 +1,131 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	stypes "atlantis/supervisor/rpc/types"
+	zookeeper "github.com/jigish/gozk-recipes"
+	"time"
+)
+
+// Who started the last round of supervisor heartbeats, and when
+type heartbeatRound struct {
+	Manager string
+	Time    int64
+}
+
+// Claims the round of supervisor heartbeats due at now for manager. every manager tries to start a round every
+// interval, so this makes sure only one of them does. rounds may start a tenth of an interval early, so that the
+// manager that started the last round keeps starting them even if its clock drifts.
+func ClaimHeartbeatRound(manager string, now time.Time, interval time.Duration) (bool, error) {
+	path := helper.GetBaseLockPath("supervisor_heartbeat")
+	mutex := zookeeper.NewMutex(Zk.Conn, path)
+	if err := mutex.Lock(); err != nil {
+		return false, err
+	}
+	defer mutex.Unlock()
+	last := heartbeatRound{}
+	if err := getJson(path, &last); err != nil {
+		return false, err
+	}
+	if now.Sub(time.Unix(0, last.Time)) < interval-interval/10 {
+		return false, nil
+	}
+	return true, setJson(path, &heartbeatRound{manager, now.UnixNano()})
+}
+
+// the inventory of a supervisor after a heartbeat at now: health if the supervisor answered it, err if it didn't.
+// the supervisor is down once it has missed maxMissed heartbeats in a row.
+func nextInventory(inventory *types.SupervisorInventory, health *stypes.SupervisorHealthCheckReply, err error,
+	now time.Time, maxMissed uint) *types.SupervisorInventory {
+	next := *inventory
+	next.LastHeartbeat = now.Unix()
+	if err != nil {
+		next.MissedHeartbeats++
+		next.LastError = err.Error()
+		if next.MissedHeartbeats >= maxMissed {
+			next.State = SupervisorDown
+		}
+		return &next
+	}
+	next.State = SupervisorUp
+	next.Zone = health.Zone
+	next.Status = health.Status
+	next.Price = health.Price
+	next.TotalContainers = health.Containers.Total
+	next.TotalCPUShares = health.CPUShares.Total
+	next.TotalMemory = health.Memory.Total
+	next.LastSeen = now.Unix()
+	next.MissedHeartbeats = 0
+	next.LastError = ""
+	return &next
+}
+
+// sets what the containers on a supervisor use in its inventory. containers is every container on it, and insts
+// the ones whose instance could be found.
+func setUsage(inventory *types.SupervisorInventory, containers uint, insts []*ZkInstance) {
+	inventory.UsedContainers = containers
+	inventory.UsedCPUShares = 0
+	inventory.UsedMemory = 0
+	inventory.Running = map[string]map[string]map[string]int{}
+	for _, inst := range insts {
+		if inst.Manifest != nil {
+			inventory.UsedCPUShares += inst.Manifest.CPUShares
+			inventory.UsedMemory += inst.Manifest.MemoryLimit
+		}
+		if inventory.Running[inst.Env] == nil {
+			inventory.Running[inst.Env] = map[string]map[string]int{}
+		}
+		if inventory.Running[inst.Env][inst.App] == nil {
+			inventory.Running[inst.Env][inst.App] = map[string]int{}
+		}
+		inventory.Running[inst.Env][inst.App][inst.Sha]++
+	}
+}
+
+// Records a heartbeat of host at now (see nextInventory), along with what its containers use, and returns its new
+// inventory. this is the only place the containers of a supervisor are read, so placement doesn't have to.
+func RecordHeartbeat(host string, health *stypes.SupervisorHealthCheckReply, err error, now time.Time,
+	maxMissed uint) (*types.SupervisorInventory, error) {
+	inventory, getErr := GetSupervisorInventory(host)
+	if getErr != nil {
+		// a supervisor that has never answered isn't up until it does
+		inventory = &types.SupervisorInventory{Host: host, State: SupervisorDown, Status: StatusUnknown}
+	}
+	inventory = nextInventory(inventory, health, err, now, maxMissed)
+	if info, infoErr := Supervisor(host).Info(); infoErr == nil {
+		insts := []*ZkInstance{}
+		for container, _ := range info.PortMap {
+			if inst, instErr := GetInstance(container); instErr == nil {
+				insts = append(insts, inst)
+			}
+		}
+		setUsage(inventory, uint(len(info.PortMap)), insts)
+	}
+	return inventory, setJson(helper.GetBaseSupervisorInventoryPath(host), inventory)
+}
+
+func GetSupervisorInventory(host string) (*types.SupervisorInventory, error) {
+	inventory := &types.SupervisorInventory{}
+	if err := getJson(helper.GetBaseSupervisorInventoryPath(host), inventory); err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+func DeleteSupervisorInventory(host string) error {
+	return Zk.RecursiveDelete(helper.GetBaseSupervisorInventoryPath(host))
+}
@@ -0,0 +1,117 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	stypes "atlantis/supervisor/rpc/types"
+	"errors"
+	. "launchpad.net/gocheck"
+	"time"
+)
+
+func (s *DatamodelSuite) TestHeartbeats(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseSupervisorInventoryPath())
+	now := time.Unix(1400000000, 0)
+	health := &stypes.SupervisorHealthCheckReply{Zone: "us-east-1a", Status: StatusOk, Price: 1.5}
+	health.Containers.Total = 10
+	health.CPUShares.Total = 1000
+	health.Memory.Total = 8192
+	_, err := GetSupervisorInventory(host)
+	c.Assert(err, Not(IsNil))
+	inventory, err := RecordHeartbeat(host, health, nil, now, 2)
+	c.Assert(err, IsNil)
+	c.Assert(inventory.State, Equals, SupervisorUp)
+	c.Assert(inventory.Zone, Equals, "us-east-1a")
+	c.Assert(inventory.TotalMemory, Equals, uint(8192))
+	c.Assert(inventory.LastSeen, Equals, now.Unix())
+	// it takes 2 missed heartbeats in a row to be down
+	inventory, err = RecordHeartbeat(host, nil, errors.New("connection refused"), now.Add(time.Minute), 2)
+	c.Assert(err, IsNil)
+	c.Assert(inventory.State, Equals, SupervisorUp)
+	c.Assert(inventory.MissedHeartbeats, Equals, uint(1))
+	inventory, err = RecordHeartbeat(host, nil, errors.New("connection refused"), now.Add(2*time.Minute), 2)
+	c.Assert(err, IsNil)
+	c.Assert(inventory.State, Equals, SupervisorDown)
+	c.Assert(inventory.LastError, Equals, "connection refused")
+	inventory, err = GetSupervisorInventory(host)
+	c.Assert(err, IsNil)
+	c.Assert(inventory.State, Equals, SupervisorDown)
+	c.Assert(inventory.LastSeen, Equals, now.Unix())
+	c.Assert(inventory.Zone, Equals, "us-east-1a")
+	// and one answer to be up again
+	inventory, err = RecordHeartbeat(host, health, nil, now.Add(3*time.Minute), 2)
+	c.Assert(err, IsNil)
+	c.Assert(inventory.State, Equals, SupervisorUp)
+	c.Assert(inventory.MissedHeartbeats, Equals, uint(0))
+	c.Assert(inventory.LastError, Equals, "")
+	// a supervisor that never answered is down from the start
+	inventory, err = RecordHeartbeat(host+"1", nil, errors.New("timeout"), now, 2)
+	c.Assert(err, IsNil)
+	c.Assert(inventory.State, Equals, SupervisorDown)
+
+	c.Assert(DeleteSupervisorInventory(host), IsNil)
+	c.Assert(DeleteSupervisorInventory(host+"1"), IsNil)
+	_, err = GetSupervisorInventory(host)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *DatamodelSuite) TestClaimHeartbeatRound(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseLockPath("supervisor_heartbeat"))
+	now := time.Unix(1400000000, 0)
+	claimed, err := ClaimHeartbeatRound("manager1", now, time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(claimed, Equals, true)
+	claimed, err = ClaimHeartbeatRound("manager2", now.Add(30*time.Second), time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(claimed, Equals, false)
+	// a little early is fine
+	claimed, err = ClaimHeartbeatRound("manager1", now.Add(59*time.Second), time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(claimed, Equals, true)
+}
+
+func (s *DatamodelSuite) TestFitsOn(c *C) {
+	inventory := &types.SupervisorInventory{TotalContainers: 10, TotalCPUShares: 1000, TotalMemory: 8192}
+	c.Assert(fitsOn(inventory, 100, 512), Equals, uint(10))
+	inventory.UsedMemory = 6144
+	c.Assert(fitsOn(inventory, 100, 512), Equals, uint(4))
+	inventory.UsedContainers, inventory.UsedCPUShares, inventory.UsedMemory = 2, 700, 0
+	c.Assert(fitsOn(inventory, 100, 512), Equals, uint(3))
+	inventory.UsedContainers, inventory.UsedCPUShares = 10, 0
+	c.Assert(fitsOn(inventory, 100, 512), Equals, uint(0))
+	inventory.UsedContainers, inventory.UsedCPUShares = 0, 950
+	c.Assert(fitsOn(inventory, 100, 512), Equals, uint(0))
+}
+
+func (s *DatamodelSuite) TestSetUsage(c *C) {
+	inventory := &types.SupervisorInventory{UsedContainers: 7, Running: map[string]map[string]map[string]int{
+		"old": map[string]map[string]int{"gone": map[string]int{"sha": 1}}}}
+	setUsage(inventory, 4, []*ZkInstance{
+		&ZkInstance{App: "app", Sha: "sha1", Env: "prod", Manifest: &stypes.Manifest{CPUShares: 100, MemoryLimit: 512}},
+		&ZkInstance{App: "app", Sha: "sha2", Env: "prod", Manifest: &stypes.Manifest{CPUShares: 100, MemoryLimit: 512}},
+		&ZkInstance{App: "app", Sha: "sha2", Env: "prod", Manifest: &stypes.Manifest{CPUShares: 100, MemoryLimit: 512}},
+		&ZkInstance{App: "other", Sha: "sha", Env: "staging"},
+	})
+	c.Assert(inventory.UsedContainers, Equals, uint(4))
+	c.Assert(inventory.UsedCPUShares, Equals, uint(300))
+	c.Assert(inventory.UsedMemory, Equals, uint(1536))
+	c.Assert(inventory.CountAppShaEnv("app", "sha2", "prod"), Equals, 2)
+	c.Assert(inventory.CountAppShaEnv("app", "sha2", "staging"), Equals, 0)
+	c.Assert(inventory.CountAppShaEnv("gone", "sha", "old"), Equals, 0)
+	c.Assert(inventory.AppEnvCounts("prod"), DeepEquals, map[string]int{"app": 3})
+	c.Assert(inventory.AppEnvCounts("staging"), DeepEquals, map[string]int{"other": 1})
+	c.Assert(inventory.AppEnvCounts("dev"), DeepEquals, map[string]int{})
+}
@@ -0,0 +1,94 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	"atlantis/supervisor/rpc/types"
+	"sort"
+	"time"
+)
+
+// the number of manifests cached per app. the ones cached longest ago are deleted when new ones are added.
+var MaxCachedManifests = 20
+
+// the manifest built for app+sha, kept so that deploying the sha again doesn't need another build. Repo and
+// Root are what the app was registered with at the time so that re-registering the app invalidates it.
+type ZkManifest struct {
+	App      string
+	Sha      string
+	Repo     string
+	Root     string
+	Time     int64 // when it was cached (unix nanoseconds)
+	Manifest *types.Manifest
+}
+
+// Returns the manifest built for app+sha from repo+root, or nil if it hasn't been built yet.
+func GetCachedManifest(app, repo, root, sha string) *types.Manifest {
+	zm := &ZkManifest{App: app, Sha: sha}
+	if stat, err := Zk.Exists(zm.path()); err != nil || stat == nil {
+		return nil
+	}
+	if err := getJson(zm.path(), zm); err != nil || zm.Manifest == nil {
+		return nil
+	}
+	if zm.Repo != repo || zm.Root != root {
+		return nil
+	}
+	return zm.Manifest
+}
+
+// Caches the manifest built for app+sha and prunes the ones of app cached longest ago.
+func CacheManifest(app, repo, root, sha string, manifest *types.Manifest) error {
+	zm := &ZkManifest{App: app, Sha: sha, Repo: repo, Root: root, Time: time.Now().UnixNano(), Manifest: manifest}
+	if err := zm.Save(); err != nil {
+		return err
+	}
+	shas, _, err := Zk.VisibleChildren(helper.GetBaseManifestPath(app))
+	if err != nil || len(shas) <= MaxCachedManifests {
+		return nil
+	}
+	cached := ZkManifestList{}
+	for _, cachedSha := range shas {
+		old := &ZkManifest{App: app, Sha: cachedSha}
+		getJson(old.path(), old) // unreadable ones keep Time 0 so they go first
+		cached = append(cached, old)
+	}
+	sort.Sort(cached)
+	for _, old := range cached[:len(cached)-MaxCachedManifests] {
+		Zk.RecursiveDelete(old.path())
+	}
+	return nil
+}
+
+func (zm *ZkManifest) Save() error {
+	return setJson(zm.path(), zm)
+}
+
+func (zm *ZkManifest) path() string {
+	return helper.GetBaseManifestPath(zm.App, zm.Sha)
+}
+
+// sorts oldest first
+type ZkManifestList []*ZkManifest
+
+func (l ZkManifestList) Len() int {
+	return len(l)
+}
+
+func (l ZkManifestList) Less(i, j int) bool {
+	return l[i].Time < l[j].Time
+}
+
+func (l ZkManifestList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
@@ -0,0 +1,54 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	stypes "atlantis/supervisor/rpc/types"
+	"fmt"
+	. "launchpad.net/gocheck"
+)
+
+func (s *DatamodelSuite) TestManifestCache(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseManifestPath())
+	CreateManifestPath()
+	c.Assert(GetCachedManifest(app, "repo", "root", sha), IsNil)
+
+	c.Assert(CacheManifest(app, "repo", "root", sha, &stypes.Manifest{Name: app, Instances: 2}), IsNil)
+	manifest := GetCachedManifest(app, "repo", "root", sha)
+	c.Assert(manifest, Not(IsNil))
+	c.Assert(manifest.Name, Equals, app)
+	c.Assert(manifest.Instances, Equals, uint(2))
+
+	// other shas and registrations don't see it
+	c.Assert(GetCachedManifest(app, "repo", "root", "othersha"), IsNil)
+	c.Assert(GetCachedManifest(app, "otherrepo", "root", sha), IsNil)
+	c.Assert(GetCachedManifest(app, "repo", "otherroot", sha), IsNil)
+}
+
+func (s *DatamodelSuite) TestManifestCachePrune(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseManifestPath())
+	CreateManifestPath()
+	oldMax := MaxCachedManifests
+	defer func() { MaxCachedManifests = oldMax }()
+	MaxCachedManifests = 2
+	for i := 0; i < 4; i++ {
+		c.Assert(CacheManifest(app, "repo", "root", fmt.Sprintf("sha%d", i), &stypes.Manifest{Name: app}), IsNil)
+	}
+	c.Assert(CacheManifest("otherapp", "repo", "root", "sha0", &stypes.Manifest{Name: "otherapp"}), IsNil)
+	// only the last ones cached of each app are kept
+	c.Assert(GetCachedManifest(app, "repo", "root", "sha0"), IsNil)
+	c.Assert(GetCachedManifest(app, "repo", "root", "sha1"), IsNil)
+	c.Assert(GetCachedManifest(app, "repo", "root", "sha2"), Not(IsNil))
+	c.Assert(GetCachedManifest(app, "repo", "root", "sha3"), Not(IsNil))
+	c.Assert(GetCachedManifest("otherapp", "repo", "root", "sha0"), Not(IsNil))
+}
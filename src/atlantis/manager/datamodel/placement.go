@@ -0,0 +1,106 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/manager/constant"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// What a PlacementStrategy knows about a supervisor that has room for a new container of app @ sha in env
+type PlacementCandidate struct {
+	Supervisor  string
+	Count       int  // containers of app @ sha in env the supervisor already has
+	UsedCPU     uint // CPU shares used once the new container is on it
+	TotalCPU    uint
+	UsedMemory  uint // MBytes used once the new container is on it
+	TotalMemory uint
+	CPU         uint    // CPU shares of the new container
+	Memory      uint    // MBytes of the new container
+	Price       float64 // of the whole supervisor (from its health check)
+}
+
+func (c *PlacementCandidate) cpuUsed() float64 {
+	return float64(c.UsedCPU) / float64(c.TotalCPU)
+}
+
+func (c *PlacementCandidate) memoryUsed() float64 {
+	return float64(c.UsedMemory) / float64(c.TotalMemory)
+}
+
+// Weighs the supervisors a container could go on. ChooseSupervisorsList tries the lowest weights first.
+type PlacementStrategy interface {
+	Weight(c *PlacementCandidate) float64
+}
+
+// Spreads an app out: the fewer containers of the app @ sha in env and the less loaded the better.
+type SpreadPlacement struct{}
+
+func (p SpreadPlacement) Weight(c *PlacementCandidate) float64 {
+	// +2 weight for every one of this app/sha/env we see
+	return float64(2*c.Count) + c.memoryUsed() + c.cpuUsed()
+}
+
+// Packs containers tightly, e.g. for batch jobs: the more loaded the better, so that whole supervisors stay free.
+type BinpackPlacement struct{}
+
+func (p BinpackPlacement) Weight(c *PlacementCandidate) float64 {
+	return (1 - c.memoryUsed()) + (1 - c.cpuUsed())
+}
+
+// Puts containers where they are cheapest: the price of the part of the supervisor the container takes up.
+type CostPlacement struct{}
+
+func (p CostPlacement) Weight(c *PlacementCandidate) float64 {
+	share := (float64(c.CPU)/float64(c.TotalCPU) + float64(c.Memory)/float64(c.TotalMemory)) / 2
+	return c.Price * share
+}
+
+// How the containers of a deploy are placed
+type Placement struct {
+	Strategy PlacementStrategy // nil for DefaultPlacement
+	Selector string            // the supervisors also have to match, on top of the app's SupervisorSelector
+	// the deploy tears down the app's other shas in the env, so they don't count for its rule with itself
+	Replace bool
+}
+
+var placementStrategies = map[string]PlacementStrategy{
+	PlacementSpread:  SpreadPlacement{},
+	PlacementBinpack: BinpackPlacement{},
+	PlacementCost:    CostPlacement{},
+}
+
+// the names of the built-in strategies
+func PlacementStrategyNames() []string {
+	names := make([]string, 0, len(placementStrategies))
+	for name := range placementStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// returns the strategy called name ("" is DefaultPlacement)
+func GetPlacementStrategy(name string) (PlacementStrategy, error) {
+	if name == "" {
+		name = DefaultPlacement
+	}
+	strategy, ok := placementStrategies[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Invalid placement strategy %s. Should be one of %s", name,
+			strings.Join(PlacementStrategyNames(), ", ")))
+	}
+	return strategy, nil
+}
@@ -0,0 +1,70 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
+	. "launchpad.net/gocheck"
+	"sort"
+)
+
+func weighCandidates(strategy PlacementStrategy, candidates []*PlacementCandidate) []string {
+	list := SupervisorAndWeightList{}
+	for _, candidate := range candidates {
+		list = append(list, SupervisorAndWeight{Supervisor: candidate.Supervisor, Weight: strategy.Weight(candidate)})
+	}
+	sort.Sort(list)
+	hosts := make([]string, len(list))
+	for i, elem := range list {
+		hosts[i] = elem.Supervisor
+	}
+	return hosts
+}
+
+func (s *DatamodelSuite) TestPlacementStrategies(c *C) {
+	// a container of 100 shares and 512 MB on: an empty host, a half full host that already runs the app, and a
+	// nearly full host that is cheap
+	candidates := []*PlacementCandidate{
+		{Supervisor: "empty", Count: 0, UsedCPU: 100, TotalCPU: 1000, UsedMemory: 512, TotalMemory: 8192,
+			CPU: 100, Memory: 512, Price: 2},
+		{Supervisor: "half", Count: 1, UsedCPU: 600, TotalCPU: 1000, UsedMemory: 4608, TotalMemory: 8192,
+			CPU: 100, Memory: 512, Price: 1},
+		{Supervisor: "full", Count: 0, UsedCPU: 900, TotalCPU: 1000, UsedMemory: 7680, TotalMemory: 8192,
+			CPU: 100, Memory: 512, Price: 0.5},
+	}
+	spread, err := GetPlacementStrategy("")
+	c.Assert(err, IsNil)
+	c.Assert(spread, Equals, placementStrategies[DefaultPlacement])
+	c.Assert(weighCandidates(spread, candidates), DeepEquals, []string{"empty", "full", "half"})
+	binpack, err := GetPlacementStrategy(PlacementBinpack)
+	c.Assert(err, IsNil)
+	c.Assert(weighCandidates(binpack, candidates), DeepEquals, []string{"full", "half", "empty"})
+	cost, err := GetPlacementStrategy(PlacementCost)
+	c.Assert(err, IsNil)
+	c.Assert(weighCandidates(cost, candidates), DeepEquals, []string{"full", "half", "empty"})
+
+	_, err = GetPlacementStrategy("random")
+	c.Assert(err, ErrorMatches, "Invalid placement strategy random. Should be one of binpack, cost, spread")
+}
+
+func (s *DatamodelSuite) TestSetPlacement(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseAppPath())
+	zkApp, err := CreateOrUpdateApp(false, true, app, repo, root, "jigish@ooyala.com")
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.Placement, Equals, "")
+	c.Assert(zkApp.SetPlacement(PlacementBinpack), IsNil)
+	c.Assert(zkApp.SetPlacement("random"), Not(IsNil))
+	zkApp, err = GetApp(app)
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.Placement, Equals, PlacementBinpack)
+}
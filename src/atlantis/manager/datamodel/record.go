@@ -0,0 +1,84 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// deploy history, deploy requests and scheduled deploys are kept under a directory in ZK as records whose IDs
+// sort by when they were added
+
+// an ID for a record added now
+func newRecordID() string {
+	return fmt.Sprintf("%020d", time.Now().UnixNano())
+}
+
+// the IDs of the records under dir, oldest first
+func listRecordIDs(dir string) []string {
+	ids, _, err := Zk.VisibleChildren(dir)
+	if err != nil || ids == nil {
+		// no records yet
+		return []string{}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// a deploy that waits in a record until it is decided or started, e.g. a request waiting for approval
+type queuedDeploy interface {
+	deployArg() *types.ManagerDeployArg
+	pending() bool
+}
+
+// reads the queued deploy with id
+type queuedDeployReader func(id string) (queuedDeploy, error)
+
+// Deletes the oldest queued deploys under dir that are no longer pending until at most max of them are left.
+// pending ones are never pruned.
+func pruneQueuedDeploys(dir string, max int, read queuedDeployReader) {
+	done := []string{}
+	for _, id := range listRecordIDs(dir) {
+		if queued, err := read(id); err == nil && !queued.pending() {
+			done = append(done, id)
+		}
+	}
+	for len(done) > max {
+		Zk.RecursiveDelete(helper.JoinWithBase(dir, done[0]))
+		done = done[1:]
+	}
+}
+
+// Returns the queued deploys under dir for app and env (empty for any), oldest first. only pending ones unless
+// all is set.
+func listQueuedDeploys(dir, app, env string, all bool, read queuedDeployReader) []queuedDeploy {
+	deploys := []queuedDeploy{}
+	for _, id := range listRecordIDs(dir) {
+		queued, err := read(id)
+		if err != nil {
+			continue // deleted out from under us
+		}
+		arg := queued.deployArg()
+		if (app != "" && arg.App != app) || (env != "" && arg.Env != env) {
+			continue
+		}
+		if !all && !queued.pending() {
+			continue
+		}
+		deploys = append(deploys, queued)
+	}
+	return deploys
+}
@@ -28,6 +28,9 @@ var (
 	MaxRouterPort = DefaultMaxRouterPort
 )
 
+// the router rule type used to send a percentage of traffic to a canary pool
+const CanaryRuleType = "percentage"
+
 // Router Port Reservation
 
 type ZkRouterPorts types.RouterPorts
@@ -180,6 +183,147 @@ func UpdateAppEnvTrie(internal bool, app, sha, env string) (string, error) {
 	return trieName, nil
 }
 
+// Returns the rules of the app+env trie, or no rules if the trie does not exist yet.
+func GetAppEnvTrieRules(internal bool, app, env string) ([]string, error) {
+	helper.SetRouterRoot(internal)
+	trieName := helper.GetAppEnvTrieName(app, env)
+	if exists, err := routerzk.TrieExists(Zk.Conn, trieName); !exists || err != nil {
+		return []string{}, err
+	}
+	trie, err := routerzk.GetTrie(Zk.Conn, trieName)
+	if err != nil {
+		return nil, err
+	}
+	return trie.Rules, nil
+}
+
+// Points the app+env trie at only the static rule for sha. Returns the rules the trie had before the swap so
+// that they can be handed to RestoreAppEnvTrie if the caller needs to back out.
+func SwapAppEnvTrie(internal bool, app, sha, env string) ([]string, error) {
+	helper.SetRouterRoot(internal)
+	trieName := helper.GetAppEnvTrieName(app, env)
+	trie, err := routerzk.GetTrie(Zk.Conn, trieName)
+	if err != nil {
+		return nil, err
+	}
+	oldRules := trie.Rules
+	ruleName := helper.GetAppShaEnvStaticRuleName(app, sha, env)
+	if exists, err := routerzk.RuleExists(Zk.Conn, ruleName); !exists || err != nil {
+		return oldRules, errors.New(fmt.Sprintf("No static rule for %s @ %s in %s", app, sha, env))
+	}
+	trie.Rules = []string{ruleName}
+	if err = routerzk.SetTrie(Zk.Conn, trie); err != nil {
+		return oldRules, err
+	}
+	// the canary rule (if any) is no longer in the trie so the canary is over
+	if zkApp, err := GetApp(app); err == nil {
+		if canary := zkApp.GetCanary(env); canary != nil {
+			routerzk.DelRule(Zk.Conn, helper.GetAppShaEnvCanaryRuleName(app, canary.Sha, env))
+			zkApp.RemoveCanary(env)
+		}
+	}
+	return oldRules, nil
+}
+
+// Rewrites the app+env trie so that weight percent of the traffic goes to the pool for sha and the rest falls
+// through to the static rules of the other shas. A weight of 0 stops the canary and puts the static rule for the
+// canary sha back at the end of the trie. Only one canary may exist per app+env; setting a new one stops the old.
+func SetAppEnvCanary(internal bool, app, sha, env string, weight uint) error {
+	if weight > 100 {
+		return errors.New("Canary weight must be between 0 and 100")
+	}
+	zkApp, err := GetApp(app)
+	if err != nil {
+		return err
+	}
+	helper.SetRouterRoot(internal)
+	trieName := helper.GetAppEnvTrieName(app, env)
+	trie, err := routerzk.GetTrie(Zk.Conn, trieName)
+	if err != nil {
+		return err
+	}
+	rules := trie.Rules
+	oldCanaryRule := ""
+	if oldCanary := zkApp.GetCanary(env); oldCanary != nil {
+		oldCanaryRule = helper.GetAppShaEnvCanaryRuleName(app, oldCanary.Sha, env)
+		rules = removeCanaryRule(app, oldCanary.Sha, env, rules)
+	}
+	canaryRule := ""
+	if weight > 0 {
+		staticRule := helper.GetAppShaEnvStaticRuleName(app, sha, env)
+		if exists, err := routerzk.RuleExists(Zk.Conn, staticRule); !exists || err != nil {
+			return errors.New(fmt.Sprintf("No static rule for %s @ %s in %s", app, sha, env))
+		}
+		canaryRule = helper.GetAppShaEnvCanaryRuleName(app, sha, env)
+		err = routerzk.SetRule(Zk.Conn, routercfg.Rule{
+			Name:     canaryRule,
+			Type:     CanaryRuleType,
+			Value:    fmt.Sprintf("%d", weight),
+			Pool:     helper.CreatePoolName(app, sha, env),
+			Internal: internal,
+		})
+		if err != nil {
+			return err
+		}
+		// canary goes first. drop the static rule for sha so the rest of the traffic goes to the other shas.
+		newRules := []string{canaryRule}
+		for _, rule := range rules {
+			if rule != staticRule {
+				newRules = append(newRules, rule)
+			}
+		}
+		rules = newRules
+	}
+	trie.Rules = rules
+	if err = routerzk.SetTrie(Zk.Conn, trie); err != nil {
+		return err
+	}
+	// only delete the old canary rule once the trie no longer points to it
+	if oldCanaryRule != "" && oldCanaryRule != canaryRule {
+		if err = routerzk.DelRule(Zk.Conn, oldCanaryRule); err != nil {
+			log.Printf("Error deleting old canary rule %s: %s", oldCanaryRule, err.Error())
+		}
+	}
+	if weight == 0 {
+		return zkApp.RemoveCanary(env)
+	}
+	return zkApp.SetCanary(env, sha, weight)
+}
+
+// takes the canary rule for sha out of rules and puts the static rule for sha back at the end (if it still exists)
+func removeCanaryRule(app, sha, env string, rules []string) []string {
+	canaryRule := helper.GetAppShaEnvCanaryRuleName(app, sha, env)
+	staticRule := helper.GetAppShaEnvStaticRuleName(app, sha, env)
+	newRules := []string{}
+	for _, rule := range rules {
+		if rule != canaryRule && rule != staticRule {
+			newRules = append(newRules, rule)
+		}
+	}
+	if exists, err := routerzk.RuleExists(Zk.Conn, staticRule); exists && err == nil {
+		newRules = append(newRules, staticRule)
+	}
+	return newRules
+}
+
+func RestoreAppEnvTrie(internal bool, app, env string, rules []string) error {
+	helper.SetRouterRoot(internal)
+	trieName := helper.GetAppEnvTrieName(app, env)
+	trie, err := routerzk.GetTrie(Zk.Conn, trieName)
+	if err != nil {
+		return err
+	}
+	// skip rules that have been deleted since (their pool was torn down), the router can't use them anyways
+	restoredRules := []string{}
+	for _, rule := range rules {
+		if exists, err := routerzk.RuleExists(Zk.Conn, rule); exists && err == nil {
+			restoredRules = append(restoredRules, rule)
+		}
+	}
+	trie.Rules = restoredRules
+	return routerzk.SetTrie(Zk.Conn, trie)
+}
+
 func reserveRouterPort(internal bool, app, env string) (string, error) {
 	lock := NewRouterPortsLock(internal)
 	lock.Lock()
@@ -484,15 +628,16 @@ func CleanupCreatedPoolRefs(internal bool, app, sha, env string) error {
 	helper.SetRouterRoot(internal)
 	// remove static rule, cleanup rule from trie if needed
 	ruleName := helper.GetAppShaEnvStaticRuleName(app, sha, env)
+	canaryRuleName := helper.GetAppShaEnvCanaryRuleName(app, sha, env)
 	trieName := helper.GetAppEnvTrieName(app, env)
-	// remove static rule from trie
+	// remove static and canary rules from trie
 	trie, err := routerzk.GetTrie(Zk.Conn, trieName)
 	if err != nil {
 		return err
 	}
 	newRules := []string{}
 	for _, rule := range trie.Rules {
-		if rule != ruleName {
+		if rule != ruleName && rule != canaryRuleName {
 			newRules = append(newRules, rule)
 		}
 	}
@@ -508,5 +653,12 @@ func CleanupCreatedPoolRefs(internal bool, app, sha, env string) error {
 	if err != nil {
 		return err
 	}
+	// if this sha was the canary, the canary is over
+	if zkApp, err := GetApp(app); err == nil {
+		if canary := zkApp.GetCanary(env); canary != nil && canary.Sha == sha {
+			routerzk.DelRule(Zk.Conn, canaryRuleName)
+			zkApp.RemoveCanary(env)
+		}
+	}
 	return nil
 }
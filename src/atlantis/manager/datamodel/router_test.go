@@ -14,6 +14,7 @@ package datamodel
 import (
 	. "atlantis/manager/constant"
 	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
 	"atlantis/router/config"
 	routerzk "atlantis/router/zk"
 	. "launchpad.net/gocheck"
@@ -313,3 +314,111 @@ func (s *DatamodelSuite) TestRouterExternalPool(c *C) {
 	thePool, err = routerzk.GetPool(Zk.Conn, theName)
 	c.Assert(err, Not(IsNil))
 }
+
+func (s *DatamodelSuite) TestSwapAndRestoreAppEnvTrie(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseRouterPortsPath(true))
+	Zk.RecursiveDelete(helper.GetBaseLockPath())
+	Zk.RecursiveDelete("/atlantis/router")
+	CreateRouterPaths()
+	CreateRouterPortsPaths()
+	CreateLockPaths()
+
+	MinRouterPort = uint16(65533)
+	MaxRouterPort = uint16(65535)
+
+	rules, err := GetAppEnvTrieRules(true, "app", "env")
+	c.Assert(err, IsNil)
+	c.Assert(rules, DeepEquals, []string{})
+	_, _, err = ReserveRouterPortAndUpdateTrie(true, "app", "sha", "env")
+	c.Assert(err, IsNil)
+	_, _, err = ReserveRouterPortAndUpdateTrie(true, "app", "sha2", "env")
+	c.Assert(err, IsNil)
+	rules, err = GetAppEnvTrieRules(true, "app", "env")
+	c.Assert(err, IsNil)
+	c.Assert(len(rules), Equals, 2)
+	oldRules, err := SwapAppEnvTrie(true, "app", "sha2", "env")
+	c.Assert(err, IsNil)
+	c.Assert(oldRules, DeepEquals, []string{helper.GetAppShaEnvStaticRuleName("app", "sha", "env"),
+		helper.GetAppShaEnvStaticRuleName("app", "sha2", "env")})
+	helper.SetRouterRoot(true)
+	trie, err := routerzk.GetTrie(Zk.Conn, helper.GetAppEnvTrieName("app", "env"))
+	c.Assert(err, IsNil)
+	c.Assert(trie.Rules, DeepEquals, []string{helper.GetAppShaEnvStaticRuleName("app", "sha2", "env")})
+	_, err = SwapAppEnvTrie(true, "app", "nosuchsha", "env")
+	c.Assert(err, Not(IsNil))
+
+	c.Assert(RestoreAppEnvTrie(true, "app", "env", oldRules), IsNil)
+	helper.SetRouterRoot(true)
+	trie, err = routerzk.GetTrie(Zk.Conn, helper.GetAppEnvTrieName("app", "env"))
+	c.Assert(err, IsNil)
+	c.Assert(trie.Rules, DeepEquals, oldRules)
+
+	// rules that no longer exist are not restored
+	helper.SetRouterRoot(true)
+	c.Assert(routerzk.DelRule(Zk.Conn, helper.GetAppShaEnvStaticRuleName("app", "sha", "env")), IsNil)
+	c.Assert(RestoreAppEnvTrie(true, "app", "env", oldRules), IsNil)
+	helper.SetRouterRoot(true)
+	trie, err = routerzk.GetTrie(Zk.Conn, helper.GetAppEnvTrieName("app", "env"))
+	c.Assert(err, IsNil)
+	c.Assert(trie.Rules, DeepEquals, []string{helper.GetAppShaEnvStaticRuleName("app", "sha2", "env")})
+}
+
+func (s *DatamodelSuite) TestSetAppEnvCanary(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseRouterPortsPath(true))
+	Zk.RecursiveDelete(helper.GetBaseLockPath())
+	Zk.RecursiveDelete("/atlantis/router")
+	Zk.RecursiveDelete("/atlantis/apps")
+	CreateRouterPaths()
+	CreateRouterPortsPaths()
+	CreateLockPaths()
+	CreateAppPath()
+	CreateOrUpdateApp(false, true, "app", "ssh://git@omg.com/app", "/", "omg@omg.com")
+
+	MinRouterPort = uint16(65533)
+	MaxRouterPort = uint16(65535)
+
+	_, _, err := ReserveRouterPortAndUpdateTrie(true, "app", "sha", "env")
+	c.Assert(err, IsNil)
+	_, _, err = ReserveRouterPortAndUpdateTrie(true, "app", "sha2", "env")
+	c.Assert(err, IsNil)
+	c.Assert(SetAppEnvCanary(true, "app", "sha2", "env", 101), Not(IsNil))
+	c.Assert(SetAppEnvCanary(true, "app", "nosuchsha", "env", 10), Not(IsNil))
+
+	c.Assert(SetAppEnvCanary(true, "app", "sha2", "env", 10), IsNil)
+	canaryRule := helper.GetAppShaEnvCanaryRuleName("app", "sha2", "env")
+	helper.SetRouterRoot(true)
+	trie, err := routerzk.GetTrie(Zk.Conn, helper.GetAppEnvTrieName("app", "env"))
+	c.Assert(err, IsNil)
+	c.Assert(trie.Rules, DeepEquals, []string{canaryRule, helper.GetAppShaEnvStaticRuleName("app", "sha", "env")})
+	rule, err := routerzk.GetRule(Zk.Conn, canaryRule)
+	c.Assert(err, IsNil)
+	c.Assert(rule.Type, Equals, CanaryRuleType)
+	c.Assert(rule.Value, Equals, "10")
+	c.Assert(rule.Pool, Equals, helper.CreatePoolName("app", "sha2", "env"))
+	zkApp, err := GetApp("app")
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.GetCanary("env"), DeepEquals, &types.Canary{Sha: "sha2", Weight: 10})
+
+	// changing the weight keeps the same trie
+	c.Assert(SetAppEnvCanary(true, "app", "sha2", "env", 50), IsNil)
+	helper.SetRouterRoot(true)
+	trie, err = routerzk.GetTrie(Zk.Conn, helper.GetAppEnvTrieName("app", "env"))
+	c.Assert(err, IsNil)
+	c.Assert(trie.Rules, DeepEquals, []string{canaryRule, helper.GetAppShaEnvStaticRuleName("app", "sha", "env")})
+	rule, err = routerzk.GetRule(Zk.Conn, canaryRule)
+	c.Assert(err, IsNil)
+	c.Assert(rule.Value, Equals, "50")
+
+	// weight 0 puts the static rule back and removes the canary
+	c.Assert(SetAppEnvCanary(true, "app", "sha2", "env", 0), IsNil)
+	helper.SetRouterRoot(true)
+	trie, err = routerzk.GetTrie(Zk.Conn, helper.GetAppEnvTrieName("app", "env"))
+	c.Assert(err, IsNil)
+	c.Assert(trie.Rules, DeepEquals, []string{helper.GetAppShaEnvStaticRuleName("app", "sha", "env"),
+		helper.GetAppShaEnvStaticRuleName("app", "sha2", "env")})
+	exists, err := routerzk.RuleExists(Zk.Conn, canaryRule)
+	c.Assert(exists, Equals, false)
+	zkApp, err = GetApp("app")
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.GetCanary("env"), IsNil)
+}
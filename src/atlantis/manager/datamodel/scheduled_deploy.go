@@ -0,0 +1,135 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
+	zookeeper "github.com/jigish/gozk-recipes"
+	"time"
+)
+
+// the number of started, failed and cancelled schedules kept. pending schedules are never pruned.
+var MaxScheduledDeploys = 100
+
+type ZkScheduledDeploy types.ScheduledDeploy
+
+// Saves a new pending schedule, giving it an ID that sorts by when it was added, and prunes the oldest schedules
+// that are done.
+func AddScheduledDeploy(schedule *types.ScheduledDeploy) error {
+	zs := ZkScheduledDeploy(*schedule)
+	zs.ID = newRecordID()
+	zs.Status = ScheduledDeployPending
+	if err := zs.Save(); err != nil {
+		return err
+	}
+	schedule.ID = zs.ID
+	schedule.Status = zs.Status
+	pruneQueuedDeploys(helper.GetBaseScheduledDeployPath(), MaxScheduledDeploys, readScheduledDeploy)
+	return nil
+}
+
+func GetScheduledDeploy(id string) (*types.ScheduledDeploy, error) {
+	schedule := &types.ScheduledDeploy{}
+	if err := getJson(helper.GetBaseScheduledDeployPath(id), schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Saves the status (and task) of a schedule
+func UpdateScheduledDeploy(schedule *types.ScheduledDeploy) error {
+	zs := ZkScheduledDeploy(*schedule)
+	return zs.Save()
+}
+
+func (zs *ZkScheduledDeploy) Save() error {
+	return setJson(zs.path(), zs)
+}
+
+func (zs *ZkScheduledDeploy) path() string {
+	return helper.GetBaseScheduledDeployPath(zs.ID)
+}
+
+func (zs *ZkScheduledDeploy) deployArg() *types.ManagerDeployArg {
+	return &zs.Arg
+}
+
+func (zs *ZkScheduledDeploy) pending() bool {
+	return zs.Status == ScheduledDeployPending
+}
+
+func readScheduledDeploy(id string) (queuedDeploy, error) {
+	zs := &ZkScheduledDeploy{}
+	return zs, getJson(helper.GetBaseScheduledDeployPath(id), zs)
+}
+
+// runs f on the schedule with id while no other manager can claim or cancel it
+func withScheduledDeploy(id string, f func(*types.ScheduledDeploy) error) (*types.ScheduledDeploy, error) {
+	mutex := zookeeper.NewMutex(Zk.Conn, helper.GetBaseLockPath("scheduled_deploy"))
+	if err := mutex.Lock(); err != nil {
+		return nil, err
+	}
+	defer mutex.Unlock()
+	schedule, err := GetScheduledDeploy(id)
+	if err != nil {
+		return nil, errors.New("Scheduled deploy " + id + " does not exist")
+	}
+	if err := f(schedule); err != nil {
+		return nil, err
+	}
+	return schedule, UpdateScheduledDeploy(schedule)
+}
+
+// Marks the schedule with id as started by manager if it is pending and due at now. returns nil if it isn't, e.g.
+// because another manager got to it first. only the manager that gets the schedule back may start its deploy.
+func ClaimScheduledDeploy(id, manager string, now time.Time) (*types.ScheduledDeploy, error) {
+	notDue := errors.New("not due")
+	schedule, err := withScheduledDeploy(id, func(schedule *types.ScheduledDeploy) error {
+		if schedule.Status != ScheduledDeployPending || schedule.Time > now.Unix() {
+			return notDue
+		}
+		schedule.Status = ScheduledDeployStarted
+		schedule.Manager = manager
+		return nil
+	})
+	if err == notDue {
+		return nil, nil
+	}
+	return schedule, err
+}
+
+// Cancels the schedule with id if it hasn't been started yet
+func CancelScheduledDeploy(id, user string) (*types.ScheduledDeploy, error) {
+	return withScheduledDeploy(id, func(schedule *types.ScheduledDeploy) error {
+		if schedule.Status != ScheduledDeployPending {
+			return errors.New(fmt.Sprintf("Scheduled deploy %s is already %s", id, schedule.Status))
+		}
+		schedule.Status = ScheduledDeployCancelled
+		schedule.CancelledBy = user
+		return nil
+	})
+}
+
+// Returns the schedules for app and env (empty for any), in the order they were added. only pending schedules
+// unless all is set.
+func ListScheduledDeploys(app, env string, all bool) ([]*types.ScheduledDeploy, error) {
+	schedules := []*types.ScheduledDeploy{}
+	dir := helper.GetBaseScheduledDeployPath()
+	for _, queued := range listQueuedDeploys(dir, app, env, all, readScheduledDeploy) {
+		schedules = append(schedules, (*types.ScheduledDeploy)(queued.(*ZkScheduledDeploy)))
+	}
+	return schedules, nil
+}
@@ -0,0 +1,85 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	. "atlantis/manager/constant"
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	. "launchpad.net/gocheck"
+	"time"
+)
+
+func (s *DatamodelSuite) TestScheduledDeploys(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseScheduledDeployPath())
+	CreateScheduledDeployPath()
+	CreateLockPaths()
+	schedules, err := ListScheduledDeploys("", "", true)
+	c.Assert(err, IsNil)
+	c.Assert(len(schedules), Equals, 0)
+
+	now := time.Now()
+	ids := []string{}
+	for _, theSha := range []string{"sha1", "sha2", "sha3"} {
+		schedule := &types.ScheduledDeploy{User: "user", Arg: types.ManagerDeployArg{App: app, Sha: theSha, Env: env},
+			Time: now.Add(time.Hour).Unix()}
+		c.Assert(AddScheduledDeploy(schedule), IsNil)
+		c.Assert(schedule.ID, Not(Equals), "")
+		c.Assert(schedule.Status, Equals, ScheduledDeployPending)
+		ids = append(ids, schedule.ID)
+	}
+	schedules, err = ListScheduledDeploys(app, env, false)
+	c.Assert(err, IsNil)
+	c.Assert(len(schedules), Equals, 3)
+	c.Assert(schedules[0].Arg.Sha, Equals, "sha1")
+	schedules, err = ListScheduledDeploys(app, "otherenv", false)
+	c.Assert(err, IsNil)
+	c.Assert(len(schedules), Equals, 0)
+
+	// a schedule can only be claimed once it is due, and only once
+	schedule, err := ClaimScheduledDeploy(ids[0], "manager1", now)
+	c.Assert(err, IsNil)
+	c.Assert(schedule, IsNil)
+	later := now.Add(2 * time.Hour)
+	schedule, err = ClaimScheduledDeploy(ids[0], "manager1", later)
+	c.Assert(err, IsNil)
+	c.Assert(schedule, Not(IsNil))
+	c.Assert(schedule.Status, Equals, ScheduledDeployStarted)
+	c.Assert(schedule.Manager, Equals, "manager1")
+	schedule, err = ClaimScheduledDeploy(ids[0], "manager2", later)
+	c.Assert(err, IsNil)
+	c.Assert(schedule, IsNil)
+	schedule, err = GetScheduledDeploy(ids[0])
+	c.Assert(err, IsNil)
+	c.Assert(schedule.Manager, Equals, "manager1")
+
+	// only pending schedules can be cancelled, and cancelled ones can't be claimed
+	_, err = CancelScheduledDeploy(ids[0], "user")
+	c.Assert(err, Not(IsNil))
+	schedule, err = CancelScheduledDeploy(ids[1], "user")
+	c.Assert(err, IsNil)
+	c.Assert(schedule.Status, Equals, ScheduledDeployCancelled)
+	c.Assert(schedule.CancelledBy, Equals, "user")
+	schedule, err = ClaimScheduledDeploy(ids[1], "manager1", later)
+	c.Assert(err, IsNil)
+	c.Assert(schedule, IsNil)
+	_, err = CancelScheduledDeploy("nonexistent", "user")
+	c.Assert(err, Not(IsNil))
+
+	schedules, err = ListScheduledDeploys("", "", false)
+	c.Assert(err, IsNil)
+	c.Assert(len(schedules), Equals, 1)
+	c.Assert(schedules[0].ID, Equals, ids[2])
+	schedules, err = ListScheduledDeploys("", "", true)
+	c.Assert(err, IsNil)
+	c.Assert(len(schedules), Equals, 3)
+}
@@ -15,17 +15,19 @@ import (
 	. "atlantis/common"
 	. "atlantis/manager/constant"
 	"atlantis/manager/helper"
-	"atlantis/manager/supervisor"
+	"atlantis/manager/rpc/types"
 	"errors"
 	"fmt"
 	"log"
 	"sort"
+	"strings"
 )
 
 type ZkSupervisor string
 
 type SupervisorData struct {
 	PortMap map[string]uint16
+	Labels  map[string]string `json:",omitempty"` // e.g. ssd -> true, see helper.ParseLabelSelector
 }
 
 func (h *SupervisorData) HasAppShaEnv(app, sha, env string) bool {
@@ -68,8 +70,9 @@ func (h ZkSupervisor) Touch() error {
 	return err
 }
 
-// Delete the host node and all child container nodes of that host
+// Delete the host node and all child container nodes of that host, and its inventory
 func (h ZkSupervisor) Delete() error {
+	DeleteSupervisorInventory(string(h)) // it's fine if it never had a heartbeat
 	return Zk.RecursiveDelete(h.path())
 }
 
@@ -102,11 +105,63 @@ func (h ZkSupervisor) RemoveContainer(container string) (retErr error) {
 	return
 }
 
-func ListSupervisorsForApp(app string) (hosts []string, err error) {
-	hosts, err = ListSupervisors()
-	// TODO(jbhat): Filter out hosts that already are running the app or cannot run the app (whitelist/blacklist).
-	// For now, return all hosts.
-	return
+// Sets the labels in set and removes the ones in remove. returns the labels the supervisor ends up with.
+func (h ZkSupervisor) SetLabels(set map[string]string, remove []string) (map[string]string, error) {
+	for key, value := range set {
+		if err := helper.CheckLabel(key, value); err != nil {
+			return nil, err
+		}
+	}
+	data := SupervisorData{}
+	if err := getJson(h.path(), &data); err != nil {
+		log.Printf("Error getting json from host node. Error: %s.", err.Error())
+		return nil, err
+	}
+	if data.Labels == nil {
+		data.Labels = map[string]string{}
+	}
+	for _, key := range remove {
+		delete(data.Labels, key)
+	}
+	for key, value := range set {
+		data.Labels[key] = value
+	}
+	if err := setJson(h.path(), &data); err != nil {
+		log.Printf("Error setting json to host node. Error: %s.", err.Error())
+		return nil, err
+	}
+	return data.Labels, nil
+}
+
+// Lists the supervisors app can go on: the ones matching both the app's SupervisorSelector and selector
+func ListSupervisorsForApp(app, selector string) (hosts []string, err error) {
+	if zkApp, err := GetApp(app); err == nil {
+		selector = helper.JoinLabelSelectors(zkApp.SupervisorSelector, selector)
+	}
+	return ListSupervisorsMatching(selector)
+}
+
+// Lists the supervisors whose labels match selector (all of them if it is empty)
+func ListSupervisorsMatching(selector string) ([]string, error) {
+	parsed, err := helper.ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := ListSupervisors()
+	if err != nil || len(parsed) == 0 {
+		return hosts, err
+	}
+	matching := []string{}
+	for _, host := range hosts {
+		info, err := Supervisor(host).Info()
+		if err != nil {
+			continue // bad host, skip.
+		}
+		if parsed.Matches(info.Labels) {
+			matching = append(matching, host)
+		}
+	}
+	return matching, nil
 }
 
 func ListSupervisors() (hosts []string, err error) {
@@ -224,59 +279,157 @@ func (h SupervisorAndWeightList) Swap(i, j int) {
 	h[i], h[j] = h[j], h[i]
 }
 
-func ChooseSupervisorsList(app, sha, env string, cpu, memory uint, zones []string,
-	excludeSupervisors map[string]bool) (SupervisorAndWeightList, error) {
-	hosts, err := ListSupervisorsForApp(app)
+// how many more containers of cpu shares and memory MBytes fit on a supervisor with inventory
+func fitsOn(inventory *types.SupervisorInventory, cpu, memory uint) uint {
+	containers, usedCPU, usedMemory := inventory.UsedContainers, inventory.UsedCPUShares, inventory.UsedMemory
+	if containers >= inventory.TotalContainers || usedCPU+cpu > inventory.TotalCPUShares ||
+		usedMemory+memory > inventory.TotalMemory {
+		return 0
+	}
+	free := inventory.TotalContainers - containers
+	if memory > 0 && (inventory.TotalMemory-usedMemory)/memory < free {
+		free = (inventory.TotalMemory - usedMemory) / memory
+	}
+	if cpu > 0 && (inventory.TotalCPUShares-usedCPU)/cpu < free {
+		free = (inventory.TotalCPUShares - usedCPU) / cpu
+	}
+	return free
+}
+
+// Lists the supervisors with room for a container of app @ sha in env, sorted by how good the placement's strategy
+// and the soft affinity rules of the app (and of the apps on the supervisors with it) think they are. only supervisors matching the app's and the placement's label
+// selectors are considered. the supervisors with room that a hard affinity rule rules out are returned as
+// zone -> "host: why" instead, and the ones that are down (or have never had a heartbeat) as host -> why. nothing
+// asks the supervisors or reads their containers: their capacity and what they run come from their inventory, as
+// of the last heartbeat. a nil placement uses the DefaultPlacement.
+func ChooseSupervisorsList(app, sha, env string, cpu, memory uint, zones []string, excludeSupervisors map[string]bool,
+	placement *Placement) (SupervisorAndWeightList, map[string][]string, map[string]string, error) {
+	strategy, _ := GetPlacementStrategy(DefaultPlacement)
+	selector := ""
+	if placement != nil {
+		if placement.Strategy != nil {
+			strategy = placement.Strategy
+		}
+		selector = placement.Selector
+	}
+	hosts, err := ListSupervisorsForApp(app, selector)
 	if err != nil {
 		log.Println("Error listing hosts for app "+app+":", err)
-		return nil, err
+		return nil, nil, nil, err
 	}
 	if len(hosts) == 0 {
-		return nil, errors.New("No hosts available for app " + app)
+		if zkApp, err := GetApp(app); err == nil {
+			selector = helper.JoinLabelSelectors(zkApp.SupervisorSelector, selector)
+		}
+		if selector != "" {
+			return nil, nil, nil, errors.New("No hosts matching " + selector + " available for app " + app)
+		}
+		return nil, nil, nil, errors.New("No hosts available for app " + app)
 	}
-	list := SupervisorAndWeightList{}
+	var rules map[string]*types.AffinityRule
+	if zkApp, err := GetApp(app); err == nil {
+		rules = zkApp.Affinities
+	}
+	otherRules := map[string]map[string]*types.AffinityRule{} // the rules of the other apps looked up so far
+	candidates := []string{}
 	for _, host := range hosts {
-		if excludeSupervisors != nil && excludeSupervisors[host] {
-			continue
+		if excludeSupervisors == nil || !excludeSupervisors[host] {
+			candidates = append(candidates, host)
 		}
-		// check if this host already has this app-sha
-		hostInfo, err := Supervisor(host).Info()
+	}
+	list := SupervisorAndWeightList{}
+	ruledOut := map[string][]string{}
+	unreachable := map[string]string{}
+	for _, host := range candidates {
+		inventory, err := GetSupervisorInventory(host)
 		if err != nil {
-			continue // bad host, skip.
-		}
-		health, err := supervisor.HealthCheck(host)
-		if err != nil || health.Status != StatusOk {
-			continue // health check fail
+			unreachable[host] = "no heartbeat recorded yet"
+			continue
 		}
-		if health.Containers.Free == 0 || health.Memory.Free < memory || health.CPUShares.Free < cpu {
+		if inventory.State == SupervisorDown {
+			unreachable[host] = fmt.Sprintf("down after %d missed heartbeats (%s)", inventory.MissedHeartbeats,
+				inventory.LastError)
 			continue
 		}
-		// figure out how many we can stack on
-		free := health.Containers.Free
-		if health.Memory.Free/memory < free {
-			free = health.Memory.Free / memory
+		if inventory.Status != StatusOk {
+			continue // not taking containers
 		}
-		if health.CPUShares.Free/cpu < free {
-			free = health.CPUShares.Free / cpu
+		// figure out how many we can stack on
+		free := fitsOn(inventory, cpu, memory)
+		if free == 0 {
+			continue
 		}
 		// we're chillin. add the weight to the host map
-		// +2 weight for every one of this app/sha/env we see
-		weight := float64(2*hostInfo.CountAppShaEnv(app, sha, env)) +
-			(float64(health.Memory.Used+memory) / float64(health.Memory.Total)) +
-			(float64(health.CPUShares.Used+cpu) / float64(health.CPUShares.Total))
-		list = append(list, SupervisorAndWeight{Supervisor: host, Zone: health.Zone, Free: free, Weight: weight})
+		weight := strategy.Weight(&PlacementCandidate{
+			Supervisor:  host,
+			Count:       inventory.CountAppShaEnv(app, sha, env),
+			UsedCPU:     inventory.UsedCPUShares + cpu,
+			TotalCPU:    inventory.TotalCPUShares,
+			UsedMemory:  inventory.UsedMemory + memory,
+			TotalMemory: inventory.TotalMemory,
+			CPU:         cpu,
+			Memory:      memory,
+			Price:       inventory.Price,
+		})
+		// the rules of app and the rules that the other apps on the supervisor have with app both apply
+		counts := inventory.AppEnvCounts(env)
+		reverse := rulesWithApp(app, counts, otherRules)
+		if len(rules) > 0 || len(reverse) > 0 {
+			own := counts[app]
+			if placement != nil && placement.Replace {
+				own = inventory.CountAppShaEnv(app, sha, env)
+			}
+			affinityWeight, onlyOne, violation := checkAffinity(app, rules, counts, own)
+			reverseWeight, reverseViolation := checkReverseAffinity(app, reverse, counts)
+			if violation != "" && reverseViolation != "" {
+				violation += ", " + reverseViolation
+			} else if reverseViolation != "" {
+				violation = reverseViolation
+			}
+			if violation != "" {
+				ruledOut[inventory.Zone] = append(ruledOut[inventory.Zone], host+": "+violation)
+				continue
+			}
+			if onlyOne {
+				free = 1
+			}
+			weight += affinityWeight + reverseWeight
+		}
+		list = append(list, SupervisorAndWeight{Supervisor: host, Zone: inventory.Zone, Free: free, Weight: weight})
 	}
 	sort.Sort(list) // sort in weight order, lowest to highest
-	return list, nil
+	return list, ruledOut, unreachable, nil
+}
+
+// explains why the supervisors of zones were ruled out ("" if none were)
+func RuledOutReason(ruledOut map[string][]string, zones ...string) string {
+	reasons := []string{}
+	for _, zone := range zones {
+		reasons = append(reasons, ruledOut[zone]...)
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return ". Ruled out by affinity rules: " + strings.Join(reasons, "; ")
 }
 
-// Choses hosts and sorts them based on how "free" they are. returns a map of zone -> host slice.
-func ChooseSupervisors(app, sha, env string, instances, cpu, memory uint, zones []string,
-	excludeSupervisors map[string]bool) (map[string][]string, error) {
-	list, err := ChooseSupervisorsList(app, sha, env, cpu, memory, zones, excludeSupervisors)
+// Choses hosts and sorts them based on how "free" they are. zones is a map of zone -> # of instances to place in it.
+// returns a map of zone -> host slice, and the supervisors that are down (see ChooseSupervisorsList).
+func ChooseSupervisors(app, sha, env string, zones map[string]uint, cpu, memory uint,
+	excludeSupervisors map[string]bool, placement *Placement) (map[string][]string, map[string]string, error) {
+	list, ruledOut, unreachable, err := ChooseSupervisorsList(app, sha, env, cpu, memory, SortedZones(zones),
+		excludeSupervisors, placement)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	hosts, err := SupervisorsByZone(app, zones, list, ruledOut)
+	return hosts, unreachable, err
+}
+
+// Groups a list from ChooseSupervisorsList by zone, keeping the weight order. fails if any zone can't fit its
+// instances, explaining which supervisors ruledOut (see ChooseSupervisorsList) took out of the zone.
+func SupervisorsByZone(app string, zones map[string]uint, list SupervisorAndWeightList,
+	ruledOut map[string][]string) (map[string][]string, error) {
 	chosenSupervisors := map[string][]string{}
 	freeZones := map[string]uint{}
 	for _, host := range list {
@@ -288,18 +441,29 @@ func ChooseSupervisors(app, sha, env string, instances, cpu, memory uint, zones
 		freeZones[host.Zone] = freeZones[host.Zone] + host.Free
 	}
 	// ensure all zones are represented and have enough free
-	for _, zone := range zones {
+	for _, zone := range SortedZones(zones) {
+		instances := zones[zone]
 		if hosts, ok := chosenSupervisors[zone]; !ok || hosts == nil {
-			msg := fmt.Sprintf("No host for app %s available in zone %s", app, zone)
+			msg := fmt.Sprintf("No host for app %s available in zone %s", app, zone) + RuledOutReason(ruledOut, zone)
 			log.Println(msg)
 			return nil, errors.New(msg)
 		}
 		if freeZones[zone] < instances {
 			msg := fmt.Sprintf("Not enough instances for app %s available in zone %s (%d reqd, %d free)", app, zone,
-				instances, freeZones[zone])
+				instances, freeZones[zone]) + RuledOutReason(ruledOut, zone)
 			log.Println(msg)
 			return nil, errors.New(msg)
 		}
 	}
 	return chosenSupervisors, nil
 }
+
+// the zones of a zone -> instances map in a stable order
+func SortedZones(zones map[string]uint) []string {
+	sorted := make([]string, 0, len(zones))
+	for zone := range zones {
+		sorted = append(sorted, zone)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -12,6 +12,7 @@
 package datamodel
 
 import (
+	"atlantis/manager/helper"
 	. "launchpad.net/gocheck"
 	"sort"
 )
@@ -54,7 +55,7 @@ func (s *DatamodelSuite) TestSupervisor(c *C) {
 	sort.Strings(hosts) // sort so DeepEquals works
 	c.Assert(hosts, DeepEquals, []string{host, host + "1"})
 	// test ListSupervisorsForApp
-	hosts, err = ListSupervisorsForApp(app)
+	hosts, err = ListSupervisorsForApp(app, "")
 	c.Assert(err, IsNil)
 	sort.Strings(hosts)
 	c.Assert(hosts, DeepEquals, []string{host, host + "1"})
@@ -66,7 +67,7 @@ func (s *DatamodelSuite) TestSupervisor(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(hosts, DeepEquals, []string{host + "1"})
 	// test to make sure host was deleted in ListSupervisorsForApp
-	hosts, err = ListSupervisorsForApp(app)
+	hosts, err = ListSupervisorsForApp(app, "")
 	c.Assert(err, IsNil)
 	c.Assert(hosts, DeepEquals, []string{host + "1"})
 	// delete second host
@@ -76,7 +77,60 @@ func (s *DatamodelSuite) TestSupervisor(c *C) {
 	hosts, err = ListSupervisors()
 	c.Assert(err, IsNil)
 	c.Assert(hosts, DeepEquals, []string{})
-	hosts, err = ListSupervisorsForApp(app)
+	hosts, err = ListSupervisorsForApp(app, "")
 	c.Assert(err, IsNil)
 	c.Assert(hosts, DeepEquals, []string{})
 }
+
+func (s *DatamodelSuite) TestSupervisorLabels(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseSupervisorPath())
+	Zk.RecursiveDelete(helper.GetBaseAppPath())
+	ssd := Supervisor(host)
+	c.Assert(ssd.Touch(), IsNil)
+	labels, err := ssd.SetLabels(map[string]string{"ssd": "true", "tier": "web"}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, map[string]string{"ssd": "true", "tier": "web"})
+	batch := Supervisor(host + "1")
+	c.Assert(batch.Touch(), IsNil)
+	_, err = batch.SetLabels(map[string]string{"tier": "batch"}, nil)
+	c.Assert(err, IsNil)
+	_, err = batch.SetLabels(map[string]string{"bad key": "true"}, nil)
+	c.Assert(err, Not(IsNil))
+	// labels survive containers coming and going
+	c.Assert(ssd.SetContainerAndPort("container", 1337), IsNil)
+	c.Assert(ssd.RemoveContainer("container"), IsNil)
+	data, err := ssd.Info()
+	c.Assert(err, IsNil)
+	c.Assert(data.Labels["ssd"], Equals, "true")
+
+	hosts, err := ListSupervisorsMatching("tier!=batch")
+	c.Assert(err, IsNil)
+	c.Assert(hosts, DeepEquals, []string{host})
+	hosts, err = ListSupervisorsMatching("")
+	c.Assert(err, IsNil)
+	c.Assert(len(hosts), Equals, 2)
+	_, err = ListSupervisorsMatching("ssd==true")
+	c.Assert(err, Not(IsNil))
+
+	zkApp, err := CreateOrUpdateApp(false, true, app, repo, root, "jigish@ooyala.com")
+	c.Assert(err, IsNil)
+	c.Assert(zkApp.SetSupervisorSelector("tier"), IsNil)
+	hosts, err = ListSupervisorsForApp(app, "")
+	c.Assert(err, IsNil)
+	sort.Strings(hosts)
+	c.Assert(hosts, DeepEquals, []string{host, host + "1"})
+	// a deploy's selector narrows the app's down
+	hosts, err = ListSupervisorsForApp(app, "tier=batch")
+	c.Assert(err, IsNil)
+	c.Assert(hosts, DeepEquals, []string{host + "1"})
+	labels, err = ssd.SetLabels(nil, []string{"tier"})
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, map[string]string{"ssd": "true"})
+	hosts, err = ListSupervisorsForApp(app, "")
+	c.Assert(err, IsNil)
+	c.Assert(hosts, DeepEquals, []string{host + "1"})
+
+	c.Assert(ssd.Delete(), IsNil)
+	c.Assert(batch.Delete(), IsNil)
+	Zk.RecursiveDelete(helper.GetBaseAppPath())
+}
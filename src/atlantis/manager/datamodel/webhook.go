@@ -0,0 +1,75 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type ZkWebhook types.Webhook
+
+// Saves webhook, giving it an ID that sorts by when it was added
+func AddWebhook(webhook *types.Webhook) error {
+	zw := ZkWebhook(*webhook)
+	zw.ID = fmt.Sprintf("%020d", time.Now().UnixNano())
+	if err := zw.Save(); err != nil {
+		return err
+	}
+	webhook.ID = zw.ID
+	return nil
+}
+
+func GetWebhook(id string) (*types.Webhook, error) {
+	webhook := &types.Webhook{}
+	if err := getJson(helper.GetBaseWebhookPath(id), webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func DeleteWebhook(id string) error {
+	return Zk.RecursiveDelete(helper.GetBaseWebhookPath(id))
+}
+
+func (zw *ZkWebhook) Save() error {
+	return setJson(zw.path(), zw)
+}
+
+func (zw *ZkWebhook) path() string {
+	return helper.GetBaseWebhookPath(zw.ID)
+}
+
+// Returns the webhooks for app, oldest first. if global is set, the webhooks for every app are included. an
+// empty app with global set returns every webhook.
+func ListWebhooks(app string, global bool) ([]*types.Webhook, error) {
+	ids, _, err := Zk.VisibleChildren(helper.GetBaseWebhookPath())
+	if err != nil || ids == nil {
+		// no webhooks yet
+		return []*types.Webhook{}, nil
+	}
+	sort.Strings(ids)
+	webhooks := []*types.Webhook{}
+	for _, id := range ids {
+		webhook, err := GetWebhook(id)
+		if err != nil {
+			continue // deleted out from under us
+		}
+		if (app == "" && global) || webhook.App == app || (global && webhook.App == "") {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
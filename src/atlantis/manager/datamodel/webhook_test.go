@@ -0,0 +1,57 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package datamodel
+
+import (
+	"atlantis/manager/helper"
+	"atlantis/manager/rpc/types"
+	. "launchpad.net/gocheck"
+)
+
+func (s *DatamodelSuite) TestWebhooks(c *C) {
+	Zk.RecursiveDelete(helper.GetBaseWebhookPath())
+	CreateWebhookPath()
+	webhooks, err := ListWebhooks("", true)
+	c.Assert(err, IsNil)
+	c.Assert(len(webhooks), Equals, 0)
+
+	global := &types.Webhook{URL: "http://chat/hook", User: "user"}
+	c.Assert(AddWebhook(global), IsNil)
+	c.Assert(global.ID, Not(Equals), "")
+	mine := &types.Webhook{URL: "http://dashboard/hook", App: app, User: "user"}
+	c.Assert(AddWebhook(mine), IsNil)
+	other := &types.Webhook{URL: "http://other/hook", App: "other-app", User: "user"}
+	c.Assert(AddWebhook(other), IsNil)
+
+	webhooks, err = ListWebhooks("", true)
+	c.Assert(err, IsNil)
+	c.Assert(len(webhooks), Equals, 3)
+	webhooks, err = ListWebhooks(app, true)
+	c.Assert(err, IsNil)
+	c.Assert(len(webhooks), Equals, 2)
+	c.Assert(webhooks[0].URL, Equals, global.URL)
+	c.Assert(webhooks[1].URL, Equals, mine.URL)
+	webhooks, err = ListWebhooks(app, false)
+	c.Assert(err, IsNil)
+	c.Assert(len(webhooks), Equals, 1)
+	webhooks, err = ListWebhooks("", false)
+	c.Assert(err, IsNil)
+	c.Assert(len(webhooks), Equals, 1)
+	c.Assert(webhooks[0].ID, Equals, global.ID)
+
+	c.Assert(DeleteWebhook(mine.ID), IsNil)
+	_, err = GetWebhook(mine.ID)
+	c.Assert(err, Not(IsNil))
+	webhooks, err = ListWebhooks(app, true)
+	c.Assert(err, IsNil)
+	c.Assert(len(webhooks), Equals, 1)
+}
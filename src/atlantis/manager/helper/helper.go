@@ -16,10 +16,13 @@ import (
 	. "atlantis/manager/constant"
 	"atlantis/manager/rpc/types"
 	routerzk "atlantis/router/zk"
+	"errors"
 	"fmt"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const contRandIDSize = 6
@@ -66,6 +69,11 @@ func GetBaseSupervisorPath(args ...string) string {
 	return JoinWithBase(base, args...)
 }
 
+func GetBaseSupervisorInventoryPath(args ...string) string {
+	base := fmt.Sprintf("/atlantis/supervisor_inventory/%s", Region)
+	return JoinWithBase(base, args...)
+}
+
 func CreatePoolName(app, sha, env string) string {
 	return fmt.Sprintf("%s-%s-%s", app, sha, env)
 }
@@ -110,6 +118,10 @@ func GetAppShaEnvStaticRuleName(app, sha, env string) string {
 	return fmt.Sprintf("static-%s-%s-%s", app, sha, env)
 }
 
+func GetAppShaEnvCanaryRuleName(app, sha, env string) string {
+	return fmt.Sprintf("canary-%s-%s-%s", app, sha, env)
+}
+
 func GetBaseManagerPath(args ...string) string {
 	base := "/atlantis/managers"
 	return JoinWithBase(base, args...)
@@ -139,6 +151,36 @@ func GetBaseEnvPath(args ...string) string {
 	return JoinWithBase(base, args...)
 }
 
+func GetBaseDeployHistoryPath(args ...string) string {
+	base := fmt.Sprintf("/atlantis/deploy_history/%s", Region)
+	return JoinWithBase(base, args...)
+}
+
+func GetBaseReleasePath(args ...string) string {
+	base := fmt.Sprintf("/atlantis/releases/%s", Region)
+	return JoinWithBase(base, args...)
+}
+
+func GetBaseManifestPath(args ...string) string {
+	base := fmt.Sprintf("/atlantis/manifests/%s", Region)
+	return JoinWithBase(base, args...)
+}
+
+func GetBaseDeployRequestPath(args ...string) string {
+	base := fmt.Sprintf("/atlantis/deploy_requests/%s", Region)
+	return JoinWithBase(base, args...)
+}
+
+func GetBaseScheduledDeployPath(args ...string) string {
+	base := fmt.Sprintf("/atlantis/scheduled_deploys/%s", Region)
+	return JoinWithBase(base, args...)
+}
+
+func GetBaseWebhookPath(args ...string) string {
+	base := fmt.Sprintf("/atlantis/webhooks/%s", Region)
+	return JoinWithBase(base, args...)
+}
+
 func GetBaseLockPath(args ...string) string {
 	base := fmt.Sprintf("/atlantis/lock/%s", Region)
 	return JoinWithBase(base, args...)
@@ -195,6 +237,211 @@ func ZoneMinusRegion(zone string) string {
 	return strings.Replace(zone, Region, "", 1)
 }
 
+// parses zone:instances pairs (e.g. "us-east-1a:4,us-east-1b:2") into a map of zone -> instances
+func ParseZoneInstances(pairs []string) (map[string]uint, error) {
+	zones := map[string]uint{}
+	for _, pair := range pairs {
+		for _, zoneInstances := range strings.Split(pair, ",") {
+			if zoneInstances == "" {
+				continue
+			}
+			parts := strings.SplitN(zoneInstances, ":", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return nil, errors.New(fmt.Sprintf("Invalid zone %s. Should be zone:instances", zoneInstances))
+			}
+			instances, err := strconv.ParseUint(parts[1], 10, 0)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid instances for zone %s: %s", parts[0], err.Error()))
+			}
+			zones[parts[0]] = uint(instances)
+		}
+	}
+	return zones, nil
+}
+
+var labelRegexp = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9._/-]*$")
+
+// checks that a supervisor label can be stored and selected on
+func CheckLabel(key, value string) error {
+	if !labelRegexp.MatchString(key) {
+		return errors.New(fmt.Sprintf("Invalid label key %q. Should be letters, digits, '.', '_', '/' or '-'", key))
+	}
+	if value != "" && !labelRegexp.MatchString(value) {
+		return errors.New(fmt.Sprintf("Invalid value %q for label %s. Should be letters, digits, '.', '_', '/' "+
+			"or '-'", value, key))
+	}
+	return nil
+}
+
+// parses key=value pairs (e.g. "ssd=true,tier=batch") into a map of label -> value
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range pairs {
+		for _, label := range strings.Split(pair, ",") {
+			if label == "" {
+				continue
+			}
+			parts := strings.SplitN(label, "=", 2)
+			if len(parts) != 2 {
+				return nil, errors.New(fmt.Sprintf("Invalid label %s. Should be key=value", label))
+			}
+			if err := CheckLabel(parts[0], parts[1]); err != nil {
+				return nil, err
+			}
+			labels[parts[0]] = parts[1]
+		}
+	}
+	return labels, nil
+}
+
+// One requirement of a label selector. see ParseLabelSelector.
+type LabelRequirement struct {
+	Key   string
+	Value string
+	Equal bool // whether the label has to be Value, or must not be
+	Exist bool // only whether the label is there (Equal) or not (!Equal) matters
+}
+
+func (r LabelRequirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	if r.Exist {
+		return ok == r.Equal
+	}
+	return (ok && value == r.Value) == r.Equal
+}
+
+type LabelSelector []LabelRequirement
+
+// a supervisor matches a selector if it matches all of its requirements
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// parses a comma separated label selector. every requirement is one of key=value, key!=value, key (the label is
+// set) or !key (the label is not set). e.g. "ssd=true,tier!=batch,!draining"
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	parsed := LabelSelector{}
+	for _, requirement := range strings.Split(selector, ",") {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" {
+			continue
+		}
+		var r LabelRequirement
+		if parts := strings.SplitN(requirement, "!=", 2); len(parts) == 2 {
+			r = LabelRequirement{Key: parts[0], Value: parts[1], Equal: false}
+		} else if parts := strings.SplitN(requirement, "=", 2); len(parts) == 2 {
+			r = LabelRequirement{Key: parts[0], Value: parts[1], Equal: true}
+		} else if strings.HasPrefix(requirement, "!") {
+			r = LabelRequirement{Key: requirement[1:], Exist: true, Equal: false}
+		} else {
+			r = LabelRequirement{Key: requirement, Exist: true, Equal: true}
+		}
+		if err := CheckLabel(r.Key, r.Value); err != nil {
+			return nil, errors.New("Invalid label selector " + selector + ": " + err.Error())
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// combines label selectors into one that needs all of them to match
+func JoinLabelSelectors(selectors ...string) string {
+	joined := []string{}
+	for _, selector := range selectors {
+		if selector = strings.Trim(selector, ", "); selector != "" {
+			joined = append(joined, selector)
+		}
+	}
+	return strings.Join(joined, ",")
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parses a time of the week like "Fri 16:00" (UTC) into how long after Sunday 00:00 it is
+func ParseWeekTime(weekTime string) (time.Duration, error) {
+	parts := strings.Fields(weekTime)
+	if len(parts) != 2 {
+		return 0, errors.New(fmt.Sprintf("Invalid time of the week %s. Should be like Fri 16:00", weekTime))
+	}
+	day, ok := weekdays[strings.ToLower(parts[0])]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("Invalid day %s. Should be one of Sun, Mon, Tue, Wed, Thu, Fri, Sat",
+			parts[0]))
+	}
+	clock, err := time.Parse("15:04", parts[1])
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid time %s. Should be like 16:00", parts[1]))
+	}
+	return time.Duration(day)*24*time.Hour + time.Duration(clock.Hour())*time.Hour +
+		time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// checks that freeze has a name, a reason and a window that makes sense
+func CheckFreeze(freeze *types.Freeze) error {
+	if freeze.Name == "" {
+		return errors.New("Please specify a name for the freeze")
+	}
+	if freeze.Reason == "" {
+		return errors.New("Please specify a reason for the freeze")
+	}
+	if freeze.Start > 0 && freeze.End > 0 && freeze.End <= freeze.Start {
+		return errors.New("The freeze should end after it starts")
+	}
+	if (freeze.WeeklyStart == "") != (freeze.WeeklyEnd == "") {
+		return errors.New("Please specify both the weekly start and end of the freeze")
+	}
+	if freeze.WeeklyStart != "" {
+		if _, err := ParseWeekTime(freeze.WeeklyStart); err != nil {
+			return err
+		}
+		if _, err := ParseWeekTime(freeze.WeeklyEnd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// true if freeze is in effect at now. weekly windows may wrap around the end of the week (e.g. Fri - Mon).
+func FreezeActive(freeze *types.Freeze, now time.Time) bool {
+	if freeze.Start > 0 && now.Unix() < freeze.Start {
+		return false
+	}
+	if freeze.End > 0 && now.Unix() >= freeze.End {
+		return false
+	}
+	if freeze.WeeklyStart == "" {
+		return true
+	}
+	start, err := ParseWeekTime(freeze.WeeklyStart)
+	if err != nil {
+		return false
+	}
+	end, err := ParseWeekTime(freeze.WeeklyEnd)
+	if err != nil {
+		return false
+	}
+	now = now.UTC()
+	sinceSunday := time.Duration(now.Weekday())*24*time.Hour + time.Duration(now.Hour())*time.Hour +
+		time.Duration(now.Minute())*time.Minute
+	if start <= end {
+		return sinceSunday >= start && sinceSunday < end
+	}
+	return sinceSunday >= start || sinceSunday < end
+}
+
 var envSuffixRegexp = regexp.MustCompile("^(prod|production)([_-]|$)")
 
 func EmptyIfProdPrefix(env string) string {
@@ -13,10 +13,12 @@ package helper
 
 import (
 	. "atlantis/manager/constant"
+	"atlantis/manager/rpc/types"
 	routerzk "atlantis/router/zk"
 	. "launchpad.net/gocheck"
 	"sort"
 	"testing"
+	"time"
 )
 
 const (
@@ -217,3 +219,89 @@ func (s *HelperSuite) TestHelperEmptyIfProdPrefix(c *C) {
 	c.Assert(EmptyIfProdPrefix("production-ooga-booga"), Equals, "")
 	c.Assert(EmptyIfProdPrefix("production_ooga-booga"), Equals, "")
 }
+
+func (s *HelperSuite) TestParseZoneInstances(c *C) {
+	zones, err := ParseZoneInstances([]string{"us-east-1a:4,us-east-1b:2", "us-east-1c:1"})
+	c.Assert(err, IsNil)
+	c.Assert(zones, DeepEquals, map[string]uint{"us-east-1a": 4, "us-east-1b": 2, "us-east-1c": 1})
+	zones, err = ParseZoneInstances([]string{})
+	c.Assert(err, IsNil)
+	c.Assert(len(zones), Equals, 0)
+	_, err = ParseZoneInstances([]string{"us-east-1a"})
+	c.Assert(err, Not(IsNil))
+	_, err = ParseZoneInstances([]string{"us-east-1a:many"})
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *HelperSuite) TestParseLabels(c *C) {
+	labels, err := ParseLabels([]string{"ssd=true,tier=batch", "rack="})
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, map[string]string{"ssd": "true", "tier": "batch", "rack": ""})
+	_, err = ParseLabels([]string{"ssd"})
+	c.Assert(err, Not(IsNil))
+	_, err = ParseLabels([]string{"bad key=true"})
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *HelperSuite) TestLabelSelector(c *C) {
+	selector, err := ParseLabelSelector("ssd=true, tier!=batch,!draining")
+	c.Assert(err, IsNil)
+	c.Assert(len(selector), Equals, 3)
+	c.Assert(selector.Matches(map[string]string{"ssd": "true"}), Equals, true)
+	c.Assert(selector.Matches(map[string]string{"ssd": "true", "tier": "web"}), Equals, true)
+	c.Assert(selector.Matches(map[string]string{"ssd": "true", "tier": "batch"}), Equals, false)
+	c.Assert(selector.Matches(map[string]string{"ssd": "true", "draining": ""}), Equals, false)
+	c.Assert(selector.Matches(map[string]string{"ssd": "false"}), Equals, false)
+	c.Assert(selector.Matches(nil), Equals, false)
+	selector, err = ParseLabelSelector("gpu")
+	c.Assert(err, IsNil)
+	c.Assert(selector.Matches(map[string]string{"gpu": ""}), Equals, true)
+	c.Assert(selector.Matches(map[string]string{}), Equals, false)
+	selector, err = ParseLabelSelector("")
+	c.Assert(err, IsNil)
+	c.Assert(selector.Matches(nil), Equals, true)
+	_, err = ParseLabelSelector("ssd==true")
+	c.Assert(err, Not(IsNil))
+	c.Assert(JoinLabelSelectors("ssd=true,", "", "tier!=batch"), Equals, "ssd=true,tier!=batch")
+}
+
+func (s *HelperSuite) TestParseWeekTime(c *C) {
+	offset, err := ParseWeekTime("Fri 16:00")
+	c.Assert(err, IsNil)
+	c.Assert(offset, Equals, 5*24*time.Hour+16*time.Hour)
+	offset, err = ParseWeekTime("sun 00:30")
+	c.Assert(err, IsNil)
+	c.Assert(offset, Equals, 30*time.Minute)
+	_, err = ParseWeekTime("Friday 16:00")
+	c.Assert(err, Not(IsNil))
+	_, err = ParseWeekTime("Fri 4pm")
+	c.Assert(err, Not(IsNil))
+	_, err = ParseWeekTime("16:00")
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *HelperSuite) TestFreezeActive(c *C) {
+	weekend := &types.Freeze{Name: "weekend", Reason: "weekend", WeeklyStart: "Fri 16:00", WeeklyEnd: "Mon 09:00"}
+	c.Assert(CheckFreeze(weekend), IsNil)
+	// 2014-01-03 was a Friday
+	c.Assert(FreezeActive(weekend, time.Date(2014, 1, 3, 15, 59, 0, 0, time.UTC)), Equals, false)
+	c.Assert(FreezeActive(weekend, time.Date(2014, 1, 3, 16, 0, 0, 0, time.UTC)), Equals, true)
+	c.Assert(FreezeActive(weekend, time.Date(2014, 1, 5, 12, 0, 0, 0, time.UTC)), Equals, true)
+	c.Assert(FreezeActive(weekend, time.Date(2014, 1, 6, 8, 59, 0, 0, time.UTC)), Equals, true)
+	c.Assert(FreezeActive(weekend, time.Date(2014, 1, 6, 9, 0, 0, 0, time.UTC)), Equals, false)
+	c.Assert(FreezeActive(weekend, time.Date(2014, 1, 8, 12, 0, 0, 0, time.UTC)), Equals, false)
+
+	now := time.Date(2014, 1, 8, 12, 0, 0, 0, time.UTC)
+	incident := &types.Freeze{Name: "incident", Reason: "outage", Start: now.Unix()}
+	c.Assert(CheckFreeze(incident), IsNil)
+	c.Assert(FreezeActive(incident, now.Add(-time.Minute)), Equals, false)
+	c.Assert(FreezeActive(incident, now), Equals, true)
+	c.Assert(FreezeActive(incident, now.Add(24*time.Hour)), Equals, true)
+	incident.End = now.Add(time.Hour).Unix()
+	c.Assert(FreezeActive(incident, now.Add(time.Hour)), Equals, false)
+
+	c.Assert(CheckFreeze(&types.Freeze{Name: "nameless"}), Not(IsNil))
+	c.Assert(CheckFreeze(&types.Freeze{Reason: "no name"}), Not(IsNil))
+	c.Assert(CheckFreeze(&types.Freeze{Name: "half", Reason: "half", WeeklyStart: "Fri 16:00"}), Not(IsNil))
+	c.Assert(CheckFreeze(&types.Freeze{Name: "backwards", Reason: "backwards", Start: 10, End: 5}), Not(IsNil))
+}
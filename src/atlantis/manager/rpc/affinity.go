@@ -0,0 +1,113 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	"atlantis/manager/datamodel"
+	. "atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
+)
+
+type SetAffinityRuleExecutor struct {
+	arg   ManagerAffinityRuleArg
+	reply *ManagerAffinityRuleReply
+}
+
+func (e *SetAffinityRuleExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *SetAffinityRuleExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *SetAffinityRuleExecutor) Description() string {
+	if e.arg.Remove {
+		return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s with %s: remove", e.arg.App, e.arg.Rule.App)
+	}
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s with %s: anti: %t, hard: %t", e.arg.App,
+		e.arg.Rule.App, e.arg.Rule.Anti, e.arg.Rule.Hard)
+}
+
+func (e *SetAffinityRuleExecutor) Authorize() error {
+	return AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App)
+}
+
+func (e *SetAffinityRuleExecutor) Execute(t *Task) error {
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if e.arg.Rule.App == "" {
+		return errors.New("Please specify the app the rule is with")
+	}
+	app, err := datamodel.GetApp(e.arg.App)
+	if err != nil {
+		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+	}
+	if e.arg.Remove {
+		err = app.RemoveAffinityRule(e.arg.Rule.App)
+	} else {
+		rule := e.arg.Rule
+		err = app.SetAffinityRule(&rule)
+	}
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	e.reply.Rules = app.Affinities
+	return nil
+}
+
+type ListAffinityRulesExecutor struct {
+	arg   ManagerAffinityRuleArg
+	reply *ManagerAffinityRuleReply
+}
+
+func (e *ListAffinityRulesExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ListAffinityRulesExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ListAffinityRulesExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s", e.arg.App)
+}
+
+func (e *ListAffinityRulesExecutor) Authorize() error {
+	return SimpleAuthorize(&e.arg.ManagerAuthArg)
+}
+
+func (e *ListAffinityRulesExecutor) Execute(t *Task) error {
+	app, err := datamodel.GetApp(e.arg.App)
+	if err != nil {
+		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+	}
+	e.reply.Rules = app.Affinities
+	if e.reply.Rules == nil {
+		e.reply.Rules = map[string]*AffinityRule{}
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (m *ManagerRPC) SetAffinityRule(arg ManagerAffinityRuleArg, reply *ManagerAffinityRuleReply) error {
+	return NewTask("SetAffinityRule", &SetAffinityRuleExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) ListAffinityRules(arg ManagerAffinityRuleArg, reply *ManagerAffinityRuleReply) error {
+	return NewTask("ListAffinityRules", &ListAffinityRulesExecutor{arg, reply}).Run()
+}
@@ -0,0 +1,304 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/datamodel"
+	aldap "atlantis/manager/ldap"
+	. "atlantis/manager/rpc/types"
+	"atlantis/manager/smtp"
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// held while a request is being decided so that it can only be approved or denied once
+var deployRequestMutex = &sync.Mutex{}
+
+// ----------------------------------------------------------------------------------------------------------
+// Deploy Requests
+// ----------------------------------------------------------------------------------------------------------
+
+type DeployRequestTemplate struct {
+	ID           string
+	App          string
+	Sha          string
+	Env          string
+	Instances    uint
+	User         string
+	ManagerCName string
+}
+
+var deployRequestTemplate = template.Must(template.New("deploy_request").Parse(`
+{{.User}} has requested to deploy '{{.App}}' @ {{.Sha}} to the protected environment '{{.Env}}' ({{.Instances}} instances per zone).
+
+A team admin of '{{.App}}' other than {{.User}} has to approve the deploy before it runs:
+
+  atlantis-manager approve-deploy --id {{.ID}}
+  atlantis-manager deny-deploy --id {{.ID}} --reason <why>
+
+Or on https://{{.ManagerCName}}: POST /deploy_requests/{{.ID}}/approve
+`))
+
+// saves arg as a request for a team admin of the app to approve and lets the app's teams know about it
+func requestDeploy(t *Task, arg *ManagerDeployArg, reply *ManagerDeployReply) error {
+	app, err := datamodel.GetApp(arg.App)
+	if err != nil {
+		return errors.New("App " + arg.App + " is not registered: " + err.Error())
+	}
+	requestArg := *arg
+	requestArg.ManagerAuthArg = ManagerAuthArg{User: arg.ManagerAuthArg.User}
+	request := &DeployRequest{User: arg.ManagerAuthArg.User, Arg: requestArg, Time: time.Now().Unix()}
+	if err := datamodel.AddDeployRequest(request); err != nil {
+		return err
+	}
+	t.Log("%s is protected. Deploy request %s is waiting for a team admin of %s to approve it", arg.Env,
+		request.ID, arg.App)
+	reply.PendingRequest = request.ID
+	reply.Status = DeployRequestPending
+	// the request is saved, so failing to send the mail only warrants a warning
+	if err := notifyApprovers(&arg.ManagerAuthArg, app, request); err != nil {
+		t.AddWarning("Could not email the approvers of " + arg.App + ": " + err.Error())
+	}
+	return nil
+}
+
+// emails the app and its teams about a new deploy request
+func notifyApprovers(auth *ManagerAuthArg, app *datamodel.ZkApp, request *DeployRequest) error {
+	to := []string{}
+	if app.Email != "" {
+		to = append(to, app.Email)
+	}
+	teams, err := ListAppTeams(app.Name, auth)
+	if err != nil {
+		return err
+	}
+	for _, team := range teams {
+		emails, err := ListTeamEmails(team, auth)
+		if err != nil {
+			return err
+		}
+		to = append(to, emails...)
+	}
+	if len(to) == 0 {
+		return errors.New("no emails found")
+	}
+	myself, err := datamodel.GetManager(Region, Host)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer([]byte{})
+	deployRequestTemplate.Execute(buf, DeployRequestTemplate{
+		ID:           request.ID,
+		App:          request.Arg.App,
+		Sha:          request.Arg.Sha,
+		Env:          request.Arg.Env,
+		Instances:    request.Arg.Instances,
+		User:         request.User,
+		ManagerCName: myself.ManagerCName,
+	})
+	subject := fmt.Sprintf("[Atlantis] %s is requesting to deploy '%s' @ %s to %s", request.User,
+		request.Arg.App, request.Arg.Sha, request.Arg.Env)
+	return smtp.SendMail(to, subject, buf.String())
+}
+
+// only a team admin of the app (or a superuser) other than the user who asked for the deploy can decide on it
+func authorizeApprover(auth *ManagerAuthArg, request *DeployRequest) error {
+	if err := SimpleAuthorize(auth); err != nil {
+		return err
+	}
+	if auth.User == request.User {
+		return errors.New("You cannot approve or deny your own deploy request")
+	}
+	if err := authorizeAppAdmin(auth, request.Arg.App); err != nil {
+		return errors.New("Only a team admin of " + request.Arg.App + " can approve or deny its deploys")
+	}
+	return nil
+}
+
+// whether auth is a team admin of one of app's teams or a superuser
+func authorizeAppAdmin(auth *ManagerAuthArg, app string) error {
+	if aldap.SkipAuthorization {
+		return nil
+	}
+	teams, err := ListAppTeams(app, auth)
+	if err != nil {
+		return err
+	}
+	for _, team := range teams {
+		if err := AuthorizeTeamAdmin(auth, team); err == nil {
+			return nil
+		}
+	}
+	return AuthorizeSuperUser(auth)
+}
+
+// the pending request id (to be decided by auth)
+func getPendingDeployRequest(auth *ManagerAuthArg, id string) (*DeployRequest, error) {
+	if id == "" {
+		return nil, errors.New("Please specify a deploy request")
+	}
+	request, err := datamodel.GetDeployRequest(id)
+	if err != nil {
+		return nil, errors.New("Deploy request " + id + " does not exist")
+	}
+	if request.Status != DeployRequestPending {
+		return nil, errors.New(fmt.Sprintf("Deploy request %s was already %s by %s", id, request.Status,
+			request.DecidedBy))
+	}
+	if err := authorizeApprover(auth, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+type ApproveDeployExecutor struct {
+	arg   ManagerDeployRequestArg
+	reply *ManagerDeployReply
+}
+
+func (e *ApproveDeployExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ApproveDeployExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ApproveDeployExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s", e.arg.ID)
+}
+
+func (e *ApproveDeployExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	_, err := getPendingDeployRequest(&e.arg.ManagerAuthArg, e.arg.ID)
+	return err
+}
+
+func (e *ApproveDeployExecutor) Execute(t *Task) error {
+	// check again in case someone else decided while we were waiting to run
+	deployRequestMutex.Lock()
+	request, err := getPendingDeployRequest(&e.arg.ManagerAuthArg, e.arg.ID)
+	if err != nil {
+		deployRequestMutex.Unlock()
+		return err
+	}
+	request.Status = DeployRequestApproved
+	request.DecidedBy = e.arg.ManagerAuthArg.User
+	request.TaskID = t.ID
+	err = datamodel.UpdateDeployRequest(request)
+	deployRequestMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	t.Log("%s approved %s's deploy of %s @ %s to %s", request.DecidedBy, request.User, request.Arg.App,
+		request.Arg.Sha, request.Arg.Env)
+	// the deploy runs as the approver since the request doesn't keep the requester's credentials
+	arg := request.Arg
+	arg.ManagerAuthArg = e.arg.ManagerAuthArg
+	return executeDeploy(t, &arg, e.reply)
+}
+
+type DenyDeployExecutor struct {
+	arg   ManagerDeployRequestArg
+	reply *ManagerDeployRequestReply
+}
+
+func (e *DenyDeployExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *DenyDeployExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *DenyDeployExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s: %s", e.arg.ID, e.arg.Reason)
+}
+
+func (e *DenyDeployExecutor) Authorize() error {
+	_, err := getPendingDeployRequest(&e.arg.ManagerAuthArg, e.arg.ID)
+	return err
+}
+
+func (e *DenyDeployExecutor) Execute(t *Task) error {
+	if e.arg.Reason == "" {
+		return errors.New("Please specify why the deploy is denied")
+	}
+	deployRequestMutex.Lock()
+	defer deployRequestMutex.Unlock()
+	request, err := getPendingDeployRequest(&e.arg.ManagerAuthArg, e.arg.ID)
+	if err != nil {
+		return err
+	}
+	request.Status = DeployRequestDenied
+	request.DecidedBy = e.arg.ManagerAuthArg.User
+	request.Reason = e.arg.Reason
+	if err := datamodel.UpdateDeployRequest(request); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Request = request
+	e.reply.Status = StatusOk
+	return nil
+}
+
+type ListDeployRequestsExecutor struct {
+	arg   ManagerListDeployRequestsArg
+	reply *ManagerListDeployRequestsReply
+}
+
+func (e *ListDeployRequestsExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ListDeployRequestsExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ListDeployRequestsExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] app: %s, env: %s (all: %t)", e.arg.App, e.arg.Env,
+		e.arg.All)
+}
+
+func (e *ListDeployRequestsExecutor) Authorize() error {
+	return SimpleAuthorize(&e.arg.ManagerAuthArg)
+}
+
+func (e *ListDeployRequestsExecutor) Execute(t *Task) (err error) {
+	if e.reply.Requests, err = datamodel.ListDeployRequests(e.arg.App, e.arg.Env, e.arg.All); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (m *ManagerRPC) ApproveDeploy(arg ManagerDeployRequestArg, reply *AsyncReply) error {
+	executor := &ApproveDeployExecutor{arg, &ManagerDeployReply{}}
+	return NewTask("ApproveDeploy", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
+}
+
+func (m *ManagerRPC) DenyDeploy(arg ManagerDeployRequestArg, reply *ManagerDeployRequestReply) error {
+	return NewTask("DenyDeploy", &DenyDeployExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) ListDeployRequests(arg ManagerListDeployRequestsArg, reply *ManagerListDeployRequestsReply) error {
+	return NewTask("ListDeployRequests", &ListDeployRequestsExecutor{arg, reply}).Run()
+}
@@ -0,0 +1,102 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	"atlantis/manager/datamodel"
+	. "atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
+)
+
+// ----------------------------------------------------------------------------------------------------------
+// Canary
+// ----------------------------------------------------------------------------------------------------------
+
+type CanarySetWeightExecutor struct {
+	arg   ManagerCanaryArg
+	reply *ManagerCanaryReply
+}
+
+func (e *CanarySetWeightExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *CanarySetWeightExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *CanarySetWeightExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s @ %s in %s -> %d%%", e.arg.App, e.arg.Sha, e.arg.Env,
+		e.arg.Weight)
+}
+
+func (e *CanarySetWeightExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	return AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App)
+}
+
+func (e *CanarySetWeightExecutor) Execute(t *Task) error {
+	// error checking
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if e.arg.Sha == "" {
+		return errors.New("Please specify a sha")
+	}
+	if e.arg.Env == "" {
+		return errors.New("Please specify an environment")
+	}
+	if e.arg.Weight > 100 {
+		return errors.New("Please specify a weight between 0 and 100")
+	}
+	zkApp, err := datamodel.GetApp(e.arg.App)
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	if zkApp.NonAtlantis {
+		e.reply.Status = StatusError
+		return errors.New(fmt.Sprintf("%s is not an atlantis app", e.arg.App))
+	}
+	if e.arg.Weight > 0 {
+		instances, err := datamodel.ListInstances(e.arg.App, e.arg.Sha, e.arg.Env)
+		if err != nil || len(instances) == 0 {
+			e.reply.Status = StatusError
+			return errors.New(fmt.Sprintf("%s @ %s is not deployed in %s", e.arg.App, e.arg.Sha, e.arg.Env))
+		}
+	}
+	// don't change the trie while a deploy of this sha is in progress
+	dl := datamodel.NewDeployLock(t.ID, e.arg.App, e.arg.Sha, e.arg.Env)
+	if err := dl.Lock(); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	defer dl.Unlock()
+	t.LogStatus("Setting Canary Weight")
+	if err := datamodel.SetAppEnvCanary(zkApp.Internal, e.arg.App, e.arg.Sha, e.arg.Env, e.arg.Weight); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	if e.arg.Weight > 0 {
+		e.reply.Canary = &Canary{Sha: e.arg.Sha, Weight: e.arg.Weight}
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (m *ManagerRPC) CanarySetWeight(arg ManagerCanaryArg, reply *ManagerCanaryReply) error {
+	return NewTask("CanarySetWeight", &CanarySetWeightExecutor{arg, reply}).Run()
+}
@@ -21,6 +21,9 @@ import (
 	. "atlantis/supervisor/rpc/types"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
 type DeployExecutor struct {
@@ -47,46 +50,59 @@ func (e *DeployExecutor) Authorize() error {
 	return SimpleAuthorize(&e.arg.ManagerAuthArg)
 }
 
-func (e *DeployExecutor) Execute(t *Task) error {
+func (e *DeployExecutor) Execute(t *Task) (err error) {
 	// error checking
 	if e.arg.App == "" {
 		return errors.New("Please specify an app")
 	}
-	if e.arg.Sha == "" {
-		return errors.New("Please specify a sha")
-	}
-	if e.arg.Env == "" {
-		return errors.New("Please specify an environment")
+	if err := checkDeployArg(&e.arg); err != nil {
+		return err
 	}
-	if e.arg.CPUShares < 0 ||
-		(e.arg.CPUShares > 0 && e.arg.CPUShares != 1 && e.arg.CPUShares%CPUSharesIncrement != 0) {
-		return errors.New(fmt.Sprintf("CPU Shares should be 1 or a multiple of %d", CPUSharesIncrement))
-	}
-	if e.arg.MemoryLimit < 0 ||
-		(e.arg.MemoryLimit > 0 && e.arg.MemoryLimit%MemoryLimitIncrement != 0) {
-		return errors.New(fmt.Sprintf("Memory should be a multiple of %d", MemoryLimitIncrement))
+	if !e.arg.DryRun && envProtected(e.arg.Env) {
+		return requestDeploy(t, &e.arg, e.reply)
 	}
+	return executeDeploy(t, &e.arg, e.reply)
+}
+
+// builds and deploys arg. shared by Deploy and ApproveDeploy.
+func executeDeploy(t *Task, arg *ManagerDeployArg, reply *ManagerDeployReply) (err error) {
 	// fetch the repo and root
-	app, err := datamodel.GetApp(e.arg.App)
+	app, err := datamodel.GetApp(arg.App)
 	if err != nil {
-		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+		return errors.New("App " + arg.App + " is not registered: " + err.Error())
+	}
+	var manifest *Manifest
+	defer func() { recordDeploy(t, arg, manifest, err) }()
+	if manifest, err = buildManifest(t, app, arg.Sha, arg.Rebuild); err != nil {
+		return err
 	}
-	// fetch and parse manifest for app name
-	manifestReader, err := builder.DefaultBuilder.Build(t, app.Repo, app.Root, e.arg.Sha)
+	return deployManifest(t, arg, reply, app, manifest)
+}
+
+// returns the manifest for app @ sha, building it only if it isn't cached (or rebuild is set). the built
+// manifest is cached so that deploying the sha again, e.g. to another env, doesn't wait on the builder.
+func buildManifest(t *Task, app *datamodel.ZkApp, sha string, rebuild bool) (*Manifest, error) {
+	if !rebuild {
+		if manifest := datamodel.GetCachedManifest(app.Name, app.Repo, app.Root, sha); manifest != nil {
+			t.Log("Using the cached manifest for %s @ %s", app.Name, sha)
+			return manifest, nil
+		}
+	}
+	manifestReader, err := builder.DefaultBuilder.Build(t, app.Repo, app.Root, sha)
 	if err != nil {
-		return errors.New("Build Error: " + err.Error())
+		return nil, errors.New("Build Error: " + err.Error())
 	}
 	defer manifestReader.Close()
 	t.LogStatus("Reading Manifest")
 	data, err := bman.Read(manifestReader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	manifest, err := CreateManifest(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if e.arg.App != manifest.Name {
+	if app.Name != manifest.Name {
 		// NOTE(edanaher): If we kick off two jobs simultaneously, they will assume they have the same job id, so
 		// one of them will get the manifest from the other one's Jenkins job.  Unfortunately, Jenkins doesn't
 		// give back any sort of useful information when you create the job, so we can't just use an ID easily.
@@ -96,30 +112,200 @@ func (e *DeployExecutor) Execute(t *Task) error {
 		// erroring out and blaming Jenkins rather than adding a giant pile of code to handle that case.  We could
 		// retry the job ourself, but after a day trying to beat Jenkins into submission, I have no interest in
 		// applying further hacks.
-		return errors.New("The app name you specified does not match the manifest.  This is probably due to an unavoidable race condition in Jenkin's RESTless API.  Please try again.")
+		return nil, errors.New("The app name you specified does not match the manifest.  This is probably due to an unavoidable race condition in Jenkin's RESTless API.  Please try again.")
 	}
-	if e.arg.CPUShares > 0 {
-		manifest.CPUShares = e.arg.CPUShares
+	if err := datamodel.CacheManifest(app.Name, app.Repo, app.Root, sha, manifest); err != nil {
+		t.Log("Error caching the manifest for %s @ %s: %s", app.Name, sha, err.Error())
 	}
-	if e.arg.MemoryLimit > 0 {
-		manifest.MemoryLimit = e.arg.MemoryLimit
+	return manifest, nil
+}
+
+// everything but the app is checked here so that DeployManifest, which takes the app from the manifest, can share it
+func checkDeployArg(arg *ManagerDeployArg) error {
+	if arg.Sha == "" {
+		return errors.New("Please specify a sha")
+	}
+	if arg.Env == "" {
+		return errors.New("Please specify an environment")
+	}
+	if arg.CPUShares < 0 ||
+		(arg.CPUShares > 0 && arg.CPUShares != 1 && arg.CPUShares%CPUSharesIncrement != 0) {
+		return errors.New(fmt.Sprintf("CPU Shares should be 1 or a multiple of %d", CPUSharesIncrement))
+	}
+	if arg.MemoryLimit < 0 ||
+		(arg.MemoryLimit > 0 && arg.MemoryLimit%MemoryLimitIncrement != 0) {
+		return errors.New(fmt.Sprintf("Memory should be a multiple of %d", MemoryLimitIncrement))
+	}
+	return nil
+}
+
+// deploys a built manifest of app according to arg. shared by Deploy and DeployManifest.
+func deployManifest(t *Task, arg *ManagerDeployArg, reply *ManagerDeployReply, app *datamodel.ZkApp,
+	manifest *Manifest) (err error) {
+	if arg.CPUShares > 0 {
+		manifest.CPUShares = arg.CPUShares
+	}
+	if arg.MemoryLimit > 0 {
+		manifest.MemoryLimit = arg.MemoryLimit
 	}
 	// figure out how many instances we need
-	if e.arg.Instances > 0 {
-		manifest.Instances = e.arg.Instances
+	if arg.Instances > 0 {
+		manifest.Instances = arg.Instances
 	} else if manifest.Instances == 0 {
 		manifest.Instances = uint(1) // default to 1 instance
 	}
-	if e.arg.Dev {
-		e.reply.Containers, err = devDeploy(&e.arg.ManagerAuthArg, manifest, e.arg.Sha, e.arg.Env, t)
+	if arg.MaxFailPct > 100 {
+		return errors.New("Max fail percent should be between 0 and 100")
+	}
+	if arg.Dev && len(arg.Zones) > 0 {
+		return errors.New("Zones are not supported for dev deploys")
+	}
+	if (arg.MinZones > 0 || len(arg.RequiredZones) > 0 || arg.OnlyMissing) && (arg.Dev || arg.Replace) {
+		return errors.New("MinZones, RequiredZones and OnlyMissing are not supported for dev or replace deploys")
+	}
+	if err = checkPromotionRule(app, arg.Sha, arg.Env, time.Now()); err != nil {
+		return err
+	}
+	var policy *zonePolicy
+	if arg.MinZones > 0 || len(arg.RequiredZones) > 0 {
+		policy = &zonePolicy{minZones: arg.MinZones, required: arg.RequiredZones}
+	}
+	placement, err := appPlacement(app.Name, arg.Placement, arg.Selector)
+	if err != nil {
+		return err
+	}
+	placement.Replace = arg.Replace
+	zones := arg.Zones
+	if arg.OnlyMissing {
+		if zones, err = missingZones(arg.App, arg.Sha, arg.Env, zones, manifest.Instances); err != nil {
+			return err
+		}
+		t.Log("Deploying the missing zones: %v", zones)
+	}
+	if arg.DryRun {
+		reply.Plan, err = planDeploy(&arg.ManagerAuthArg, manifest, arg.Sha, arg.Env, zones, arg.Dev, placement, t)
+		return err
+	}
+	if err = checkFreeze(t, &arg.ManagerAuthArg, arg.Env, arg.OverrideFreeze); err != nil {
+		return err
+	}
+	if err = checkCancelled(t); err != nil {
+		return err
+	}
+	watch := time.Duration(arg.WatchTime) * time.Second
+	if arg.Dev {
+		if arg.Replace {
+			return errors.New("Replace is not supported for dev deploys")
+		}
+		reply.Containers, err = devDeploy(&arg.ManagerAuthArg, manifest, arg.Sha, arg.Env, placement, t)
+	} else if arg.Replace {
+		reply.Containers, err = replaceDeploy(&arg.ManagerAuthArg, manifest, arg.Sha, arg.Env, arg.Zones,
+			time.Duration(arg.DrainTime)*time.Second, watch, arg.MaxFailPct, placement, t)
 	} else {
-		e.reply.Containers, err = deploy(&e.arg.ManagerAuthArg, manifest, e.arg.Sha, e.arg.Env, t)
+		// remember what the trie looked like so that we can roll back to it
+		var oldRules []string
+		if watch > 0 {
+			if oldRules, err = datamodel.GetAppEnvTrieRules(app.Internal, arg.App, arg.Env); err != nil {
+				return err
+			}
+		}
+		reply.Containers, reply.Zones, err = deploy(&arg.ManagerAuthArg, manifest, arg.Sha, arg.Env, zones,
+			policy, placement, t)
+		if err == nil {
+			if err = watchDeploy(app.Internal, arg.App, arg.Sha, arg.Env, oldRules, reply.Containers,
+				watch, arg.MaxFailPct, t); err != nil {
+				reply.Containers = nil
+			}
+		}
+	}
+	if _, ok := err.(RollbackError); ok {
+		reply.RolledBack = true
 	}
 	return err
 }
 
 func (m *ManagerRPC) Deploy(arg ManagerDeployArg, reply *AsyncReply) error {
-	return NewTask("Deploy", &DeployExecutor{arg, &ManagerDeployReply{}}).RunAsync(reply)
+	executor := &DeployExecutor{arg, &ManagerDeployReply{}}
+	return NewTask("Deploy", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
+}
+
+// parses a manifest in the format the builder produces
+func readManifest(manifest string) (*Manifest, error) {
+	data, err := bman.Read(strings.NewReader(manifest))
+	if err != nil {
+		return nil, errors.New("Manifest Error: " + err.Error())
+	}
+	return CreateManifest(data)
+}
+
+type DeployManifestExecutor struct {
+	arg   ManagerDeployManifestArg
+	reply *ManagerDeployReply
+}
+
+func (e *DeployManifestExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *DeployManifestExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *DeployManifestExecutor) Description() string {
+	app := e.arg.App
+	if manifest, err := readManifest(e.arg.Manifest); err == nil {
+		app = manifest.Name
+	}
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s @ %s in %s (uploaded manifest)", app, e.arg.Sha,
+		e.arg.Env)
+}
+
+func (e *DeployManifestExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	// the app comes from the manifest so that is what we have to be allowed to deploy
+	manifest, err := readManifest(e.arg.Manifest)
+	if err != nil {
+		return err
+	}
+	return AuthorizeApp(&e.arg.ManagerAuthArg, manifest.Name)
+}
+
+func (e *DeployManifestExecutor) Execute(t *Task) (err error) {
+	if e.arg.Manifest == "" {
+		return errors.New("Please specify a manifest")
+	}
+	t.LogStatus("Reading Manifest")
+	manifest, err := readManifest(e.arg.Manifest)
+	if err != nil {
+		return err
+	}
+	if e.arg.App == "" {
+		e.arg.App = manifest.Name
+	} else if e.arg.App != manifest.Name {
+		return errors.New(fmt.Sprintf("The app you specified (%s) does not match the manifest (%s)", e.arg.App,
+			manifest.Name))
+	}
+	if err := checkDeployArg(&e.arg.ManagerDeployArg); err != nil {
+		return err
+	}
+	if !e.arg.DryRun && envProtected(e.arg.Env) {
+		// a request only keeps what to build, so uploaded manifests can't go through approval
+		return errors.New(fmt.Sprintf("%s is protected. Please use Deploy so that the deploy can be approved",
+			e.arg.Env))
+	}
+	app, err := datamodel.GetApp(e.arg.App)
+	if err != nil {
+		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+	}
+	defer func() { recordDeploy(t, &e.arg.ManagerDeployArg, manifest, err) }()
+	return deployManifest(t, &e.arg.ManagerDeployArg, e.reply, app, manifest)
+}
+
+func (m *ManagerRPC) DeployManifest(arg ManagerDeployManifestArg, reply *AsyncReply) error {
+	executor := &DeployManifestExecutor{arg, &ManagerDeployReply{}}
+	return NewTask("DeployManifest", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
 }
 
 type DeployContainerExecutor struct {
@@ -162,12 +348,30 @@ func (e *DeployContainerExecutor) Execute(t *Task) error {
 	if err != nil {
 		return err
 	}
+	if e.arg.DryRun {
+		cont := ihReply.Container
+		cont.Manifest.Instances = e.arg.Instances
+		placement, err := appPlacement(cont.Manifest.Name, "", "")
+		if err != nil {
+			return err
+		}
+		e.reply.Plan, err = planDeploy(&e.arg.ManagerAuthArg, cont.Manifest, cont.Sha, cont.Env, nil, false,
+			placement, t)
+		return err
+	}
+	if err = checkNotProtected(ihReply.Container.Env); err != nil {
+		return err
+	}
+	if err = checkFreeze(t, &e.arg.ManagerAuthArg, ihReply.Container.Env, e.arg.OverrideFreeze); err != nil {
+		return err
+	}
 	e.reply.Containers, err = deployContainer(&e.arg.ManagerAuthArg, ihReply.Container, e.arg.Instances, t)
 	return err
 }
 
 func (m *ManagerRPC) DeployContainer(arg ManagerDeployContainerArg, reply *AsyncReply) error {
-	return NewTask("DeployContainer", &DeployContainerExecutor{arg, &ManagerDeployReply{}}).RunAsync(reply)
+	executor := &DeployContainerExecutor{arg, &ManagerDeployReply{}}
+	return NewTask("DeployContainer", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
 }
 
 type CopyContainerExecutor struct {
@@ -202,6 +406,21 @@ func (e *CopyContainerExecutor) Execute(t *Task) error {
 	if e.arg.ToHost == "" {
 		return errors.New("To Host is empty")
 	}
+	if e.arg.DryRun {
+		plan, err := planCopyContainer(&e.arg.ManagerAuthArg, e.arg.ContainerID, e.arg.ToHost, t)
+		e.reply.Plan = plan
+		return err
+	}
+	inst, err := datamodel.GetInstance(e.arg.ContainerID)
+	if err != nil {
+		return err
+	}
+	if err = checkNotProtected(inst.Env); err != nil {
+		return err
+	}
+	if err = checkFreeze(t, &e.arg.ManagerAuthArg, inst.Env, e.arg.OverrideFreeze); err != nil {
+		return err
+	}
 	cont, err := copyContainer(&e.arg.ManagerAuthArg, e.arg.ContainerID, e.arg.ToHost, t)
 	if err != nil {
 		return err
@@ -234,7 +453,8 @@ func (e *CopyContainerExecutor) Execute(t *Task) error {
 }
 
 func (m *ManagerRPC) CopyContainer(arg ManagerCopyContainerArg, reply *AsyncReply) error {
-	return NewTask("CopyContainer", &CopyContainerExecutor{arg, &ManagerDeployReply{}}).RunAsync(reply)
+	executor := &CopyContainerExecutor{arg, &ManagerDeployReply{}}
+	return NewTask("CopyContainer", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
 }
 
 type ResolveDepsExecutor struct {
@@ -306,11 +526,36 @@ func (e *TeardownExecutor) Authorize() error {
 	return AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App)
 }
 
-func (e *TeardownExecutor) Execute(t *Task) error {
+func (e *TeardownExecutor) Execute(t *Task) (err error) {
 	hostMap, err := getContainerIDsToTeardown(t, e.arg)
 	if err != nil {
 		return err
 	}
+	recordMap := hostMap
+	if e.arg.All {
+		// the hosts are told to tear down all of their containers, so record every container they have
+		recordMap = supervisorContainerIDs(hostMap)
+	}
+	records := teardownRecords(recordMap)
+	defer func() {
+		for _, record := range records {
+			recordDeployHistory(t, "Teardown", e.arg.ManagerAuthArg.User, record.App, record.Sha, record.Env,
+				record.Manifest, nil, err)
+		}
+	}()
+	envs := teardownEnvs(records)
+	if e.arg.All {
+		// every supervisor tears down all of its containers, whatever the datamodel knows about them
+		if envs, err = datamodel.ListEnvs(); err != nil {
+			return err
+		}
+		sort.Strings(envs)
+	}
+	for _, env := range envs {
+		if err = checkFreeze(t, &e.arg.ManagerAuthArg, env, e.arg.OverrideFreeze); err != nil {
+			return err
+		}
+	}
 	if e.arg.All {
 		tl := datamodel.NewTeardownLock(t.ID)
 		if err := tl.Lock(); err != nil {
@@ -336,42 +581,147 @@ func (e *TeardownExecutor) Execute(t *Task) error {
 		}
 		defer tl.Unlock()
 	}
-	tornContainers := []string{}
+	// one supervisor at a time so that a cancel can stop in between
+	e.reply.ContainerIDs = []string{}
 	for host, containerIDs := range hostMap {
-		if e.arg.All {
-			t.LogStatus("Tearing Down * from %s", host)
-		} else {
-			t.LogStatus("Tearing Down %v from %s", containerIDs, host)
+		if err = checkCancelled(t); err != nil {
+			return err
 		}
-
-		ihReply, err := supervisor.Teardown(host, containerIDs, e.arg.All)
+		tornContainers, err := teardownContainers(t, map[string][]string{host: containerIDs}, e.arg.All)
+		e.reply.ContainerIDs = append(e.reply.ContainerIDs, tornContainers...)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Error Tearing Down %v from %s : %s", containerIDs, host,
-				err.Error()))
-		}
-		tornContainers = append(tornContainers, ihReply.ContainerIDs...)
-		for _, tornContainerID := range tornContainers {
-			err := datamodel.DeleteFromPool([]string{tornContainerID})
-			if err != nil {
-				t.Log("Error removing %s from pool: %v", tornContainerID, err)
-			}
-			datamodel.Supervisor(host).RemoveContainer(tornContainerID)
-			instance, err := datamodel.GetInstance(tornContainerID)
-			if err != nil {
-				continue
-			}
-			last, _ := instance.Delete()
-			if last {
-				DeleteAppShaFromEnv(instance.App, instance.Sha, instance.Env)
-			}
+			return err
 		}
 	}
-	e.reply.ContainerIDs = tornContainers
 	return nil
 }
 
 func (m *ManagerRPC) Teardown(arg ManagerTeardownArg, reply *AsyncReply) error {
-	return NewTask("Teardown", &TeardownExecutor{arg, &ManagerTeardownReply{}}).RunAsync(reply)
+	executor := &TeardownExecutor{arg, &ManagerTeardownReply{}}
+	return NewTask("Teardown", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
+}
+
+type ScaleExecutor struct {
+	arg   ManagerScaleArg
+	reply *ManagerScaleReply
+}
+
+func (e *ScaleExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ScaleExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ScaleExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s @ %s in %s x%d", e.arg.App, e.arg.Sha, e.arg.Env,
+		e.arg.Instances)
+}
+
+func (e *ScaleExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	return AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App)
+}
+
+func (e *ScaleExecutor) Execute(t *Task) (err error) {
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if e.arg.Sha == "" {
+		return errors.New("Please specify a sha")
+	}
+	if e.arg.Env == "" {
+		return errors.New("Please specify an environment")
+	}
+	if e.arg.Instances <= 0 && len(e.arg.Zones) == 0 {
+		return errors.New("Instances should be > 0. Please use teardown to remove every container.")
+	}
+	if err = checkNotProtected(e.arg.Env); err != nil {
+		return err
+	}
+	if err = checkFreeze(t, &e.arg.ManagerAuthArg, e.arg.Env, e.arg.OverrideFreeze); err != nil {
+		return err
+	}
+	var manifest *Manifest
+	var layout map[string]uint
+	defer func() {
+		recordDeployHistory(t, "Scale", e.arg.ManagerAuthArg.User, e.arg.App, e.arg.Sha, e.arg.Env, manifest,
+			layout, err)
+	}()
+	manifest, layout, e.reply.Added, e.reply.Removed, err = scale(&e.arg.ManagerAuthArg, e.arg.App, e.arg.Sha,
+		e.arg.Env, e.arg.Instances, e.arg.Zones, t)
+	if err != nil {
+		e.reply.Status = StatusError
+	} else {
+		e.reply.Status = StatusOk
+	}
+	return err
+}
+
+func (m *ManagerRPC) Scale(arg ManagerScaleArg, reply *AsyncReply) error {
+	executor := &ScaleExecutor{arg, &ManagerScaleReply{}}
+	return NewTask("Scale", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
+}
+
+type RollbackExecutor struct {
+	arg   ManagerRollbackArg
+	reply *ManagerDeployReply
+}
+
+func (e *RollbackExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *RollbackExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *RollbackExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s in %s -> %s", e.arg.App, e.arg.Env, e.arg.Sha)
+}
+
+func (e *RollbackExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	return AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App)
+}
+
+func (e *RollbackExecutor) Execute(t *Task) (err error) {
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if e.arg.Env == "" {
+		return errors.New("Please specify an environment")
+	}
+	if err = checkNotProtected(e.arg.Env); err != nil {
+		return err
+	}
+	if err = checkFreeze(t, &e.arg.ManagerAuthArg, e.arg.Env, e.arg.OverrideFreeze); err != nil {
+		return err
+	}
+	current, release, err := chooseRollbackRelease(e.arg.App, e.arg.Env, e.arg.Sha)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		recordDeployHistory(t, "Rollback", e.arg.ManagerAuthArg.User, e.arg.App, release.Sha, e.arg.Env,
+			release.Manifest, nil, err)
+		if err == nil {
+			recordRelease(t, e.arg.App, release.Sha, e.arg.Env, release.Manifest)
+		}
+	}()
+	e.reply.Containers, err = rollback(&e.arg.ManagerAuthArg, e.arg.App, e.arg.Env, current, release,
+		e.arg.Teardown, t)
+	return err
+}
+
+func (m *ManagerRPC) Rollback(arg ManagerRollbackArg, reply *AsyncReply) error {
+	executor := &RollbackExecutor{arg, &ManagerDeployReply{}}
+	return NewTask("Rollback", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
 }
 
 func (m *ManagerRPC) DeployResult(id string, result *ManagerDeployReply) error {
@@ -382,18 +732,53 @@ func (m *ManagerRPC) DeployResult(id string, result *ManagerDeployReply) error {
 	if status.Status == StatusUnknown {
 		return errors.New("Unknown ID.")
 	}
-	if status.Name != "Deploy" && status.Name != "CopyContainer" {
+	if status.Name != "Deploy" && status.Name != "DeployManifest" && status.Name != "CopyContainer" &&
+		status.Name != "Rollback" && status.Name != "ApproveDeploy" && status.Name != "Promote" {
 		return errors.New("ID is not a Deploy.")
 	}
 	if !status.Done {
 		return errors.New("Deploy isn't done.")
 	}
+	// a failed deploy is returned too, since its result says how each of its zones did. net/rpc drops the reply
+	// of a call that fails, so the error goes in the reply.
+	getResult := Tracker.Result(id)
+	switch r := getResult.(type) {
+	case *ManagerDeployReply:
+		*result = *r
+	default:
+		if status.Status == StatusError || err != nil {
+			return err
+		}
+		// this should never happen
+		return errors.New("Invalid Result Type.")
+	}
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+	}
+	return nil
+}
+
+func (m *ManagerRPC) ScaleResult(id string, result *ManagerScaleReply) error {
+	if id == "" {
+		return errors.New("ID empty")
+	}
+	status, err := Tracker.Status(id)
+	if status.Status == StatusUnknown {
+		return errors.New("Unknown ID.")
+	}
+	if status.Name != "Scale" {
+		return errors.New("ID is not a Scale.")
+	}
+	if !status.Done {
+		return errors.New("Scale isn't done.")
+	}
 	if status.Status == StatusError || err != nil {
 		return err
 	}
 	getResult := Tracker.Result(id)
 	switch r := getResult.(type) {
-	case *ManagerDeployReply:
+	case *ManagerScaleReply:
 		*result = *r
 	default:
 		// this should never happen
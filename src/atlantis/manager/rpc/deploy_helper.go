@@ -24,7 +24,20 @@ import (
 	. "atlantis/supervisor/rpc/types"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+)
+
+// the supervisor calls that deploys make (swapped out by tests)
+var (
+	supervisorHealthCheck       = supervisor.HealthCheck
+	supervisorDeploy            = supervisor.Deploy
+	supervisorDeployWithTimeout = supervisor.DeployWithTimeout
+	supervisorTeardown          = supervisor.Teardown
 )
 
 //
@@ -34,7 +47,97 @@ import (
 func deployContainer(auth *ManagerAuthArg, cont *Container, instances uint, t *Task) ([]*Container, error) {
 	manifest := cont.Manifest
 	manifest.Instances = instances
-	return deploy(auth, manifest, cont.Sha, cont.Env, t)
+	placement, err := appPlacement(manifest.Name, "", "")
+	if err != nil {
+		return nil, err
+	}
+	deployed, _, err := deploy(auth, manifest, cont.Sha, cont.Env, nil, nil, placement, t)
+	return deployed, err
+}
+
+// how to place the containers of app: with the strategy called name (the app's own if name is empty), on the
+// supervisors matching selector as well as the app's SupervisorSelector
+func appPlacement(app, name, selector string) (*datamodel.Placement, error) {
+	if name == "" {
+		if zkApp, err := datamodel.GetApp(app); err == nil {
+			name = zkApp.Placement
+		}
+	}
+	strategy, err := datamodel.GetPlacementStrategy(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := helper.ParseLabelSelector(selector); err != nil {
+		return nil, err
+	}
+	return &datamodel.Placement{Strategy: strategy, Selector: selector}, nil
+}
+
+// the zone -> instances layout to deploy with. an empty zones means instances in every available zone.
+func deployZones(zones map[string]uint, instances uint) (map[string]uint, error) {
+	if len(zones) == 0 {
+		zones = map[string]uint{}
+		for _, zone := range AvailableZones {
+			zones[zone] = instances
+		}
+		return zones, nil
+	}
+	if err := checkZonesAvailable(zones); err != nil {
+		return nil, err
+	}
+	for _, zone := range datamodel.SortedZones(zones) {
+		if zones[zone] == 0 {
+			return nil, errors.New(fmt.Sprintf("Instances in zone %s should be > 0", zone))
+		}
+	}
+	return zones, nil
+}
+
+func checkZonesAvailable(zones map[string]uint) error {
+	available := map[string]bool{}
+	for _, zone := range AvailableZones {
+		available[zone] = true
+	}
+	for _, zone := range datamodel.SortedZones(zones) {
+		if !available[zone] {
+			return errors.New(fmt.Sprintf("Zone %s is not available. Available zones are %v", zone,
+				AvailableZones))
+		}
+	}
+	return nil
+}
+
+// the zone -> instances layout app @ sha in env was last deployed or scaled to (nil if none was recorded)
+func getInstanceLayout(app, sha, env string) map[string]uint {
+	containerIDs, err := datamodel.ListInstances(app, sha, env)
+	if err != nil {
+		return nil
+	}
+	for _, containerID := range containerIDs {
+		if inst, err := datamodel.GetInstance(containerID); err == nil && inst.Zones != nil {
+			return inst.Zones
+		}
+	}
+	return nil
+}
+
+// records zones as the layout of every container of app @ sha in env. failing to record it should not fail the task
+// so errors are only logged.
+func setInstanceLayout(app, sha, env string, zones map[string]uint, t *Task) {
+	containerIDs, err := datamodel.ListInstances(app, sha, env)
+	if err != nil {
+		t.Log("Error recording zones of %s @ %s in %s: %s", app, sha, env, err.Error())
+		return
+	}
+	for _, containerID := range containerIDs {
+		inst, err := datamodel.GetInstance(containerID)
+		if err == nil {
+			err = inst.SetZones(zones)
+		}
+		if err != nil {
+			t.Log("Error recording zones of %s: %s", containerID, err.Error())
+		}
+	}
 }
 
 func MergeDependerEnvData(dst *DependerEnvData, src *DependerEnvData) *DependerEnvData {
@@ -142,9 +245,11 @@ func ResolveDepValues(app string, zkEnv *datamodel.ZkEnv, names []string, encryp
 
 func validateDeploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, t *Task) (deps map[string]DepsType, err error) {
 	t.LogStatus("Validate Deploy")
-	// authorize that we're allowed to use the app
-	if err = AuthorizeApp(auth, manifest.Name); err != nil {
-		return nil, errors.New("Permission Denied: " + err.Error())
+	// authorize that we're allowed to use the app (scheduled deploys were authorized when they were scheduled)
+	if !scheduledTask(t.ID) {
+		if err = AuthorizeApp(auth, manifest.Name); err != nil {
+			return nil, errors.New("Permission Denied: " + err.Error())
+		}
 	}
 	// fetch the environment
 	t.LogStatus("Fetching Environment")
@@ -185,15 +290,24 @@ func deployToHost(respCh chan *DeployHostResult, manifest *Manifest, sha, env, h
 		respCh <- &DeployHostResult{Host: host, Container: nil, Error: err}
 		return
 	}
-	ihReply, err := supervisor.Deploy(host, manifest.Name, sha, env, instance.ID, manifest)
+	var ihReply *SupervisorDeployReply
+	if DeployHostTimeout > 0 {
+		ihReply, err = supervisorDeployWithTimeout(host, manifest.Name, sha, env, instance.ID, manifest,
+			int(DeployHostTimeout.Seconds()))
+	} else {
+		ihReply, err = supervisorDeploy(host, manifest.Name, sha, env, instance.ID, manifest)
+	}
 	if err != nil {
+		// the supervisor may still be working on it (e.g. if we timed out), make sure it doesn't stick around
+		go supervisorTeardown(host, []string{instance.ID}, false)
 		instance.Delete()
 		respCh <- &DeployHostResult{Host: host, Container: nil, Error: err}
 		return
 	}
 	if ihReply.Status != StatusOk {
 		instance.Delete()
-		respCh <- &DeployHostResult{Host: host, Container: nil, Error: err}
+		respCh <- &DeployHostResult{Host: host, Container: nil,
+			Error: errors.New(fmt.Sprintf("Deploy Status: %s", ihReply.Status))}
 		return
 	}
 	ihReply.Container.Host = host
@@ -206,107 +320,216 @@ func deployToHost(respCh chan *DeployHostResult, manifest *Manifest, sha, env, h
 type DeployZoneResult struct {
 	Zone       string
 	Containers []*Container
+	Skipped    map[string]string // host -> why it was not used for the rest of the deploy
 	Error      error
 }
 
-func deployToZone(respCh chan *DeployZoneResult, deps map[string]DepsType, rawManifest *Manifest, sha,
-	env string, hosts []string, zone string) {
+// deploys instances round robin over hosts. a host that fails a health check or a deploy is skipped for the rest of
+// the deploy and the containers that failed are retried on the hosts that are left, up to DeployHostRetries times
+// with backoff in between. if onePerHost, no host gets more than one container (and a retry never goes to a host
+// that already got one).
+func deployToZone(respCh chan *DeployZoneResult, deps map[string]DepsType, rawManifest *Manifest,
+	instances uint, sha, env string, hosts []string, zone string, onePerHost bool) {
 	hostNum := 0
-	failures := 0
+	retries := uint(0)
+	backoff := DeployRetryBackoff
 	deployed := uint(0)
-	maxFailures := len(hosts)
 	deployedContainers := []*Container{}
-	for deployed < rawManifest.Instances && failures < maxFailures {
-		numToDeploy := rawManifest.Instances - deployed
-		respCh := make(chan *DeployHostResult, numToDeploy)
-		for i := uint(0); i < numToDeploy; i++ {
+	skipped := map[string]string{}
+	full := map[string]bool{} // hosts that got their one container if onePerHost
+	for deployed < instances {
+		if retries > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		numToDeploy := instances - deployed
+		hostCh := make(chan *DeployHostResult, numToDeploy)
+		healthZones := map[string]string{} // only check the health of each host once per round
+		started := uint(0)
+		for started < numToDeploy && len(skipped)+len(full) < len(hosts) {
 			host := hosts[hostNum]
-			// check health on host to figure out its zone to get the deps
-			ihReply, err := supervisor.HealthCheck(host)
-			if err == nil && ihReply.Status == StatusOk {
-				// only try to deploy if health was fine
-				// duplicate manifest and get deps
-				manifest := rawManifest.Dup()
-				manifest.Deps = deps[ihReply.Zone]
-				go deployToHost(respCh, manifest, sha, env, host)
-			}
 			hostNum++
 			if hostNum >= len(hosts) {
 				hostNum = 0
 			}
+			if _, ok := skipped[host]; ok || full[host] {
+				continue
+			}
+			// check health on host to figure out its zone to get the deps
+			hostZone, ok := healthZones[host]
+			if !ok {
+				ihReply, err := supervisorHealthCheck(host)
+				if err != nil {
+					skipped[host] = "Health Check Error: " + err.Error()
+					continue
+				} else if ihReply.Status != StatusOk {
+					skipped[host] = "Health Check Status: " + ihReply.Status
+					continue
+				}
+				hostZone = ihReply.Zone
+				healthZones[host] = hostZone
+			}
+			// only try to deploy if health was fine
+			// duplicate manifest and get deps
+			manifest := rawManifest.Dup()
+			manifest.Deps = deps[hostZone]
+			go deployToHost(hostCh, manifest, sha, env, host)
+			started++
+			if onePerHost {
+				full[host] = true
+			}
 		}
-		numResult := uint(0)
-		for result := range respCh {
+		for i := uint(0); i < started; i++ {
+			result := <-hostCh
 			if result.Error != nil {
-				failures++
+				delete(full, result.Host)
+				skipped[result.Host] = "Deploy Error: " + result.Error.Error()
 			} else {
 				deployed++
 				deployedContainers = append(deployedContainers, result.Container)
 			}
-			numResult++
-			if numResult >= numToDeploy { // we're done
-				close(respCh)
-			}
 		}
+		if deployed >= instances || len(skipped)+len(full) >= len(hosts) || retries >= DeployHostRetries {
+			break
+		}
+		retries++
 	}
-	if failures >= maxFailures {
+	if deployed < instances {
 		respCh <- &DeployZoneResult{
 			Zone:       zone,
 			Containers: deployedContainers,
-			Error: errors.New(fmt.Sprintf("Failed to deploy %d instances in zone %s.", rawManifest.Instances,
-				zone)),
+			Skipped:    skipped,
+			Error: errors.New(fmt.Sprintf("Failed to deploy %d instances in zone %s. Deployed %d after %d retries.",
+				instances, zone, deployed, retries)),
 		}
 		return
 	}
 	respCh <- &DeployZoneResult{
 		Zone:       zone,
 		Containers: deployedContainers,
+		Skipped:    skipped,
 		Error:      nil,
 	}
 	return
 }
 
+// how many zones of a deploy have to succeed for the zones that did to be kept. a nil *zonePolicy needs every zone.
+type zonePolicy struct {
+	minZones uint     // 0 means every zone, or only the required ones if there are any
+	required []string // zones that have to succeed no matter how many others do
+}
+
+// returns an error if the zones that succeeded are not enough for the policy
+func (p *zonePolicy) check(statuses map[string]*ZoneStatus) error {
+	zones := make([]string, 0, len(statuses))
+	for zone := range statuses {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	succeeded := uint(0)
+	for _, zone := range zones {
+		if statuses[zone].Status == StatusOk {
+			succeeded++
+		} else if p == nil {
+			return errors.New(statuses[zone].Error)
+		}
+	}
+	if p == nil {
+		return nil
+	}
+	for _, zone := range p.required {
+		if zoneStatus, ok := statuses[zone]; ok && zoneStatus.Status != StatusOk {
+			return errors.New(fmt.Sprintf("Required zone %s failed: %s", zone, zoneStatus.Error))
+		}
+	}
+	minZones := p.minZones
+	if minZones == 0 && len(p.required) == 0 {
+		minZones = uint(len(zones))
+	}
+	if succeeded == 0 || succeeded < minZones {
+		return errors.New(fmt.Sprintf("Only %d of %d zones succeeded (%d needed)", succeeded, len(zones), minZones))
+	}
+	return nil
+}
+
+// deploys zones[zone] containers to the hosts of each zone. if policy allows it, the zones that fail are cleaned up
+// and the rest are kept. returns the status of every zone whether or not the deploy succeeded.
 func deployToHostsInZones(deps map[string]DepsType, manifest *Manifest, sha, env string,
-	hosts map[string][]string, zones []string, t *Task) ([]*Container, error) {
+	hosts map[string][]string, zones map[string]uint, policy *zonePolicy,
+	t *Task) ([]*Container, map[string]*ZoneStatus, error) {
 	deployedContainers := []*Container{}
+	failedContainers := []*Container{}
+	statuses := map[string]*ZoneStatus{}
 	// fetch the app
 	zkApp, err := datamodel.GetApp(manifest.Name)
 	if err != nil {
-		return nil, err
+		return nil, statuses, err
 	}
 	// first check if zones have enough hosts
-	for _, zone := range zones {
+	toDeploy := map[string]uint{}
+	for _, zone := range datamodel.SortedZones(zones) {
 		// fail if zone has no hosts
 		if hosts[zone] == nil || len(hosts[zone]) == 0 {
-			return nil, errors.New(fmt.Sprintf("No hosts available for app %s in zone %s", manifest.Name, zone))
+			err := errors.New(fmt.Sprintf("No hosts available for app %s in zone %s", manifest.Name, zone))
+			if policy == nil {
+				return nil, statuses, err
+			}
+			t.Log(err.Error())
+			statuses[zone] = &ZoneStatus{Status: StatusError, Error: err.Error()}
+			continue
 		}
+		toDeploy[zone] = zones[zone]
+	}
+	if len(toDeploy) == 0 {
+		return nil, statuses, policy.check(statuses)
 	}
 	// now that we know that enough hosts are available
-	t.LogStatus("Deploying to zones: %v", zones)
-	respCh := make(chan *DeployZoneResult, len(zones))
-	for _, zone := range zones {
-		go deployToZone(respCh, deps, manifest, sha, env, hosts[zone], zone)
+	t.LogStatus("Deploying to zones: %v", toDeploy)
+	respCh := make(chan *DeployZoneResult, len(toDeploy))
+	for zone, instances := range toDeploy {
+		go deployToZone(respCh, deps, manifest, instances, sha, env, hosts[zone], zone, zkApp.OnePerSupervisor())
 	}
 	numResults := 0
 	status := "Deployed to zones: "
+	containerZones := map[string]string{}
 	for result := range respCh {
-		deployedContainers = append(deployedContainers, result.Containers...)
+		for host, reason := range result.Skipped {
+			t.Log("Skipped %s in zone %s: %s", host, result.Zone, reason)
+		}
 		if result.Error != nil {
-			err = result.Error
-			t.Log(err.Error())
+			t.Log(result.Error.Error())
 			status += result.Zone + ":FAIL "
+			statuses[result.Zone] = &ZoneStatus{Status: StatusError, Error: result.Error.Error()}
+			failedContainers = append(failedContainers, result.Containers...)
 		} else {
 			status += result.Zone + ":SUCCESS "
+			statuses[result.Zone] = &ZoneStatus{Status: StatusOk}
+			deployedContainers = append(deployedContainers, result.Containers...)
+			for _, cont := range result.Containers {
+				containerZones[cont.ID] = result.Zone
+			}
 		}
 		t.LogStatus(status)
 		numResults++
-		if numResults >= len(zones) { // we're done
+		if numResults >= len(toDeploy) { // we're done
 			close(respCh)
 		}
 	}
+	err = policy.check(statuses)
+	if err == nil {
+		err = checkCancelled(t)
+	}
 	if err != nil {
-		cleanup(false, deployedContainers, t)
-		return nil, err
+		cleanup(false, append(deployedContainers, failedContainers...), t)
+		return nil, statuses, err
+	}
+	if len(failedContainers) > 0 {
+		cleanup(false, failedContainers, t)
+	}
+	for _, zone := range datamodel.SortedZones(zones) {
+		if statuses[zone].Status != StatusOk {
+			t.AddWarning(fmt.Sprintf("Deploy to zone %s failed: %s", zone, statuses[zone].Error))
+		}
 	}
 
 	// set ports on zk supervisor - can't do this in parallel. we may deploy to the same host at the same time
@@ -316,6 +539,32 @@ func deployToHostsInZones(deps map[string]DepsType, manifest *Manifest, sha, env
 		datamodel.Supervisor(cont.Host).SetContainerAndPort(cont.ID, cont.PrimaryPort)
 	}
 
+	// only pool containers that come up healthy
+	if HealthzTimeout > 0 {
+		t.LogStatus("Waiting up to %s for containers to become healthy", HealthzTimeout.String())
+		healthy, unhealthy := waitForHealthy(deployedContainers, HealthzTimeout)
+		if len(healthy) == 0 || uint(len(healthy))*100 < MinHealthyPercent*uint(len(deployedContainers)) {
+			cleanup(true, deployedContainers, t)
+			return nil, statuses, errors.New(fmt.Sprintf("Healthz Error: only %d of %d containers became healthy",
+				len(healthy), len(deployedContainers)))
+		}
+		if len(unhealthy) > 0 {
+			for _, cont := range unhealthy {
+				t.AddWarning(fmt.Sprintf("Container %s on %s did not become healthy and was torn down", cont.ID,
+					cont.Host))
+			}
+			cleanup(true, unhealthy, t)
+		}
+		deployedContainers = healthy
+	}
+	if err := checkCancelled(t); err != nil {
+		cleanup(true, deployedContainers, t)
+		return nil, statuses, err
+	}
+	for _, cont := range deployedContainers {
+		statuses[containerZones[cont.ID]].Containers++
+	}
+
 	// we're good now, so lets move on
 	t.LogStatus("Updating Router")
 	deployedIDs := make([]string, len(deployedContainers))
@@ -327,7 +576,7 @@ func deployToHostsInZones(deps map[string]DepsType, manifest *Manifest, sha, env
 	err = datamodel.AddToPool(deployedIDs)
 	if err != nil { // if we can't add the pool, clean up and fail
 		cleanup(true, deployedContainers, t)
-		return nil, errors.New("Update Pool Error: " + err.Error())
+		return nil, statuses, errors.New("Update Pool Error: " + err.Error())
 	}
 	if zkApp.Internal {
 		// reserve router port if needed and add app+env
@@ -335,7 +584,7 @@ func deployToHostsInZones(deps map[string]DepsType, manifest *Manifest, sha, env
 		if err != nil {
 			datamodel.DeleteFromPool(deployedIDs)
 			cleanup(true, deployedContainers, t)
-			return nil, errors.New("Reserve Router Port Error: " + err.Error())
+			return nil, statuses, errors.New("Reserve Router Port Error: " + err.Error())
 		}
 	} else {
 		// only update trie
@@ -343,28 +592,189 @@ func deployToHostsInZones(deps map[string]DepsType, manifest *Manifest, sha, env
 		if err != nil {
 			datamodel.DeleteFromPool(deployedIDs)
 			cleanup(true, deployedContainers, t)
-			return nil, errors.New("Reserve Router Port Error: " + err.Error())
+			return nil, statuses, errors.New("Reserve Router Port Error: " + err.Error())
 		}
 	}
-	return deployedContainers, nil
+	return deployedContainers, statuses, nil
 }
 
-func deploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, t *Task) ([]*Container, error) {
+// deploys zones[zone] containers in each zone (manifest.Instances in every available zone if zones is empty). the
+// layout of the zones that succeeded is added to whatever app @ sha in env already had and recorded on its
+// containers. see deployToHostsInZones for policy. placement decides which supervisors are used, and which first.
+func deploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, zones map[string]uint, policy *zonePolicy,
+	placement *datamodel.Placement, t *Task) ([]*Container, map[string]*ZoneStatus, error) {
+	zones, err := deployZones(zones, manifest.Instances)
+	if err != nil {
+		return nil, nil, err
+	}
+	if policy != nil {
+		if policy.minZones > uint(len(zones)) {
+			return nil, nil, errors.New(fmt.Sprintf("Can't need %d zones to succeed when deploying to %d",
+				policy.minZones, len(zones)))
+		}
+		for _, zone := range policy.required {
+			if _, ok := zones[zone]; !ok {
+				return nil, nil, errors.New(fmt.Sprintf("Required zone %s is not one of the zones being deployed to",
+					zone))
+			}
+		}
+	}
 	deps, err := validateDeploy(auth, manifest, sha, env, t)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	layout := map[string]uint{}
+	for zone, instances := range getInstanceLayout(manifest.Name, sha, env) {
+		layout[zone] = instances
 	}
 	// choose hosts
 	t.LogStatus("Choosing Supervisors")
-	hosts, err := datamodel.ChooseSupervisors(manifest.Name, sha, env, manifest.Instances, manifest.CPUShares,
-		manifest.MemoryLimit, AvailableZones, map[string]bool{})
+	var hosts map[string][]string
+	if policy == nil {
+		var unreachable map[string]string
+		hosts, unreachable, err = datamodel.ChooseSupervisors(manifest.Name, sha, env, zones, manifest.CPUShares,
+			manifest.MemoryLimit, map[string]bool{}, placement)
+		logUnreachable(t, unreachable)
+	} else {
+		hosts, err = chooseSupervisorsPerZone(manifest.Name, sha, env, zones, manifest.CPUShares,
+			manifest.MemoryLimit, placement, t)
+	}
+	if err != nil {
+		return nil, nil, errors.New("Choose Supervisors Error: " + err.Error())
+	}
+	deployed, statuses, err := deployToHostsInZones(deps, manifest, sha, env, hosts, zones, policy, t)
+	if err != nil {
+		return nil, statuses, err
+	}
+	for zone, instances := range zones {
+		if statuses[zone].Status == StatusOk {
+			layout[zone] += instances
+		}
+	}
+	setInstanceLayout(manifest.Name, sha, env, layout, t)
+	return deployed, statuses, nil
+}
+
+// tells the task log about the supervisors that were skipped while choosing supervisors because they are down
+func logUnreachable(t *Task, unreachable map[string]string) {
+	hosts := make([]string, 0, len(unreachable))
+	for host := range unreachable {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		t.Log("Skipping supervisor %s: %s", host, unreachable[host])
+	}
+}
+
+// like datamodel.ChooseSupervisors but a zone without room for its instances is left out (so that
+// deployToHostsInZones fails just that zone) instead of failing every zone
+func chooseSupervisorsPerZone(app, sha, env string, zones map[string]uint, cpu, memory uint,
+	placement *datamodel.Placement, t *Task) (map[string][]string, error) {
+	list, ruledOut, unreachable, err := datamodel.ChooseSupervisorsList(app, sha, env, cpu, memory,
+		datamodel.SortedZones(zones), map[string]bool{}, placement)
+	if err != nil {
+		return nil, err
+	}
+	logUnreachable(t, unreachable)
+	hosts := map[string][]string{}
+	for _, zone := range datamodel.SortedZones(zones) {
+		zoneHosts, err := datamodel.SupervisorsByZone(app, map[string]uint{zone: zones[zone]}, list, ruledOut)
+		if err != nil {
+			t.Log("Skipping zone %s: %s", zone, err.Error())
+			continue
+		}
+		hosts[zone] = zoneHosts[zone]
+	}
+	return hosts, nil
+}
+
+// the part of zones (see deployZones) that app @ sha in env does not have yet. used to retry the zones that failed
+// in an earlier deploy.
+func missingZones(app, sha, env string, zones map[string]uint, instances uint) (map[string]uint, error) {
+	zones, err := deployZones(zones, instances)
+	if err != nil {
+		return nil, err
+	}
+	byZone, err := listInstancesByZone(app, sha, env)
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string]uint{}
+	for zone, want := range zones {
+		if have := uint(len(byZone[zone])); have < want {
+			missing[zone] = want - have
+		}
+	}
+	if len(missing) == 0 {
+		return nil, errors.New(fmt.Sprintf("%s @ %s already has every zone it needs in %s", app, sha, env))
+	}
+	return missing, nil
+}
+
+// does everything deploy (or devDeploy if dev) does up to choosing supervisors and returns where the containers
+// would go without deploying anything.
+func planDeploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, zones map[string]uint, dev bool,
+	placement *datamodel.Placement, t *Task) (map[string][]*PlannedHost, error) {
+	if dev {
+		manifest.Instances = 1
+	}
+	zones, err := deployZones(zones, manifest.Instances)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := validateDeploy(auth, manifest, sha, env, t); err != nil {
+		return nil, err
+	}
+	t.LogStatus("Choosing Supervisors")
+	list, ruledOut, unreachable, err := datamodel.ChooseSupervisorsList(manifest.Name, sha, env, manifest.CPUShares,
+		manifest.MemoryLimit, datamodel.SortedZones(zones), map[string]bool{}, placement)
+	if err != nil {
+		return nil, errors.New("Choose Supervisors Error: " + err.Error())
+	}
+	logUnreachable(t, unreachable)
+	if dev {
+		hosts := make([]string, len(list))
+		for i, elem := range list {
+			hosts[i] = elem.Supervisor
+		}
+		if len(hosts) == 0 {
+			return nil, errors.New(fmt.Sprintf("No hosts available for app %s in zone [any]", manifest.Name) +
+				datamodel.RuledOutReason(ruledOut, AvailableZones...))
+		}
+		return planPlacement(list, map[string][]string{"[any]": hosts}, map[string]uint{"[any]": 1}), nil
+	}
+	hosts, err := datamodel.SupervisorsByZone(manifest.Name, zones, list, ruledOut)
 	if err != nil {
 		return nil, errors.New("Choose Supervisors Error: " + err.Error())
 	}
-	return deployToHostsInZones(deps, manifest, sha, env, hosts, AvailableZones, t)
+	return planPlacement(list, hosts, zones), nil
 }
 
-func devDeploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, t *Task) ([]*Container, error) {
+// lays out zones[zone] instances in each zone the same way deployToZone does: round robin over the hosts in weight
+// order
+func planPlacement(list datamodel.SupervisorAndWeightList, hosts map[string][]string,
+	zones map[string]uint) map[string][]*PlannedHost {
+	weights := map[string]datamodel.SupervisorAndWeight{}
+	for _, elem := range list {
+		weights[elem.Supervisor] = elem
+	}
+	plan := map[string][]*PlannedHost{}
+	for zone, instances := range zones {
+		planned := make([]*PlannedHost, len(hosts[zone]))
+		for i, host := range hosts[zone] {
+			planned[i] = &PlannedHost{Host: host, Free: weights[host].Free, Weight: weights[host].Weight}
+		}
+		for i := uint(0); i < instances && len(planned) > 0; i++ {
+			planned[i%uint(len(planned))].Instances++
+		}
+		plan[zone] = planned
+	}
+	return plan
+}
+
+func devDeploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, placement *datamodel.Placement,
+	t *Task) ([]*Container, error) {
 	manifest.Instances = 1 // set to 1 instance regardless of what came in
 	deps, err := validateDeploy(auth, manifest, sha, env, t)
 	if err != nil {
@@ -375,23 +785,528 @@ func devDeploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, t *Tas
 	}
 	// choose hosts
 	t.LogStatus("Choosing Supervisors")
-	list, err := datamodel.ChooseSupervisorsList(manifest.Name, sha, env, manifest.CPUShares, manifest.MemoryLimit,
-		AvailableZones, map[string]bool{})
+	list, ruledOut, unreachable, err := datamodel.ChooseSupervisorsList(manifest.Name, sha, env, manifest.CPUShares,
+		manifest.MemoryLimit, AvailableZones, map[string]bool{}, placement)
 	if err != nil {
 		return nil, errors.New("Choose Supervisors Error: " + err.Error())
 	}
+	logUnreachable(t, unreachable)
 	hosts := make([]string, len(list))
 	for i, elem := range list {
 		hosts[i] = elem.Supervisor
 	}
-	return deployToHostsInZones(deps, manifest, sha, env, map[string][]string{"[any]": hosts}, []string{"[any]"}, t)
+	if len(hosts) == 0 {
+		return nil, errors.New(fmt.Sprintf("No hosts available for app %s in zone [any]", manifest.Name) +
+			datamodel.RuledOutReason(ruledOut, AvailableZones...))
+	}
+	deployed, _, err := deployToHostsInZones(deps, manifest, sha, env, map[string][]string{"[any]": hosts},
+		map[string]uint{"[any]": 1}, nil, t)
+	return deployed, err
+}
+
+// deploys sha and then retires every other sha of the app in env: the app+env trie is swapped to point only at
+// the new sha, we wait for the old pools to drain, and then the old containers are torn down. if anything fails
+// after the new sha is up, the trie is put back the way it was so that traffic keeps flowing to the old shas. if
+// zones is empty, the new sha keeps the layout of the shas it replaces.
+func replaceDeploy(auth *ManagerAuthArg, manifest *Manifest, sha, env string, zones map[string]uint, drain,
+	watch time.Duration, maxFailPct uint, placement *datamodel.Placement, t *Task) ([]*Container, error) {
+	zkApp, err := datamodel.GetApp(manifest.Name)
+	if err != nil {
+		return nil, err
+	}
+	oldShas, err := listOtherShasInEnv(manifest.Name, sha, env)
+	if err != nil {
+		return nil, err
+	}
+	for _, oldSha := range oldShas {
+		if len(zones) > 0 {
+			break
+		}
+		if zones = getInstanceLayout(manifest.Name, oldSha, env); zones != nil {
+			t.Log("Keeping the zones of %s: %v", oldSha, zones)
+		}
+	}
+	deployed, _, err := deploy(auth, manifest, sha, env, zones, nil, placement, t)
+	if err != nil {
+		return nil, err
+	}
+	if len(oldShas) == 0 {
+		t.Log("No other shas of %s in %s to replace", manifest.Name, env)
+		if err := watchDeploy(zkApp.Internal, manifest.Name, sha, env, nil, deployed, watch, maxFailPct,
+			t); err != nil {
+			return nil, err
+		}
+		return deployed, nil
+	}
+	if err := checkCancelled(t); err != nil {
+		rollbackDeploy(zkApp.Internal, manifest.Name, sha, env, nil, deployed, t)
+		return nil, err
+	}
+	t.LogStatus("Swapping Router to %s", sha)
+	oldRules, err := datamodel.SwapAppEnvTrie(zkApp.Internal, manifest.Name, sha, env)
+	if err != nil {
+		// nothing routes to the new containers, so they would only take up room
+		rollbackDeploy(zkApp.Internal, manifest.Name, sha, env, oldRules, deployed, t)
+		return nil, errors.New("Swap Router Error: " + err.Error())
+	}
+	// the old shas are still up while we watch so there is something to roll back to
+	if err := watchDeploy(zkApp.Internal, manifest.Name, sha, env, oldRules, deployed, watch, maxFailPct,
+		t); err != nil {
+		return nil, err
+	}
+	if drain > 0 {
+		t.LogStatus("Draining %v for %s", oldShas, drain.String())
+		time.Sleep(drain)
+	}
+	if err := checkCancelled(t); err != nil {
+		rollbackDeploy(zkApp.Internal, manifest.Name, sha, env, oldRules, deployed, t)
+		return nil, err
+	}
+	// the new sha is healthy and serving, so an old sha that can't be torn down is left up without traffic rather
+	// than swapped back to
+	for _, oldSha := range oldShas {
+		t.LogStatus("Tearing Down %s @ %s in %s", manifest.Name, oldSha, env)
+		if _, err := teardownShaEnv(t, manifest.Name, oldSha, env); err != nil {
+			left, _ := datamodel.ListInstances(manifest.Name, oldSha, env)
+			t.AddWarning(fmt.Sprintf("Error Tearing Down %s: %s. Its containers are left up without traffic: %s",
+				oldSha, err.Error(), strings.Join(left, ", ")))
+		}
+	}
+	return deployed, nil
+}
+
+// containers of app @ sha in env grouped by the zone of their supervisor
+func listInstancesByZone(app, sha, env string) (map[string][]*datamodel.ZkInstance, error) {
+	containerIDs, err := datamodel.ListInstances(app, sha, env)
+	if err != nil {
+		return nil, err
+	}
+	hostZones := map[string]string{}
+	byZone := map[string][]*datamodel.ZkInstance{}
+	for _, containerID := range containerIDs {
+		inst, err := datamodel.GetInstance(containerID)
+		if err != nil {
+			continue
+		}
+		zone, ok := hostZones[inst.Host]
+		if !ok {
+			if zone, err = supervisorZone(inst.Host); err != nil {
+				return nil, errors.New(fmt.Sprintf("Error getting zone of %s: %s", inst.Host, err.Error()))
+			}
+			hostZones[inst.Host] = zone
+		}
+		byZone[zone] = append(byZone[zone], inst)
+	}
+	return byZone, nil
+}
+
+// the zone of host from its inventory. only a supervisor that has never had a heartbeat is asked.
+func supervisorZone(host string) (string, error) {
+	if inventory, err := datamodel.GetSupervisorInventory(host); err == nil && inventory.Zone != "" {
+		return inventory.Zone, nil
+	}
+	return supervisor.GetZone(host)
+}
+
+// how loaded host is from the point of view of app @ sha in env. uses the same weight as the spread placement so
+// the containers we remove come from the supervisors a deploy would be least likely to pick. supervisors that are
+// down or not OK are the most loaded of all.
+func supervisorLoad(host, app, sha, env string) float64 {
+	inventory, err := datamodel.GetSupervisorInventory(host)
+	if err != nil || inventory.State == SupervisorDown || inventory.Status != StatusOk {
+		return math.MaxFloat64
+	}
+	return float64(2*inventory.CountAppShaEnv(app, sha, env)) +
+		(float64(inventory.UsedMemory) / float64(inventory.TotalMemory)) +
+		(float64(inventory.UsedCPUShares) / float64(inventory.TotalCPUShares))
+}
+
+// picks num of insts to remove, one at a time from whichever supervisor is the most loaded. each container removed
+// takes 2 off of its supervisor's load, the same as its weight in the spread placement.
+func chooseContainersToRemove(insts []*datamodel.ZkInstance, num uint,
+	load map[string]float64) []*datamodel.ZkInstance {
+	byHost := map[string][]*datamodel.ZkInstance{}
+	for _, inst := range insts {
+		byHost[inst.Host] = append(byHost[inst.Host], inst)
+	}
+	hostLoad := map[string]float64{}
+	for host := range byHost {
+		hostLoad[host] = load[host]
+	}
+	chosen := []*datamodel.ZkInstance{}
+	for uint(len(chosen)) < num {
+		maxHost := ""
+		for host, hostInsts := range byHost {
+			if len(hostInsts) == 0 {
+				continue
+			}
+			if maxHost == "" || hostLoad[host] > hostLoad[maxHost] ||
+				(hostLoad[host] == hostLoad[maxHost] && host < maxHost) {
+				maxHost = host
+			}
+		}
+		if maxHost == "" {
+			break // nothing left to remove
+		}
+		chosen = append(chosen, byHost[maxHost][0])
+		byHost[maxHost] = byHost[maxHost][1:]
+		if hostLoad[maxHost] != math.MaxFloat64 {
+			hostLoad[maxHost] -= 2
+		}
+	}
+	return chosen
+}
+
+// adds or removes containers of app @ sha in env so that each zone in zones has zones[zone] of them (0 removes the
+// zone). if zones is empty, every zone of the recorded layout (or every available zone if there is none) gets
+// instances. zones that are not targeted are left alone. new containers are deployed with the manifest of an existing
+// one and go through the usual deploy path (including pooling). containers are taken out of their pools before they
+// are torn down so no traffic is sent to them. the manifest and the layout after the scale are returned as well.
+func scale(auth *ManagerAuthArg, app, sha, env string, instances uint, zones map[string]uint,
+	t *Task) (*Manifest, map[string]uint, []*Container, []string, error) {
+	added := []*Container{}
+	removed := []string{}
+	t.LogStatus("Listing Containers")
+	byZone, err := listInstancesByZone(app, sha, env)
+	if err != nil {
+		return nil, nil, added, removed, err
+	}
+	var manifest *Manifest
+	for _, insts := range byZone {
+		for _, inst := range insts {
+			if inst.Manifest != nil {
+				manifest = inst.Manifest.Dup()
+				break
+			} else if ihReply, err := supervisor.Get(inst.Host, inst.ID); err == nil {
+				manifest = ihReply.Container.Manifest.Dup()
+				break
+			}
+		}
+		if manifest != nil {
+			break
+		}
+	}
+	if manifest == nil {
+		return nil, nil, added, removed, errors.New(fmt.Sprintf("%s @ %s is not deployed in %s. Please deploy it "+
+			"first.", app, sha, env))
+	}
+	if instances > 0 {
+		manifest.Instances = instances
+	}
+	placement, err := appPlacement(app, "", "")
+	if err != nil {
+		return manifest, nil, added, removed, err
+	}
+	layout := getInstanceLayout(app, sha, env)
+	if len(zones) == 0 && layout != nil {
+		zones = map[string]uint{}
+		for zone := range layout {
+			zones[zone] = instances
+		}
+	}
+	if len(zones) == 0 {
+		if zones, err = deployZones(zones, instances); err != nil {
+			return manifest, nil, added, removed, err
+		}
+	} else if err = checkZonesAvailable(zones); err != nil {
+		return manifest, nil, added, removed, err
+	}
+	// scale down first so that a scale up can use the room that frees up
+	for _, zone := range datamodel.SortedZones(zones) {
+		have, want := uint(len(byZone[zone])), zones[zone]
+		if have <= want {
+			continue
+		}
+		if err := checkCancelled(t); err != nil {
+			return manifest, nil, added, removed, err
+		}
+		t.LogStatus("Scaling %s from %d to %d", zone, have, want)
+		load := map[string]float64{}
+		for _, inst := range byZone[zone] {
+			if _, ok := load[inst.Host]; !ok {
+				load[inst.Host] = supervisorLoad(inst.Host, app, sha, env)
+			}
+		}
+		hostMap := map[string][]string{}
+		containerIDs := []string{}
+		for _, inst := range chooseContainersToRemove(byZone[zone], have-want, load) {
+			hostMap[inst.Host] = append(hostMap[inst.Host], inst.ID)
+			containerIDs = append(containerIDs, inst.ID)
+		}
+		if err := datamodel.DeleteFromPool(containerIDs); err != nil {
+			return manifest, nil, added, removed, errors.New("Update Pool Error: " + err.Error())
+		}
+		tl := datamodel.NewTeardownLock(t.ID, app, sha, env)
+		if err := tl.Lock(); err != nil {
+			return manifest, nil, added, removed, err
+		}
+		tornDown, err := teardownContainers(t, hostMap, false)
+		tl.Unlock()
+		removed = append(removed, tornDown...)
+		if err != nil {
+			return manifest, nil, added, removed, err
+		}
+	}
+	var deps map[string]DepsType
+	for _, zone := range datamodel.SortedZones(zones) {
+		have, want := uint(len(byZone[zone])), zones[zone]
+		if have >= want {
+			continue
+		}
+		t.LogStatus("Scaling %s from %d to %d", zone, have, want)
+		zoneManifest := manifest.Dup()
+		zoneManifest.Instances = want - have
+		if deps == nil {
+			if deps, err = validateDeploy(auth, zoneManifest, sha, env, t); err != nil {
+				return manifest, nil, added, removed, err
+			}
+		}
+		zoneInstances := map[string]uint{zone: zoneManifest.Instances}
+		hosts, unreachable, err := datamodel.ChooseSupervisors(app, sha, env, zoneInstances, zoneManifest.CPUShares,
+			zoneManifest.MemoryLimit, map[string]bool{}, placement)
+		logUnreachable(t, unreachable)
+		if err != nil {
+			return manifest, nil, added, removed, errors.New("Choose Supervisors Error: " + err.Error())
+		}
+		deployed, _, err := deployToHostsInZones(deps, zoneManifest, sha, env, hosts, zoneInstances, nil, t)
+		added = append(added, deployed...)
+		if err != nil {
+			return manifest, nil, added, removed, err
+		}
+	}
+	newLayout := map[string]uint{}
+	for zone, instances := range layout {
+		newLayout[zone] = instances
+	}
+	for zone, instances := range zones {
+		if instances == 0 {
+			delete(newLayout, zone)
+		} else {
+			newLayout[zone] = instances
+		}
+	}
+	setInstanceLayout(app, sha, env, newLayout, t)
+	return manifest, newLayout, added, removed, nil
+}
+
+// returns the current release of app+env and the release to roll back to: the one with sha if given, otherwise the
+// newest release that isn't the current one.
+func chooseRollbackRelease(app, env, sha string) (string, *Release, error) {
+	zr, err := datamodel.GetReleases(app, env)
+	if err != nil || len(zr.Releases) == 0 {
+		return "", nil, errors.New(fmt.Sprintf("No releases of %s in %s have been recorded", app, env))
+	}
+	current := zr.Releases[0].Sha
+	for _, release := range zr.Releases {
+		if sha == "" && release.Sha != current {
+			return current, release, nil
+		} else if sha != "" && release.Sha == sha {
+			if sha == current {
+				return "", nil, errors.New(fmt.Sprintf("%s is already the current release of %s in %s", sha, app,
+					env))
+			}
+			return current, release, nil
+		}
+	}
+	if sha != "" {
+		return "", nil, errors.New(fmt.Sprintf("%s is not a recent release of %s in %s", sha, app, env))
+	}
+	return "", nil, errors.New(fmt.Sprintf("No release of %s in %s before %s", app, env, current))
+}
+
+// brings release back up in env (using its stored manifest, without building) if it is not still deployed, swaps
+// traffic to it and, if teardown is set, tears down the current sha.
+func rollback(auth *ManagerAuthArg, app, env, current string, release *Release, teardown bool,
+	t *Task) ([]*Container, error) {
+	zkApp, err := datamodel.GetApp(app)
+	if err != nil {
+		return nil, err
+	}
+	if release.Manifest == nil {
+		return nil, errors.New(fmt.Sprintf("No manifest was recorded for %s @ %s", app, release.Sha))
+	}
+	deployed := []*Container{}
+	if instances, err := datamodel.ListInstances(app, release.Sha, env); err == nil && len(instances) > 0 {
+		t.Log("%s @ %s is still deployed in %s", app, release.Sha, env)
+	} else {
+		t.LogStatus("Redeploying %s @ %s in %s", app, release.Sha, env)
+		// keep the layout of the release being rolled back from
+		zones := getInstanceLayout(app, current, env)
+		placement, err := appPlacement(app, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if deployed, _, err = deploy(auth, release.Manifest.Dup(), release.Sha, env, zones, nil,
+			placement, t); err != nil {
+			return nil, err
+		}
+	}
+	t.LogStatus("Swapping Router to %s", release.Sha)
+	oldRules, err := datamodel.SwapAppEnvTrie(zkApp.Internal, app, release.Sha, env)
+	if err != nil {
+		if len(deployed) > 0 {
+			// nothing routes to the redeployed containers, so they would only take up room
+			rollbackDeploy(zkApp.Internal, app, release.Sha, env, oldRules, deployed, t)
+		} else {
+			restoreAppEnvTrie(zkApp.Internal, app, env, oldRules, t)
+		}
+		return nil, errors.New("Swap Router Error: " + err.Error())
+	}
+	if teardown {
+		t.LogStatus("Tearing Down %s @ %s in %s", app, current, env)
+		if _, err := teardownShaEnv(t, app, current, env); err != nil {
+			return deployed, errors.New(fmt.Sprintf("Error Tearing Down %s: %s", current, err.Error()))
+		}
+	}
+	return deployed, nil
+}
+
+type RollbackError string
+
+func (e RollbackError) Error() string {
+	return "Rolled Back: " + string(e)
+}
+
+// keeps checking the health of the deployed containers for watch. if at any point more than maxFailPct of them are
+// unhealthy, the app+env trie is put back to oldRules, the deployed containers are torn down and a RollbackError is
+// returned.
+func watchDeploy(internal bool, app, sha, env string, oldRules []string, deployed []*Container,
+	watch time.Duration, maxFailPct uint, t *Task) error {
+	if watch <= 0 || len(deployed) == 0 {
+		return nil
+	}
+	t.LogStatus("Watching %d containers for %s", len(deployed), watch.String())
+	deadline := time.Now().Add(watch)
+	for time.Now().Before(deadline) {
+		if err := checkCancelled(t); err != nil {
+			rollbackDeploy(internal, app, sha, env, oldRules, deployed, t)
+			return err
+		}
+		_, unhealthy := waitForHealthy(deployed, 0)
+		if uint(len(unhealthy))*100 > maxFailPct*uint(len(deployed)) {
+			reason := fmt.Sprintf("%d of %d containers of %s @ %s in %s are unhealthy", len(unhealthy),
+				len(deployed), app, sha, env)
+			t.Log(reason)
+			rollbackDeploy(internal, app, sha, env, oldRules, deployed, t)
+			return RollbackError(reason)
+		}
+		time.Sleep(HealthzInterval)
+	}
+	t.Log("%s @ %s in %s stayed healthy for %s", app, sha, env, watch.String())
+	return nil
+}
+
+func rollbackDeploy(internal bool, app, sha, env string, oldRules []string, deployed []*Container, t *Task) {
+	t.LogStatus("Rolling Back %s @ %s in %s", app, sha, env)
+	restoreAppEnvTrie(internal, app, env, oldRules, t)
+	tl := datamodel.NewTeardownLock(t.ID, app, sha, env)
+	if err := tl.Lock(); err != nil {
+		t.Log("Error locking teardown of %s @ %s in %s: %s", app, sha, env, err.Error())
+		return
+	}
+	defer tl.Unlock()
+	hostMap := map[string][]string{}
+	for _, cont := range deployed {
+		hostMap[cont.Host] = append(hostMap[cont.Host], cont.ID)
+	}
+	if _, err := teardownContainers(t, hostMap, false); err != nil {
+		t.Log("Error tearing down %s @ %s in %s: %s", app, sha, env, err.Error())
+	}
+}
+
+func restoreAppEnvTrie(internal bool, app, env string, rules []string, t *Task) {
+	if rules == nil {
+		return
+	}
+	t.LogStatus("Restoring Router")
+	if err := datamodel.RestoreAppEnvTrie(internal, app, env, rules); err != nil {
+		t.Log("Error restoring trie for %s in %s: %s", app, env, err.Error())
+	}
+}
+
+// returns the shas of app other than sha that have instances in env
+func listOtherShasInEnv(app, sha, env string) ([]string, error) {
+	otherShas := []string{}
+	shas, err := datamodel.ListShas(app)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error listing shas of %s: %s", app, err.Error()))
+	}
+	for _, otherSha := range shas {
+		if otherSha == sha {
+			continue
+		}
+		containerIDs, err := datamodel.ListInstances(app, otherSha, env)
+		if err == nil && len(containerIDs) > 0 {
+			otherShas = append(otherShas, otherSha)
+		}
+	}
+	return otherShas, nil
 }
 
 func copyContainer(auth *ManagerAuthArg, cid, toHost string, t *Task) (*Container, error) {
+	inst, manifest, deps, zone, err := prepareCopyContainer(auth, cid, toHost, t)
+	if err != nil {
+		return nil, err
+	}
+	deployed, _, err := deployToHostsInZones(deps, manifest, inst.Sha, inst.Env,
+		map[string][]string{zone: []string{toHost}}, map[string]uint{zone: 1}, nil, t)
+	if err != nil {
+		return nil, err
+	}
+	// should only deploy 1 since we're only moving 1
+	if len(deployed) != 1 {
+		cleanup(true, deployed, t)
+		return nil, errors.New(fmt.Sprintf("Didn't deploy 1 container. Deployed %d", len(deployed)))
+	}
+	// the copy belongs to the same layout as the original
+	if copied, err := datamodel.GetInstance(deployed[0].ID); err == nil && inst.Zones != nil {
+		copied.SetZones(inst.Zones)
+	}
+	return deployed[0], nil
+}
+
+// returns where copyContainer would put the copy without deploying anything
+func planCopyContainer(auth *ManagerAuthArg, cid, toHost string, t *Task) (map[string][]*PlannedHost, error) {
+	inst, manifest, _, zone, err := prepareCopyContainer(auth, cid, toHost, t)
+	if err != nil {
+		return nil, err
+	}
+	placement, err := appPlacement(manifest.Name, "", "")
+	if err != nil {
+		return nil, err
+	}
+	t.LogStatus("Checking Supervisor")
+	list, ruledOut, unreachable, err := datamodel.ChooseSupervisorsList(manifest.Name, inst.Sha, inst.Env,
+		manifest.CPUShares, manifest.MemoryLimit, []string{zone}, map[string]bool{}, placement)
+	if err != nil {
+		return nil, errors.New("Choose Supervisors Error: " + err.Error())
+	}
+	logUnreachable(t, unreachable)
+	for _, elem := range list {
+		if elem.Supervisor == toHost {
+			return planPlacement(list, map[string][]string{zone: []string{toHost}}, map[string]uint{zone: 1}), nil
+		}
+	}
+	for _, reason := range ruledOut[zone] {
+		if strings.HasPrefix(reason, toHost+": ") {
+			return nil, errors.New(fmt.Sprintf("%s is ruled out for a copy of %s by an affinity rule: %s", toHost,
+				cid, strings.TrimPrefix(reason, toHost+": ")))
+		}
+	}
+	if why, ok := unreachable[toHost]; ok {
+		return nil, errors.New(fmt.Sprintf("%s can't take a copy of %s: %s", toHost, cid, why))
+	}
+	return nil, errors.New(fmt.Sprintf("%s does not have room for a copy of %s", toHost, cid))
+}
+
+// everything copyContainer needs before it can deploy: the instance being copied, its manifest, the resolved deps
+// and the zone of toHost.
+func prepareCopyContainer(auth *ManagerAuthArg, cid, toHost string, t *Task) (*datamodel.ZkInstance, *Manifest,
+	map[string]DepsType, string, error) {
 	// get old instance
 	inst, err := datamodel.GetInstance(cid)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, "", err
 	}
 
 	// get manifest
@@ -400,7 +1315,7 @@ func copyContainer(auth *ManagerAuthArg, cid, toHost string, t *Task) (*Containe
 		// if we don't have the manifest in zk, try to get it from the supervisor
 		ihReply, err := supervisor.Get(inst.Host, inst.ID)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, "", err
 		}
 		manifest = ihReply.Container.Manifest
 	}
@@ -409,32 +1324,71 @@ func copyContainer(auth *ManagerAuthArg, cid, toHost string, t *Task) (*Containe
 	// validate and get deps
 	deps, err := validateDeploy(auth, manifest, inst.Sha, inst.Env, t)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, "", err
 	}
 
 	// get zone of toHost
-	zone, err := supervisor.GetZone(toHost)
+	zone, err := supervisorZone(toHost)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, "", err
 	}
+	return inst, manifest, deps, zone, nil
+}
 
-	deployed, err := deployToHostsInZones(deps, manifest, inst.Sha, inst.Env,
-		map[string][]string{zone: []string{toHost}}, []string{zone}, t)
-	if err != nil {
-		return nil, err
+// polls /healthz on every container in parallel until it reports OK or timeout passes. returns the containers that
+// became healthy and the ones that did not.
+func waitForHealthy(containers []*Container, timeout time.Duration) (healthy, unhealthy []*Container) {
+	type healthzResult struct {
+		container *Container
+		healthy   bool
 	}
-	// should only deploy 1 since we're only moving 1
-	if len(deployed) != 1 {
-		cleanup(true, deployed, t)
-		return nil, errors.New(fmt.Sprintf("Didn't deploy 1 container. Deployed %d", len(deployed)))
+	respCh := make(chan healthzResult, len(containers))
+	for _, cont := range containers {
+		go func(cont *Container) {
+			respCh <- healthzResult{cont, pollHealthz(cont.Host, cont.PrimaryPort, timeout)}
+		}(cont)
+	}
+	healthy = []*Container{}
+	unhealthy = []*Container{}
+	for _ = range containers {
+		result := <-respCh
+		if result.healthy {
+			healthy = append(healthy, result.container)
+		} else {
+			unhealthy = append(unhealthy, result.container)
+		}
+	}
+	return healthy, unhealthy
+}
+
+func pollHealthz(host string, port uint16, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if getHealthzStatus(host, port) == StatusOk {
+			return true
+		}
+		if time.Now().Add(HealthzInterval).After(deadline) {
+			return false
+		}
+		time.Sleep(HealthzInterval)
 	}
-	return deployed[0], nil
+}
+
+// same check as the /healthz api endpoint: the container reports its status in the Server-Status header
+func getHealthzStatus(host string, port uint16) string {
+	client := &http.Client{Timeout: HealthzInterval}
+	resp, err := client.Get(fmt.Sprintf("http://%s:%d/healthz", host, port))
+	if err != nil {
+		return StatusUnknown
+	}
+	defer resp.Body.Close()
+	return resp.Header.Get("Server-Status")
 }
 
 func cleanup(removeContainerFromHost bool, deployedContainers []*Container, t *Task) {
 	// kill all references to deployed containers as well as the container itself
 	for _, container := range deployedContainers {
-		supervisor.Teardown(container.Host, []string{container.ID}, false)
+		supervisorTeardown(container.Host, []string{container.ID}, false)
 		if instance, err := datamodel.GetInstance(container.ID); err == nil {
 			instance.Delete()
 		} else {
@@ -457,6 +1411,53 @@ func cleanupZk(inst *datamodel.ZkInstance, t *Task) {
 // Teardown Stuff
 //
 
+func teardownContainers(t *Task, hostMap map[string][]string, all bool) ([]string, error) {
+	tornContainers := []string{}
+	for host, containerIDs := range hostMap {
+		if all {
+			t.LogStatus("Tearing Down * from %s", host)
+		} else {
+			t.LogStatus("Tearing Down %v from %s", containerIDs, host)
+		}
+
+		ihReply, err := supervisorTeardown(host, containerIDs, all)
+		if err != nil {
+			return tornContainers, errors.New(fmt.Sprintf("Error Tearing Down %v from %s : %s", containerIDs,
+				host, err.Error()))
+		}
+		tornContainers = append(tornContainers, ihReply.ContainerIDs...)
+		for _, tornContainerID := range tornContainers {
+			err := datamodel.DeleteFromPool([]string{tornContainerID})
+			if err != nil {
+				t.Log("Error removing %s from pool: %v", tornContainerID, err)
+			}
+			datamodel.Supervisor(host).RemoveContainer(tornContainerID)
+			instance, err := datamodel.GetInstance(tornContainerID)
+			if err != nil {
+				continue
+			}
+			last, _ := instance.Delete()
+			if last {
+				DeleteAppShaFromEnv(instance.App, instance.Sha, instance.Env)
+			}
+		}
+	}
+	return tornContainers, nil
+}
+
+func teardownShaEnv(t *Task, app, sha, env string) ([]string, error) {
+	tl := datamodel.NewTeardownLock(t.ID, app, sha, env)
+	if err := tl.Lock(); err != nil {
+		return nil, err
+	}
+	defer tl.Unlock()
+	hostMap, err := getContainerIDsToTeardown(t, ManagerTeardownArg{App: app, Sha: sha, Env: env})
+	if err != nil {
+		return nil, err
+	}
+	return teardownContainers(t, hostMap, false)
+}
+
 func getContainerIDsOfShaEnv(t *Task, app, sha, env string) ([]string, error) {
 	containerIDs, err := datamodel.ListInstances(app, sha, env)
 	if err != nil {
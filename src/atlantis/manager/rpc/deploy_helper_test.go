@@ -20,9 +20,19 @@ import (
 	"atlantis/manager/helper"
 	. "atlantis/manager/rpc/types"
 	scrypto "atlantis/supervisor/crypto"
+	. "atlantis/supervisor/rpc/types"
+	"errors"
 	"fmt"
 	zookeeper "github.com/jigish/gozk-recipes"
 	. "launchpad.net/gocheck"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type DeployHelperSuite struct{}
@@ -108,3 +118,305 @@ func (s *DeployHelperSuite) TestResolveDepValues(c *C) {
 	c.Assert(deps["dev1"]["hello-go"].DataMap, Not(IsNil))
 	c.Assert(deps["dev1"]["hello-go"].DataMap["address"], Equals, fmt.Sprintf("internal-router.1.%s.suffix.com:%d", Region, datamodel.MinRouterPort))
 }
+
+func healthzTestContainer(c *C, id, status string) (*Container, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server-Status", status)
+	}))
+	u, err := url.Parse(server.URL)
+	c.Assert(err, IsNil)
+	host, portStr, err := net.SplitHostPort(u.Host)
+	c.Assert(err, IsNil)
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	c.Assert(err, IsNil)
+	return &Container{ID: id, Host: host, PrimaryPort: uint16(port)}, server
+}
+
+func (s *DeployHelperSuite) TestWaitForHealthy(c *C) {
+	HealthzInterval = 10 * time.Millisecond
+	okCont, okServer := healthzTestContainer(c, "ok", StatusOk)
+	defer okServer.Close()
+	maintCont, maintServer := healthzTestContainer(c, "maint", StatusMaintenance)
+	defer maintServer.Close()
+	goneCont, goneServer := healthzTestContainer(c, "gone", StatusOk)
+	goneServer.Close()
+
+	c.Assert(getHealthzStatus(okCont.Host, okCont.PrimaryPort), Equals, StatusOk)
+	c.Assert(getHealthzStatus(maintCont.Host, maintCont.PrimaryPort), Equals, StatusMaintenance)
+	c.Assert(getHealthzStatus(goneCont.Host, goneCont.PrimaryPort), Equals, StatusUnknown)
+
+	healthy, unhealthy := waitForHealthy([]*Container{okCont, maintCont, goneCont}, 50*time.Millisecond)
+	c.Assert(healthy, DeepEquals, []*Container{okCont})
+	c.Assert(len(unhealthy), Equals, 2)
+}
+
+func (s *DeployHelperSuite) TestWatchDeployHealthy(c *C) {
+	HealthzInterval = 10 * time.Millisecond
+	okCont, okServer := healthzTestContainer(c, "ok", StatusOk)
+	defer okServer.Close()
+	c.Assert(watchDeploy(true, "app", "sha", "env", nil, []*Container{okCont}, 0, 0, &Task{}), IsNil)
+	c.Assert(watchDeploy(true, "app", "sha", "env", nil, []*Container{okCont}, 50*time.Millisecond, 0, &Task{}),
+		IsNil)
+	c.Assert(RollbackError("reason").Error(), Equals, "Rolled Back: reason")
+}
+
+func (s *DeployHelperSuite) TestWatchDeployRollback(c *C) {
+	HealthzInterval = 10 * time.Millisecond
+	datamodel.CreateLockPaths()
+	torn := map[string][]string{}
+	teardown := supervisorTeardown
+	supervisorTeardown = func(host string, ids []string, all bool) (*SupervisorTeardownReply, error) {
+		torn[host] = append(torn[host], ids...)
+		return &SupervisorTeardownReply{ContainerIDs: ids}, nil
+	}
+	defer func() { supervisorTeardown = teardown }()
+	okCont, okServer := healthzTestContainer(c, "ok", StatusOk)
+	defer okServer.Close()
+	goneCont, goneServer := healthzTestContainer(c, "gone", StatusOk)
+	goneServer.Close()
+	deployed := []*Container{okCont, goneCont}
+	// one of two failing is within 50%
+	t := &Task{ID: "watch-test"}
+	c.Assert(watchDeploy(true, "app", "sha", "env", nil, deployed, 50*time.Millisecond, 50, t), IsNil)
+	c.Assert(torn, DeepEquals, map[string][]string{})
+	// but not within 0%, so the new containers are torn down
+	err := watchDeploy(true, "app", "sha", "env", nil, deployed, 50*time.Millisecond, 0, t)
+	c.Assert(err, FitsTypeOf, RollbackError(""))
+	c.Assert(err, ErrorMatches, "Rolled Back: 1 of 2 containers of app @ sha in env are unhealthy")
+	c.Assert(torn, DeepEquals, map[string][]string{okCont.Host: []string{"ok", "gone"}})
+}
+
+func (s *DeployHelperSuite) TestDeployZones(c *C) {
+	zones, err := deployZones(nil, 2)
+	c.Assert(err, IsNil)
+	c.Assert(zones, DeepEquals, map[string]uint{"dev1": 2})
+	_, err = deployZones(map[string]uint{"dev1": 0}, 2)
+	c.Assert(err, Not(IsNil))
+	_, err = deployZones(map[string]uint{"nowhere": 1}, 2)
+	c.Assert(err, Not(IsNil))
+	// scale can take a zone down to 0
+	c.Assert(checkZonesAvailable(map[string]uint{"dev1": 0}), IsNil)
+	c.Assert(checkZonesAvailable(map[string]uint{"nowhere": 0}), Not(IsNil))
+}
+
+func (s *DeployHelperSuite) TestPlanPlacement(c *C) {
+	list := datamodel.SupervisorAndWeightList{
+		datamodel.SupervisorAndWeight{Supervisor: "a1", Zone: "a", Free: 3, Weight: 0.5},
+		datamodel.SupervisorAndWeight{Supervisor: "b1", Zone: "b", Free: 1, Weight: 0.7},
+		datamodel.SupervisorAndWeight{Supervisor: "a2", Zone: "a", Free: 2, Weight: 0.9},
+	}
+	hosts, err := datamodel.SupervisorsByZone("app", map[string]uint{"a": 3}, list, nil)
+	c.Assert(err, IsNil)
+	plan := planPlacement(list, hosts, map[string]uint{"a": 3})
+	c.Assert(plan, DeepEquals, map[string][]*PlannedHost{
+		"a": []*PlannedHost{
+			&PlannedHost{Host: "a1", Free: 3, Weight: 0.5, Instances: 2},
+			&PlannedHost{Host: "a2", Free: 2, Weight: 0.9, Instances: 1},
+		},
+	})
+	hosts, err = datamodel.SupervisorsByZone("app", map[string]uint{"a": 4, "b": 1}, list, nil)
+	c.Assert(err, IsNil)
+	plan = planPlacement(list, hosts, map[string]uint{"a": 4, "b": 1})
+	c.Assert(plan["a"][0].Instances, Equals, uint(2))
+	c.Assert(plan["a"][1].Instances, Equals, uint(2))
+	c.Assert(plan["b"][0].Instances, Equals, uint(1))
+	_, err = datamodel.SupervisorsByZone("app", map[string]uint{"a": 2, "b": 2}, list, nil)
+	c.Assert(err, Not(IsNil))
+	_, err = datamodel.SupervisorsByZone("app", map[string]uint{"c": 1}, list, nil)
+	c.Assert(err, Not(IsNil))
+	// the supervisors affinity rules ruled out are explained
+	ruledOut := map[string][]string{"c": []string{"c1: it already has 1 of app (anti-affinity)"}}
+	_, err = datamodel.SupervisorsByZone("app", map[string]uint{"c": 1}, list, ruledOut)
+	c.Assert(err, ErrorMatches, "No host for app app available in zone c. Ruled out by affinity rules: "+
+		"c1: it already has 1 of app \\(anti-affinity\\)")
+	ruledOut = map[string][]string{"b": []string{"b2: it has none of db (affinity)"}}
+	_, err = datamodel.SupervisorsByZone("app", map[string]uint{"a": 2, "b": 2}, list, ruledOut)
+	c.Assert(err, ErrorMatches, "Not enough instances .* zone b .*: b2: it has none of db \\(affinity\\)")
+}
+
+// stubs out the supervisors deployToZone talks to. hosts in unhealthy fail their health check and hosts in broken
+// fail every deploy. returns the hosts each container was deployed to, in order.
+func stubSupervisors(unhealthy, broken map[string]bool) (*[]string, func()) {
+	deployedTo := &[]string{}
+	lock := sync.Mutex{}
+	healthCheck, deploy, teardown := supervisorHealthCheck, supervisorDeployWithTimeout, supervisorTeardown
+	supervisorHealthCheck = func(host string) (*SupervisorHealthCheckReply, error) {
+		if unhealthy[host] {
+			return nil, errors.New("unreachable")
+		}
+		return &SupervisorHealthCheckReply{Status: StatusOk, Zone: "dev1"}, nil
+	}
+	supervisorDeployWithTimeout = func(host, app, sha, env, container string, man *Manifest,
+		timeout int) (*SupervisorDeployReply, error) {
+		if broken[host] {
+			return nil, errors.New("timed out")
+		}
+		lock.Lock()
+		*deployedTo = append(*deployedTo, host)
+		lock.Unlock()
+		return &SupervisorDeployReply{Status: StatusOk, Container: &Container{ID: container, App: app, Sha: sha,
+			Env: env, Manifest: man}}, nil
+	}
+	supervisorTeardown = func(host string, ids []string, all bool) (*SupervisorTeardownReply, error) {
+		return &SupervisorTeardownReply{ContainerIDs: ids}, nil
+	}
+	return deployedTo, func() {
+		supervisorHealthCheck, supervisorDeployWithTimeout, supervisorTeardown = healthCheck, deploy, teardown
+	}
+}
+
+func (s *DeployHelperSuite) TestDeployToZone(c *C) {
+	timeout, retries, backoff := DeployHostTimeout, DeployHostRetries, DeployRetryBackoff
+	defer func() { DeployHostTimeout, DeployHostRetries, DeployRetryBackoff = timeout, retries, backoff }()
+	DeployHostTimeout = time.Second
+	DeployHostRetries = 1
+	DeployRetryBackoff = time.Millisecond
+	datamodel.CreateInstancePaths()
+	hosts := []string{"h1", "h2", "h3"}
+	manifest := &Manifest{Name: "zone-app", Instances: 3}
+	// h2 is skipped because it is down and h3 after its deploy failed, so h1 gets its containers
+	deployedTo, restore := stubSupervisors(map[string]bool{"h2": true}, map[string]bool{"h3": true})
+	respCh := make(chan *DeployZoneResult, 1)
+	deployToZone(respCh, map[string]DepsType{}, manifest, 3, "sha", "env", hosts, "dev1", false)
+	result := <-respCh
+	restore()
+	c.Assert(result.Error, IsNil)
+	c.Assert(len(result.Containers), Equals, 3)
+	c.Assert(*deployedTo, DeepEquals, []string{"h1", "h1", "h1"})
+	c.Assert(result.Skipped["h2"], Equals, "Health Check Error: unreachable")
+	c.Assert(result.Skipped["h3"], Equals, "Deploy Error: timed out")
+	// once every host has been skipped the zone fails with what was deployed so far
+	deployedTo, restore = stubSupervisors(map[string]bool{}, map[string]bool{"h1": true, "h2": true, "h3": true})
+	deployToZone(respCh, map[string]DepsType{}, manifest, 3, "sha", "env", hosts, "dev1", false)
+	result = <-respCh
+	restore()
+	c.Assert(result.Error, ErrorMatches, "Failed to deploy 3 instances in zone dev1. Deployed 0 after 0 retries.")
+	c.Assert(len(result.Containers), Equals, 0)
+	c.Assert(len(result.Skipped), Equals, 3)
+	// with one container per host the one that failed on h3 isn't retried on the hosts that already got one
+	deployedTo, restore = stubSupervisors(map[string]bool{}, map[string]bool{"h3": true})
+	deployToZone(respCh, map[string]DepsType{}, manifest, 3, "sha", "env", hosts, "dev1", true)
+	result = <-respCh
+	restore()
+	c.Assert(result.Error, ErrorMatches, "Failed to deploy 3 instances in zone dev1. Deployed 2 after 0 retries.")
+	c.Assert(len(result.Containers), Equals, 2)
+	c.Assert(len(*deployedTo), Equals, 2)
+	c.Assert((*deployedTo)[0], Not(Equals), (*deployedTo)[1])
+	c.Assert(result.Skipped["h3"], Equals, "Deploy Error: timed out")
+}
+
+func (s *DeployHelperSuite) TestZonePolicy(c *C) {
+	statuses := map[string]*ZoneStatus{
+		"a": &ZoneStatus{Status: StatusOk},
+		"b": &ZoneStatus{Status: StatusOk},
+		"c": &ZoneStatus{Status: StatusError, Error: "no room"},
+	}
+	var policy *zonePolicy
+	c.Assert(policy.check(statuses), ErrorMatches, "no room")
+	c.Assert((&zonePolicy{}).check(statuses), Not(IsNil))
+	c.Assert((&zonePolicy{minZones: 2}).check(statuses), IsNil)
+	c.Assert((&zonePolicy{minZones: 3}).check(statuses), Not(IsNil))
+	c.Assert((&zonePolicy{minZones: 1, required: []string{"a"}}).check(statuses), IsNil)
+	c.Assert((&zonePolicy{minZones: 1, required: []string{"c"}}).check(statuses), ErrorMatches,
+		"Required zone c failed: no room")
+	// without a minimum only the required zones have to succeed
+	c.Assert((&zonePolicy{required: []string{"a"}}).check(statuses), IsNil)
+	c.Assert((&zonePolicy{required: []string{"c"}}).check(statuses), ErrorMatches, "Required zone c failed: no room")
+	statuses["a"].Status = StatusError
+	statuses["b"].Status = StatusError
+	c.Assert((&zonePolicy{minZones: 1}).check(statuses), Not(IsNil))
+}
+
+func (s *DeployHelperSuite) TestChooseContainersToRemove(c *C) {
+	insts := []*datamodel.ZkInstance{
+		&datamodel.ZkInstance{ID: "h1-1", Host: "h1"},
+		&datamodel.ZkInstance{ID: "h1-2", Host: "h1"},
+		&datamodel.ZkInstance{ID: "h1-3", Host: "h1"},
+		&datamodel.ZkInstance{ID: "h2-1", Host: "h2"},
+		&datamodel.ZkInstance{ID: "h3-1", Host: "h3"},
+		&datamodel.ZkInstance{ID: "h3-2", Host: "h3"},
+	}
+	load := map[string]float64{"h1": 6.5, "h2": 2.3, "h3": math.MaxFloat64}
+	ids := func(chosen []*datamodel.ZkInstance) []string {
+		chosenIDs := []string{}
+		for _, inst := range chosen {
+			chosenIDs = append(chosenIDs, inst.ID)
+		}
+		return chosenIDs
+	}
+	// unhealthy supervisors go first, then the most loaded ones
+	c.Assert(ids(chooseContainersToRemove(insts, 4, load)), DeepEquals, []string{"h3-1", "h3-2", "h1-1", "h1-2"})
+	// ties go to the first supervisor by name
+	c.Assert(ids(chooseContainersToRemove(insts[3:5], 1, map[string]float64{})), DeepEquals, []string{"h2-1"})
+	c.Assert(ids(chooseContainersToRemove(insts[:2], 5, load)), DeepEquals, []string{"h1-1", "h1-2"})
+}
+
+func (s *DeployHelperSuite) TestChooseRollbackRelease(c *C) {
+	datamodel.Zk.RecursiveDelete(helper.GetBaseReleasePath())
+	datamodel.CreateReleasePath()
+	_, _, err := chooseRollbackRelease("app", "env", "")
+	c.Assert(err, Not(IsNil))
+	for _, sha := range []string{"sha1", "sha2", "sha3"} {
+		c.Assert(datamodel.AddRelease("app", "env", &Release{Sha: sha, Manifest: &Manifest{Name: "app"}}), IsNil)
+	}
+	current, release, err := chooseRollbackRelease("app", "env", "")
+	c.Assert(err, IsNil)
+	c.Assert(current, Equals, "sha3")
+	c.Assert(release.Sha, Equals, "sha2")
+	current, release, err = chooseRollbackRelease("app", "env", "sha1")
+	c.Assert(err, IsNil)
+	c.Assert(current, Equals, "sha3")
+	c.Assert(release.Sha, Equals, "sha1")
+	_, _, err = chooseRollbackRelease("app", "env", "sha3")
+	c.Assert(err, Not(IsNil))
+	_, _, err = chooseRollbackRelease("app", "env", "nosuchsha")
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *DeployHelperSuite) TestChooseRelease(c *C) {
+	datamodel.Zk.RecursiveDelete(helper.GetBaseReleasePath())
+	datamodel.CreateReleasePath()
+	now := time.Now()
+	_, err := chooseRelease("app", "staging", nil, now)
+	c.Assert(err, Not(IsNil))
+	c.Assert(datamodel.AddRelease("app", "staging", &Release{Sha: "sha1", Time: now.Add(-2 * time.Hour).Unix(),
+		Manifest: &Manifest{Name: "app"}}), IsNil)
+	c.Assert(datamodel.AddRelease("app", "staging", &Release{Sha: "sha2", Time: now.Add(-30 * time.Minute).Unix(),
+		Manifest: &Manifest{Name: "app"}}), IsNil)
+	// without a rule the current release can always be promoted
+	release, err := chooseRelease("app", "staging", nil, now)
+	c.Assert(err, IsNil)
+	c.Assert(release.Sha, Equals, "sha2")
+	// the current release has to have served the env of the rule long enough
+	rule := &PromotionRule{From: "staging", MinTime: 3600}
+	_, err = chooseRelease("app", "staging", rule, now)
+	c.Assert(err, ErrorMatches, "app @ sha2 has only served staging for 30m0s. It has to for 1h0m0s before it can "+
+		"be promoted")
+	release, err = chooseRelease("app", "staging", rule, now.Add(time.Hour))
+	c.Assert(err, IsNil)
+	c.Assert(release.Sha, Equals, "sha2")
+	_, err = chooseRelease("app", "staging", &PromotionRule{From: "qa"}, now)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *DeployHelperSuite) TestCheckPromotionRule(c *C) {
+	datamodel.Zk.RecursiveDelete(helper.GetBaseReleasePath())
+	datamodel.CreateReleasePath()
+	zkApp, err := datamodel.CreateOrUpdateApp(false, true, "promoted", "ssh://github.com/ooyala/promoted", "/",
+		"jigish@ooyala.com")
+	c.Assert(err, IsNil)
+	now := time.Now()
+	// without a rule anything can be deployed
+	c.Assert(checkPromotionRule(zkApp, "sha1", "prod", now), IsNil)
+	c.Assert(zkApp.SetPromotionRule("prod", &PromotionRule{From: "staging", MinTime: 3600}), IsNil)
+	c.Assert(checkPromotionRule(zkApp, "sha1", "prod", now), Not(IsNil))
+	c.Assert(datamodel.AddRelease("promoted", "staging", &Release{Sha: "sha1", Time: now.Add(-2 * time.Hour).Unix(),
+		Manifest: &Manifest{Name: "promoted"}}), IsNil)
+	c.Assert(checkPromotionRule(zkApp, "sha1", "prod", now), IsNil)
+	c.Assert(checkPromotionRule(zkApp, "sha2", "prod", now), ErrorMatches,
+		"prod can only get promoted from staging, which is serving sha1")
+	// the current release of prod can always be redeployed
+	c.Assert(datamodel.AddRelease("promoted", "prod", &Release{Sha: "sha0", Time: now.Unix(),
+		Manifest: &Manifest{Name: "promoted"}}), IsNil)
+	c.Assert(checkPromotionRule(zkApp, "sha0", "prod", now), IsNil)
+}
@@ -14,10 +14,12 @@ package rpc
 import (
 	. "atlantis/common"
 	"atlantis/manager/datamodel"
+	"atlantis/manager/helper"
 	. "atlantis/manager/rpc/types"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
 var (
@@ -125,7 +127,11 @@ func (e *UpdateEnvExecutor) Execute(t *Task) error {
 	if IsEnvInUse(e.arg.Name) {
 		return errors.New(fmt.Sprintf("%s is in use and cannot be updated", e.arg.Name))
 	}
-	env := datamodel.Env(e.arg.Name)
+	env, err := datamodel.GetEnv(e.arg.Name)
+	if err != nil {
+		env = datamodel.Env(e.arg.Name)
+	}
+	// saving an existing env keeps its freezes
 	if err := env.Save(); err != nil {
 		return err
 	}
@@ -183,3 +189,226 @@ func (m *ManagerRPC) UpdateEnv(arg ManagerEnvArg, reply *ManagerEnvReply) error
 func (m *ManagerRPC) DeleteEnv(arg ManagerEnvArg, reply *ManagerEnvReply) error {
 	return NewTask("DeleteEnv", &DeleteEnvExecutor{arg, reply}).Run()
 }
+
+// true if deploys to env have to be approved by a team admin of the app
+func envProtected(env string) bool {
+	zkEnv, err := datamodel.GetEnv(env)
+	return err == nil && zkEnv.Protected
+}
+
+// rejects changes to the containers of a protected env that don't go through an approved deploy
+func checkNotProtected(env string) error {
+	if envProtected(env) {
+		return errors.New(fmt.Sprintf("%s is protected. Please use Deploy so that the change can be approved", env))
+	}
+	return nil
+}
+
+type ProtectEnvExecutor struct {
+	arg   ManagerProtectEnvArg
+	reply *ManagerEnvReply
+}
+
+func (e *ProtectEnvExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ProtectEnvExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ProtectEnvExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s (protected: %t)", e.arg.Env, e.arg.Protected)
+}
+
+func (e *ProtectEnvExecutor) Execute(t *Task) error {
+	env, err := datamodel.GetEnv(e.arg.Env)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Environment %s does not exist", e.arg.Env))
+	}
+	env.Protected = e.arg.Protected
+	if err := env.Save(); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (e *ProtectEnvExecutor) Authorize() error {
+	return AuthorizeSuperUser(&e.arg.ManagerAuthArg)
+}
+
+func (m *ManagerRPC) ProtectEnv(arg ManagerProtectEnvArg, reply *ManagerEnvReply) error {
+	return NewTask("ProtectEnv", &ProtectEnvExecutor{arg, reply}).Run()
+}
+
+// ----------------------------------------------------------------------------------------------------------
+// Freezes
+// ----------------------------------------------------------------------------------------------------------
+
+// the first of env's freezes in effect at now, or nil if env isn't frozen
+func activeFreeze(env string, now time.Time) *Freeze {
+	zkEnv, err := datamodel.GetEnv(env)
+	if err != nil {
+		return nil // an env without data has no freezes
+	}
+	for _, freeze := range zkEnv.Freezes {
+		if helper.FreezeActive(freeze, now) {
+			return freeze
+		}
+	}
+	return nil
+}
+
+// rejects work in env while it is frozen unless a superuser overrides the freeze with a reason
+func checkFreeze(t *Task, auth *ManagerAuthArg, env, override string) error {
+	freeze := activeFreeze(env, time.Now())
+	if freeze == nil {
+		return nil
+	}
+	if override == "" {
+		return errors.New(fmt.Sprintf("%s is frozen (%s: %s). A superuser can override the freeze with a reason.",
+			env, freeze.Name, freeze.Reason))
+	}
+	// the overrides of scheduled deploys were checked when they were scheduled
+	if !scheduledTask(t.ID) {
+		if err := AuthorizeSuperUser(auth); err != nil {
+			return errors.New(fmt.Sprintf("%s is frozen (%s: %s) and only a superuser can override it", env,
+				freeze.Name, freeze.Reason))
+		}
+	}
+	t.Log("[%s] overriding the %s freeze of %s: %s", auth.User, freeze.Name, env, override)
+	return nil
+}
+
+type AddFreezeExecutor struct {
+	arg   ManagerFreezeArg
+	reply *ManagerFreezeReply
+}
+
+func (e *AddFreezeExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *AddFreezeExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *AddFreezeExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s in %s", e.arg.Freeze.Name, e.arg.Env)
+}
+
+func (e *AddFreezeExecutor) Execute(t *Task) error {
+	freeze := e.arg.Freeze
+	freeze.User = e.arg.ManagerAuthArg.User
+	if err := helper.CheckFreeze(&freeze); err != nil {
+		return err
+	}
+	env, err := datamodel.GetEnv(e.arg.Env)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Environment %s does not exist", e.arg.Env))
+	}
+	if err := env.AddFreeze(&freeze); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	e.reply.Freezes = env.Freezes
+	return nil
+}
+
+func (e *AddFreezeExecutor) Authorize() error {
+	return AuthorizeSuperUser(&e.arg.ManagerAuthArg)
+}
+
+type DeleteFreezeExecutor struct {
+	arg   ManagerFreezeArg
+	reply *ManagerFreezeReply
+}
+
+func (e *DeleteFreezeExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *DeleteFreezeExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *DeleteFreezeExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s in %s", e.arg.Freeze.Name, e.arg.Env)
+}
+
+func (e *DeleteFreezeExecutor) Execute(t *Task) error {
+	if e.arg.Freeze.Name == "" {
+		return errors.New("Please specify the name of the freeze")
+	}
+	env, err := datamodel.GetEnv(e.arg.Env)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Environment %s does not exist", e.arg.Env))
+	}
+	if err := env.DeleteFreeze(e.arg.Freeze.Name); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	e.reply.Freezes = env.Freezes
+	return nil
+}
+
+func (e *DeleteFreezeExecutor) Authorize() error {
+	return AuthorizeSuperUser(&e.arg.ManagerAuthArg)
+}
+
+type ListFreezesExecutor struct {
+	arg   ManagerFreezeArg
+	reply *ManagerFreezeReply
+}
+
+func (e *ListFreezesExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ListFreezesExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ListFreezesExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s", e.arg.Env)
+}
+
+func (e *ListFreezesExecutor) Execute(t *Task) error {
+	env, err := datamodel.GetEnv(e.arg.Env)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Environment %s does not exist", e.arg.Env))
+	}
+	now := time.Now()
+	e.reply.Freezes = env.Freezes
+	if e.reply.Freezes == nil {
+		e.reply.Freezes = []*Freeze{}
+	}
+	e.reply.Active = []string{}
+	for _, freeze := range e.reply.Freezes {
+		if helper.FreezeActive(freeze, now) {
+			e.reply.Active = append(e.reply.Active, freeze.Name)
+		}
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (e *ListFreezesExecutor) Authorize() error {
+	return SimpleAuthorize(&e.arg.ManagerAuthArg)
+}
+
+func (m *ManagerRPC) AddFreeze(arg ManagerFreezeArg, reply *ManagerFreezeReply) error {
+	return NewTask("AddFreeze", &AddFreezeExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) DeleteFreeze(arg ManagerFreezeArg, reply *ManagerFreezeReply) error {
+	return NewTask("DeleteFreeze", &DeleteFreezeExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) ListFreezes(arg ManagerFreezeArg, reply *ManagerFreezeReply) error {
+	return NewTask("ListFreezes", &ListFreezesExecutor{arg, reply}).Run()
+}
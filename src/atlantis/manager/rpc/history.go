@@ -0,0 +1,169 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	"atlantis/manager/datamodel"
+	. "atlantis/manager/rpc/types"
+	. "atlantis/supervisor/rpc/types"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// records a deploy or teardown of app @ sha in env. the manifest (if known) holds what was actually deployed.
+// failing to record history should never fail the task so errors are only logged.
+func recordDeployHistory(t *Task, action, user, app, sha, env string, manifest *Manifest, zones map[string]uint,
+	err error) {
+	record := &DeployRecord{
+		TaskID: t.ID,
+		Action: action,
+		User:   user,
+		App:    app,
+		Sha:    sha,
+		Env:    env,
+		Time:   time.Now().Unix(),
+		Zones:  zones,
+		Status: StatusOk,
+	}
+	if manifest != nil {
+		record.CPUShares = manifest.CPUShares
+		record.MemoryLimit = manifest.MemoryLimit
+		record.Instances = manifest.Instances
+	}
+	if err != nil {
+		record.Status = StatusError
+		record.Error = err.Error()
+	}
+	if err := datamodel.AddDeployRecord(record); err != nil {
+		t.Log("Error recording %s history for %s @ %s in %s: %s", action, app, sha, env, err.Error())
+	}
+}
+
+// remembers that sha (deployed with manifest) is now serving app+env so that we can roll back to it later
+func recordRelease(t *Task, app, sha, env string, manifest *Manifest) {
+	release := &Release{Sha: sha, Time: time.Now().Unix(), Manifest: manifest.Dup()}
+	if err := datamodel.AddRelease(app, env, release); err != nil {
+		t.Log("Error recording release of %s @ %s in %s: %s", app, sha, env, err.Error())
+	}
+}
+
+// records the history (and, if it succeeded, the release) of a deploy made with arg
+func recordDeploy(t *Task, arg *ManagerDeployArg, manifest *Manifest, err error) {
+	if arg.DryRun {
+		return
+	}
+	recordDeployHistory(t, "Deploy", arg.ManagerAuthArg.User, arg.App, arg.Sha, arg.Env, manifest, nil, err)
+	if err == nil {
+		recordRelease(t, arg.App, arg.Sha, arg.Env, manifest)
+	}
+}
+
+type ListDeployHistoryExecutor struct {
+	arg   ManagerListDeployHistoryArg
+	reply *ManagerListDeployHistoryReply
+}
+
+func (e *ListDeployHistoryExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ListDeployHistoryExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ListDeployHistoryExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s in %s", e.arg.App, e.arg.Env)
+}
+
+func (e *ListDeployHistoryExecutor) Authorize() error {
+	return AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App)
+}
+
+func (e *ListDeployHistoryExecutor) Execute(t *Task) (err error) {
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if e.arg.Env == "" {
+		return errors.New("Please specify an environment")
+	}
+	e.reply.History, err = datamodel.ListDeployHistory(e.arg.App, e.arg.Env, int(e.arg.Limit))
+	if err != nil {
+		e.reply.Status = StatusError
+	} else {
+		e.reply.Status = StatusOk
+	}
+	return err
+}
+
+func (m *ManagerRPC) ListDeployHistory(arg ManagerListDeployHistoryArg, reply *ManagerListDeployHistoryReply) error {
+	return NewTask("ListDeployHistory", &ListDeployHistoryExecutor{arg, reply}).Run()
+}
+
+// the containers the datamodel has on each of the hosts of hostMap, for a teardown that tells them to remove all
+// of theirs
+func supervisorContainerIDs(hostMap map[string][]string) map[string][]string {
+	containers := map[string][]string{}
+	for host := range hostMap {
+		info, err := datamodel.Supervisor(host).Info()
+		if err != nil {
+			continue
+		}
+		for containerID := range info.PortMap {
+			containers[host] = append(containers[host], containerID)
+		}
+	}
+	return containers
+}
+
+// what a teardown of the containers in hostMap will remove, one entry per app+sha+env. the manifest's Instances is
+// the number of containers being torn down.
+func teardownRecords(hostMap map[string][]string) map[string]*datamodel.ZkInstance {
+	records := map[string]*datamodel.ZkInstance{}
+	for _, containerIDs := range hostMap {
+		for _, containerID := range containerIDs {
+			instance, err := datamodel.GetInstance(containerID)
+			if err != nil {
+				continue
+			}
+			key := instance.App + "/" + instance.Sha + "/" + instance.Env
+			if record, ok := records[key]; ok {
+				record.Manifest.Instances++
+				continue
+			}
+			manifest := &Manifest{}
+			if instance.Manifest != nil {
+				manifest = instance.Manifest.Dup()
+			}
+			manifest.Instances = 1
+			instance.Manifest = manifest
+			records[key] = instance
+		}
+	}
+	return records
+}
+
+// the envs that the teardown records are in, sorted so that freezes are checked in a stable order
+func teardownEnvs(records map[string]*datamodel.ZkInstance) []string {
+	seen := map[string]bool{}
+	envs := []string{}
+	for _, record := range records {
+		if !seen[record.Env] {
+			seen[record.Env] = true
+			envs = append(envs, record.Env)
+		}
+	}
+	sort.Strings(envs)
+	return envs
+}
@@ -0,0 +1,70 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/manager/constant"
+	"atlantis/manager/datamodel"
+	"atlantis/manager/supervisor"
+	"log"
+	"time"
+)
+
+// a supervisor is marked down once it has missed this many heartbeats in a row
+var MaxMissedHeartbeats = DefaultMaxMissedHeartbeats
+
+// Every manager tries to start a round of heartbeats every interval. ClaimHeartbeatRound makes sure only one of
+// them does, so that a supervisor misses a heartbeat at most once per interval.
+func SupervisorHeartbeats(interval time.Duration) {
+	go func() {
+		for {
+			heartbeatSupervisors(time.Now(), interval)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// health checks every registered supervisor and records what they said (or that they didn't) in their inventory
+func heartbeatSupervisors(now time.Time, interval time.Duration) {
+	claimed, err := datamodel.ClaimHeartbeatRound(Host, now, interval)
+	if err != nil {
+		log.Printf("[Heartbeat] ERROR: could not claim a round of heartbeats: %s", err.Error())
+		return
+	}
+	if !claimed {
+		return // another manager has this round
+	}
+	hosts, err := datamodel.ListSupervisors()
+	if err != nil {
+		log.Printf("[Heartbeat] ERROR: could not list supervisors: %s", err.Error())
+		return
+	}
+	results := supervisor.HealthCheckAll(hosts)
+	for _, host := range hosts {
+		wasDown := false
+		if inventory, err := datamodel.GetSupervisorInventory(host); err == nil {
+			wasDown = inventory.State == SupervisorDown
+		}
+		inventory, err := datamodel.RecordHeartbeat(host, results[host].Reply, results[host].Err, now,
+			MaxMissedHeartbeats)
+		if err != nil {
+			log.Printf("[Heartbeat] ERROR: could not record the heartbeat of %s: %s", host, err.Error())
+			continue
+		}
+		if inventory.State == SupervisorDown && !wasDown {
+			log.Printf("[Heartbeat] %s is down after %d missed heartbeats: %s", host, inventory.MissedHeartbeats,
+				inventory.LastError)
+		} else if inventory.State == SupervisorUp && wasDown {
+			log.Printf("[Heartbeat] %s is up", host)
+		}
+	}
+}
@@ -1039,6 +1039,25 @@ func ListTeamApps(team string, auth *ManagerAuthArg) ([]string, error) {
 	return result, nil
 }
 
+// the teams that app is allowed for, taken from the DNs of the app entries (allowedApp=app,cn=team,ou=...)
+func ListAppTeams(app string, auth *ManagerAuthArg) ([]string, error) {
+	result := []string{}
+	filterStr := "(&(objectClass=" + aldap.AppClass + ")(" + aldap.AllowedAppAttr + "=" + app + "))"
+	ss, err := NewSearchReq(filterStr, []string{aldap.AllowedAppAttr}, auth)
+	if err != nil {
+		return result, err
+	}
+	for _, entry := range ss.Entries {
+		rdns := strings.Split(entry.DN, ",")
+		if len(rdns) < 2 || !strings.HasPrefix(rdns[1], aldap.TeamCommonName+"=") {
+			continue
+		}
+		result = append(result, strings.TrimPrefix(rdns[1], aldap.TeamCommonName+"="))
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
 func UserExists(name string, auth *ManagerAuthArg) bool {
 	filterStr := "(&(objectClass=" + aldap.UserClass + ")(" + aldap.UserClassAttr + "=" + name + "))"
 	sr, err := NewSearchReq(filterStr, []string{aldap.UserClassAttr}, auth)
@@ -0,0 +1,279 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	"atlantis/manager/datamodel"
+	. "atlantis/manager/rpc/types"
+	. "atlantis/supervisor/rpc/types"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------------------------------------
+// Promote
+// ----------------------------------------------------------------------------------------------------------
+
+// the current release of app in env if rule (nil if the target env has none) lets it be promoted
+func chooseRelease(app, env string, rule *PromotionRule, now time.Time) (*Release, error) {
+	zr, err := datamodel.GetReleases(app, env)
+	if err != nil || len(zr.Releases) == 0 {
+		return nil, errors.New(fmt.Sprintf("No releases of %s in %s have been recorded", app, env))
+	}
+	release := zr.Releases[0]
+	if rule == nil {
+		return release, nil
+	}
+	if rule.From != env {
+		return nil, errors.New(fmt.Sprintf("%s can only be promoted from %s", app, rule.From))
+	}
+	if served := now.Unix() - release.Time; served < rule.MinTime {
+		return nil, errors.New(fmt.Sprintf("%s @ %s has only served %s for %s. It has to for %s before it can be "+
+			"promoted", app, release.Sha, env, time.Duration(served)*time.Second,
+			time.Duration(rule.MinTime)*time.Second))
+	}
+	return release, nil
+}
+
+// rejects a deploy of app @ sha to env unless sha is what the promotion rule of env (if any) lets be promoted to it.
+// redeploying the current release of env is always allowed.
+func checkPromotionRule(app *datamodel.ZkApp, sha, env string, now time.Time) error {
+	rule := app.GetPromotionRule(env)
+	if rule == nil {
+		return nil
+	}
+	if zr, err := datamodel.GetReleases(app.Name, env); err == nil && len(zr.Releases) > 0 &&
+		zr.Releases[0].Sha == sha {
+		return nil
+	}
+	release, err := chooseRelease(app.Name, rule.From, rule, now)
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s can only get %s from %s: %s", env, app.Name, rule.From, err.Error()))
+	}
+	if release.Sha != sha {
+		return errors.New(fmt.Sprintf("%s can only get %s from %s, which is serving %s", env, app.Name, rule.From,
+			release.Sha))
+	}
+	return nil
+}
+
+// checks /healthz of every container of app @ sha in env once
+func checkReleaseHealthy(app, sha, env string) error {
+	ids, err := datamodel.ListInstances(app, sha, env)
+	if err != nil || len(ids) == 0 {
+		return errors.New(fmt.Sprintf("%s @ %s is not deployed in %s", app, sha, env))
+	}
+	containers := []*Container{}
+	for _, id := range ids {
+		inst, err := datamodel.GetInstance(id)
+		if err != nil {
+			return err
+		}
+		containers = append(containers, &Container{ID: inst.ID, Host: inst.Host, PrimaryPort: inst.Port})
+	}
+	_, unhealthy := waitForHealthy(containers, 0)
+	if len(unhealthy) > 0 {
+		unhealthyIDs := make([]string, len(unhealthy))
+		for i, cont := range unhealthy {
+			unhealthyIDs[i] = cont.ID
+		}
+		return errors.New(fmt.Sprintf("%s @ %s is not healthy in %s: %s", app, sha, env,
+			strings.Join(unhealthyIDs, ", ")))
+	}
+	return nil
+}
+
+type PromoteExecutor struct {
+	arg   ManagerPromoteArg
+	reply *ManagerDeployReply
+}
+
+func (e *PromoteExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *PromoteExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *PromoteExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s from %s to %s", e.arg.App, e.arg.From, e.arg.To)
+}
+
+func (e *PromoteExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	return AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App)
+}
+
+func (e *PromoteExecutor) Execute(t *Task) (err error) {
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if e.arg.From == "" || e.arg.To == "" {
+		return errors.New("Please specify the environments to promote from and to")
+	}
+	if e.arg.From == e.arg.To {
+		return errors.New("Please specify two different environments")
+	}
+	app, err := datamodel.GetApp(e.arg.App)
+	if err != nil {
+		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+	}
+	if app.NonAtlantis {
+		return errors.New(fmt.Sprintf("%s is not an atlantis app", e.arg.App))
+	}
+	release, err := chooseRelease(e.arg.App, e.arg.From, app.GetPromotionRule(e.arg.To), time.Now())
+	if err != nil {
+		return err
+	}
+	t.LogStatus("Checking the health of %s @ %s in %s", e.arg.App, release.Sha, e.arg.From)
+	if err = checkReleaseHealthy(e.arg.App, release.Sha, e.arg.From); err != nil {
+		return err
+	}
+	t.Log("Promoting %s @ %s from %s to %s", e.arg.App, release.Sha, e.arg.From, e.arg.To)
+	manifest := release.Manifest.Dup()
+	arg := ManagerDeployArg{
+		ManagerAuthArg: e.arg.ManagerAuthArg,
+		App:            e.arg.App,
+		Sha:            release.Sha,
+		Env:            e.arg.To,
+		Instances:      manifest.Instances,
+		CPUShares:      manifest.CPUShares,
+		MemoryLimit:    manifest.MemoryLimit,
+		Replace:        e.arg.Replace,
+		DryRun:         e.arg.DryRun,
+		OverrideFreeze: e.arg.OverrideFreeze,
+	}
+	if !arg.DryRun && envProtected(arg.Env) {
+		// the approved deploy builds the sha, which gets the cached manifest with the same resources
+		return requestDeploy(t, &arg, e.reply)
+	}
+	defer func() { recordDeploy(t, &arg, manifest, err) }()
+	return deployManifest(t, &arg, e.reply, app, manifest)
+}
+
+func (m *ManagerRPC) Promote(arg ManagerPromoteArg, reply *AsyncReply) error {
+	executor := &PromoteExecutor{arg, &ManagerDeployReply{}}
+	return NewTask("Promote", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
+}
+
+// ----------------------------------------------------------------------------------------------------------
+// Promotion Rules
+// ----------------------------------------------------------------------------------------------------------
+
+type SetPromotionRuleExecutor struct {
+	arg   ManagerPromotionRuleArg
+	reply *ManagerPromotionRuleReply
+}
+
+func (e *SetPromotionRuleExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *SetPromotionRuleExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *SetPromotionRuleExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s in %s: from %s after %ds", e.arg.App, e.arg.Env,
+		e.arg.Rule.From, e.arg.Rule.MinTime)
+}
+
+// the rules guard the app's envs, so only its team admins (or superusers) can change them
+func (e *SetPromotionRuleExecutor) Authorize() error {
+	if err := SimpleAuthorize(&e.arg.ManagerAuthArg); err != nil {
+		return err
+	}
+	if err := authorizeAppAdmin(&e.arg.ManagerAuthArg, e.arg.App); err != nil {
+		return errors.New("Only a team admin of " + e.arg.App + " can change its promotion rules")
+	}
+	return nil
+}
+
+func (e *SetPromotionRuleExecutor) Execute(t *Task) error {
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if e.arg.Env == "" {
+		return errors.New("Please specify an environment")
+	}
+	if e.arg.Rule.From == e.arg.Env {
+		return errors.New("Please specify a different environment to promote from")
+	}
+	if e.arg.Rule.MinTime < 0 {
+		return errors.New("Please specify a time that is not negative")
+	}
+	app, err := datamodel.GetApp(e.arg.App)
+	if err != nil {
+		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+	}
+	if e.arg.Rule.From == "" {
+		err = app.RemovePromotionRule(e.arg.Env)
+	} else {
+		rule := e.arg.Rule
+		err = app.SetPromotionRule(e.arg.Env, &rule)
+	}
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	e.reply.Rules = app.Promotions
+	return nil
+}
+
+type ListPromotionRulesExecutor struct {
+	arg   ManagerPromotionRuleArg
+	reply *ManagerPromotionRuleReply
+}
+
+func (e *ListPromotionRulesExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ListPromotionRulesExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ListPromotionRulesExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s", e.arg.App)
+}
+
+func (e *ListPromotionRulesExecutor) Authorize() error {
+	return SimpleAuthorize(&e.arg.ManagerAuthArg)
+}
+
+func (e *ListPromotionRulesExecutor) Execute(t *Task) error {
+	app, err := datamodel.GetApp(e.arg.App)
+	if err != nil {
+		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+	}
+	e.reply.Rules = app.Promotions
+	if e.reply.Rules == nil {
+		e.reply.Rules = map[string]*PromotionRule{}
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (m *ManagerRPC) SetPromotionRule(arg ManagerPromotionRuleArg, reply *ManagerPromotionRuleReply) error {
+	return NewTask("SetPromotionRule", &SetPromotionRuleExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) ListPromotionRules(arg ManagerPromotionRuleArg, reply *ManagerPromotionRuleReply) error {
+	return NewTask("ListPromotionRules", &ListPromotionRulesExecutor{arg, reply}).Run()
+}
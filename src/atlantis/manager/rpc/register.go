@@ -15,6 +15,7 @@ import (
 	. "atlantis/common"
 	. "atlantis/manager/constant"
 	"atlantis/manager/datamodel"
+	"atlantis/manager/helper"
 	"atlantis/manager/manager"
 	"atlantis/manager/netsec"
 	"atlantis/manager/router"
@@ -23,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 )
 
 // ----------------------------------------------------------------------------------------------------------
@@ -288,11 +290,23 @@ func (e *RegisterAppExecutor) Execute(t *Task) error {
 	if e.arg.Email == "" {
 		return errors.New("Please specify the email of the app owner")
 	}
+	if _, err := datamodel.GetPlacementStrategy(e.arg.Placement); err != nil {
+		return err
+	}
+	if _, err := helper.ParseLabelSelector(e.arg.SupervisorSelector); err != nil {
+		return err
+	}
 	if _, err := datamodel.GetApp(e.arg.Name); err == nil {
 		return errors.New("Already Registered.")
 	}
-	_, err := datamodel.CreateOrUpdateApp(e.arg.NonAtlantis, e.arg.Internal, e.arg.Name, e.arg.Repo, e.arg.Root,
-		e.arg.Email)
+	zkApp, err := datamodel.CreateOrUpdateApp(e.arg.NonAtlantis, e.arg.Internal, e.arg.Name, e.arg.Repo,
+		e.arg.Root, e.arg.Email)
+	if err == nil {
+		err = zkApp.SetPlacement(e.arg.Placement)
+	}
+	if err == nil {
+		err = zkApp.SetSupervisorSelector(e.arg.SupervisorSelector)
+	}
 	if err != nil {
 		e.reply.Status = StatusError
 	}
@@ -338,8 +352,20 @@ func (e *UpdateAppExecutor) Execute(t *Task) error {
 	if e.arg.Email == "" {
 		return errors.New("Please specify the email of the app owner")
 	}
-	_, err := datamodel.CreateOrUpdateApp(e.arg.NonAtlantis, e.arg.Internal, e.arg.Name, e.arg.Repo, e.arg.Root,
-		e.arg.Email)
+	if _, err := datamodel.GetPlacementStrategy(e.arg.Placement); err != nil {
+		return err
+	}
+	if _, err := helper.ParseLabelSelector(e.arg.SupervisorSelector); err != nil {
+		return err
+	}
+	zkApp, err := datamodel.CreateOrUpdateApp(e.arg.NonAtlantis, e.arg.Internal, e.arg.Name, e.arg.Repo,
+		e.arg.Root, e.arg.Email)
+	if err == nil {
+		err = zkApp.SetPlacement(e.arg.Placement)
+	}
+	if err == nil {
+		err = zkApp.SetSupervisorSelector(e.arg.SupervisorSelector)
+	}
 	if err != nil {
 		e.reply.Status = StatusError
 	}
@@ -517,6 +543,11 @@ func (e *RegisterSupervisorExecutor) Execute(t *Task) error {
 	if e.arg.Host == "" {
 		return errors.New("Please specify a host to register")
 	}
+	for key, value := range e.arg.Labels {
+		if err := helper.CheckLabel(key, value); err != nil {
+			return err
+		}
+	}
 	// check health of to be registered supervisor
 	health, err := supervisor.HealthCheck(e.arg.Host)
 	if err != nil {
@@ -536,6 +567,17 @@ func (e *RegisterSupervisorExecutor) Execute(t *Task) error {
 		e.reply.Status = StatusError
 		return err
 	}
+	if len(e.arg.Labels) > 0 {
+		if _, err := datamodel.Supervisor(e.arg.Host).SetLabels(e.arg.Labels, nil); err != nil {
+			e.reply.Status = StatusError
+			return err
+		}
+	}
+	// so that it can be placed on before the next round of heartbeats
+	if _, err := datamodel.RecordHeartbeat(e.arg.Host, health, nil, time.Now(), MaxMissedHeartbeats); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
 	// try to push all ip groups to this new supervisor
 	if err := netsec.UpdateSupervisor(e.arg.Host); err != nil {
 		return err
@@ -674,20 +716,81 @@ func (e *ListSupervisorsExecutor) Description() string {
 }
 
 func (e *ListSupervisorsExecutor) Execute(t *Task) (err error) {
-	e.reply.Supervisors, err = datamodel.ListSupervisors()
+	e.reply.Supervisors, err = datamodel.ListSupervisorsMatching(e.arg.Selector)
 	if err != nil {
 		e.reply.Status = StatusError
-	} else {
-		sort.Strings(e.reply.Supervisors)
-		e.reply.Status = StatusOk
+		return err
 	}
-	return err
+	sort.Strings(e.reply.Supervisors)
+	e.reply.Labels = map[string]map[string]string{}
+	e.reply.Inventory = map[string]*SupervisorInventory{}
+	for _, host := range e.reply.Supervisors {
+		if info, err := datamodel.Supervisor(host).Info(); err == nil && len(info.Labels) > 0 {
+			e.reply.Labels[host] = info.Labels
+		}
+		if inventory, err := datamodel.GetSupervisorInventory(host); err == nil {
+			e.reply.Inventory[host] = inventory
+		}
+	}
+	e.reply.Status = StatusOk
+	return nil
 }
 
 func (e *ListSupervisorsExecutor) Authorize() error {
 	return SimpleAuthorize(&e.arg.ManagerAuthArg)
 }
 
+type LabelSupervisorExecutor struct {
+	arg   ManagerLabelSupervisorArg
+	reply *ManagerLabelSupervisorReply
+}
+
+func (e *LabelSupervisorExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *LabelSupervisorExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *LabelSupervisorExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s set: %v, remove: %v", e.arg.Host, e.arg.Labels,
+		e.arg.Remove)
+}
+
+func (e *LabelSupervisorExecutor) Execute(t *Task) (err error) {
+	if e.arg.Host == "" {
+		return errors.New("Please specify a host to label")
+	}
+	if len(e.arg.Labels) == 0 && len(e.arg.Remove) == 0 {
+		return errors.New("Please specify the labels to set or remove")
+	}
+	supervisors, err := datamodel.ListSupervisors()
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	registered := false
+	for _, host := range supervisors {
+		registered = registered || host == e.arg.Host
+	}
+	if !registered {
+		e.reply.Status = StatusError
+		return errors.New("Supervisor " + e.arg.Host + " is not registered")
+	}
+	e.reply.Labels, err = datamodel.Supervisor(e.arg.Host).SetLabels(e.arg.Labels, e.arg.Remove)
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (e *LabelSupervisorExecutor) Authorize() error {
+	return AuthorizeSuperUser(&e.arg.ManagerAuthArg)
+}
+
 // ----------------------------------------------------------------------------------------------------------
 // Register Manager
 // ----------------------------------------------------------------------------------------------------------
@@ -939,15 +1042,15 @@ func (m *ManagerRPC) ListRouters(arg ManagerListRoutersArg, reply *ManagerListRo
 }
 
 func (m *ManagerRPC) RegisterApp(arg ManagerRegisterAppArg, reply *ManagerRegisterAppReply) error {
-	return NewTask("RegisterApp", &RegisterAppExecutor{arg, reply}).Run()
+	return NewTask("RegisterApp", withWebhooks(&RegisterAppExecutor{arg, reply})).Run()
 }
 
 func (m *ManagerRPC) UpdateApp(arg ManagerRegisterAppArg, reply *ManagerRegisterAppReply) error {
-	return NewTask("UpdateApp", &UpdateAppExecutor{arg, reply}).Run()
+	return NewTask("UpdateApp", withWebhooks(&UpdateAppExecutor{arg, reply})).Run()
 }
 
 func (m *ManagerRPC) UnregisterApp(arg ManagerRegisterAppArg, reply *ManagerRegisterAppReply) error {
-	return NewTask("UnregisterApp", &UnregisterAppExecutor{arg, reply}).Run()
+	return NewTask("UnregisterApp", withWebhooks(&UnregisterAppExecutor{arg, reply})).Run()
 }
 
 func (m *ManagerRPC) GetApp(arg ManagerGetAppArg, reply *ManagerGetAppReply) error {
@@ -974,6 +1077,10 @@ func (m *ManagerRPC) ListSupervisors(arg ManagerListSupervisorsArg, reply *Manag
 	return NewTask("ListSupervisors", &ListSupervisorsExecutor{arg, reply}).Run()
 }
 
+func (m *ManagerRPC) LabelSupervisor(arg ManagerLabelSupervisorArg, reply *ManagerLabelSupervisorReply) error {
+	return NewTask("LabelSupervisor", &LabelSupervisorExecutor{arg, reply}).Run()
+}
+
 func (m *ManagerRPC) RegisterManager(arg ManagerRegisterManagerArg, reply *AsyncReply) error {
 	return NewTask("RegisterManager", &RegisterManagerExecutor{arg, &ManagerRegisterManagerReply{}}).RunAsync(reply)
 }
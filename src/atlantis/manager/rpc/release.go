@@ -0,0 +1,277 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/datamodel"
+	. "atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// how often a Release checks whether the deploy of its current app is done
+var releasePollInterval = time.Second
+
+// orders deploys so that every app comes after the apps of the bundle it depends on, keeping the given order
+// where it doesn't matter. dependers maps an app to the apps that depend on it (the keys of its DependerAppData).
+func releaseOrder(deploys []ReleaseDeploy, dependers map[string][]string) ([]ReleaseDeploy, error) {
+	inBundle := map[string]bool{}
+	for _, deploy := range deploys {
+		inBundle[deploy.App] = true
+	}
+	// app -> # of apps in the bundle it still has to wait for
+	waitingOn := map[string]int{}
+	for _, deploy := range deploys {
+		for _, depender := range dependers[deploy.App] {
+			if inBundle[depender] && depender != deploy.App {
+				waitingOn[depender]++
+			}
+		}
+	}
+	ordered := make([]ReleaseDeploy, 0, len(deploys))
+	left := deploys
+	for len(left) > 0 {
+		next := -1
+		for i, deploy := range left {
+			if waitingOn[deploy.App] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			cycle := releaseCycle(left, dependers)
+			return nil, errors.New(fmt.Sprintf("Circular dependency: %s (each app depends on the next)",
+				strings.Join(append(cycle, cycle[0]), " -> ")))
+		}
+		deploy := left[next]
+		ordered = append(ordered, deploy)
+		left = append(append([]ReleaseDeploy{}, left[:next]...), left[next+1:]...)
+		for _, depender := range dependers[deploy.App] {
+			if inBundle[depender] && depender != deploy.App {
+				waitingOn[depender]--
+			}
+		}
+	}
+	return ordered, nil
+}
+
+// every app left waits on another app left, so following what they depend on from any of them ends in a cycle
+func releaseCycle(left []ReleaseDeploy, dependers map[string][]string) []string {
+	isLeft := map[string]bool{}
+	for _, deploy := range left {
+		isLeft[deploy.App] = true
+	}
+	dependsOn := map[string]string{}
+	for _, deploy := range left {
+		for _, depender := range dependers[deploy.App] {
+			if _, ok := dependsOn[depender]; !ok && isLeft[depender] && depender != deploy.App {
+				dependsOn[depender] = deploy.App
+			}
+		}
+	}
+	path := []string{}
+	seen := map[string]int{}
+	for app := left[0].App; ; app = dependsOn[app] {
+		if i, ok := seen[app]; ok {
+			return path[i:]
+		}
+		seen[app] = len(path)
+		path = append(path, app)
+	}
+}
+
+type ReleaseExecutor struct {
+	arg   ManagerReleaseArg
+	reply *ManagerReleaseReply
+}
+
+func (e *ReleaseExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ReleaseExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ReleaseExecutor) Description() string {
+	deploys := make([]string, len(e.arg.Deploys))
+	for i, deploy := range e.arg.Deploys {
+		deploys[i] = deploy.App + " @ " + deploy.Sha
+	}
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s in %s", strings.Join(deploys, ", "), e.arg.Env)
+}
+
+func (e *ReleaseExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	return SimpleAuthorize(&e.arg.ManagerAuthArg)
+}
+
+// checks every app of the bundle before anything is deployed and returns the order to deploy them in
+func (e *ReleaseExecutor) plan() ([]ReleaseDeploy, error) {
+	if e.arg.Env == "" {
+		return nil, errors.New("Please specify an environment")
+	}
+	if len(e.arg.Deploys) == 0 {
+		return nil, errors.New("Please specify the apps to release")
+	}
+	if !e.arg.DryRun && envProtected(e.arg.Env) {
+		return nil, errors.New(fmt.Sprintf("Deploys to %s have to be approved, so please deploy its apps one at a "+
+			"time", e.arg.Env))
+	}
+	dependers := map[string][]string{}
+	for _, deploy := range e.arg.Deploys {
+		if deploy.App == "" || deploy.Sha == "" {
+			return nil, errors.New("Please specify an app and a sha for every deploy")
+		}
+		if _, ok := dependers[deploy.App]; ok {
+			return nil, errors.New(fmt.Sprintf("%s can only be released once", deploy.App))
+		}
+		app, err := datamodel.GetApp(deploy.App)
+		if err != nil {
+			return nil, errors.New("App " + deploy.App + " is not registered: " + err.Error())
+		}
+		if app.NonAtlantis {
+			return nil, errors.New(fmt.Sprintf("%s is not an atlantis app", deploy.App))
+		}
+		if err := AuthorizeApp(&e.arg.ManagerAuthArg, deploy.App); err != nil {
+			return nil, err
+		}
+		dependers[deploy.App] = []string{}
+		for depender := range app.DependerAppData {
+			dependers[deploy.App] = append(dependers[deploy.App], depender)
+		}
+	}
+	return releaseOrder(e.arg.Deploys, dependers)
+}
+
+func (e *ReleaseExecutor) Execute(t *Task) (err error) {
+	defer func() {
+		if err != nil {
+			e.reply.Error = err.Error()
+		}
+	}()
+	order, err := e.plan()
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	for _, deploy := range order {
+		e.reply.Order = append(e.reply.Order, deploy.App)
+	}
+	t.Log("Releasing %s to %s", strings.Join(e.reply.Order, ", "), e.arg.Env)
+	for _, deploy := range order {
+		if err := checkCancelled(t); err != nil {
+			e.reply.Status = StatusCancelled
+			return err
+		}
+		result := e.deploy(t, deploy)
+		e.reply.Deploys = append(e.reply.Deploys, result)
+		if result.Status != StatusOk {
+			if err := checkCancelled(t); err != nil {
+				e.reply.Status = StatusCancelled
+				return err
+			}
+			e.reply.Status = StatusError
+			msg := fmt.Sprintf("Stopped the release because the deploy of %s did not succeed (%s)", deploy.App,
+				result.Status)
+			if result.Error != "" {
+				msg += ": " + result.Error
+			}
+			return errors.New(msg)
+		}
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+// runs the Deploy of one app as a task of its own and waits for it. cancelling the release cancels the deploy.
+func (e *ReleaseExecutor) deploy(t *Task, deploy ReleaseDeploy) *ReleaseDeployResult {
+	result := &ReleaseDeployResult{ReleaseDeploy: deploy}
+	arg := e.arg.ManagerDeployArg
+	arg.App = deploy.App
+	arg.Sha = deploy.Sha
+	var reply AsyncReply
+	executor := &DeployExecutor{arg, &ManagerDeployReply{}}
+	err := NewTask("Deploy", withWebhooks(cancellable(arg.User, executor))).RunAsync(&reply)
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+		return result
+	}
+	result.TaskID = reply.ID
+	t.LogStatus("Deploying %s @ %s as task %s", deploy.App, deploy.Sha, reply.ID)
+	for {
+		status, err := Tracker.Status(reply.ID)
+		if status != nil && status.Done {
+			if _, ok := err.(CancelledError); ok {
+				result.Status = StatusCancelled
+			} else if err != nil || status.Status == StatusError {
+				result.Status = StatusError
+				if err != nil {
+					result.Error = err.Error()
+				}
+			} else {
+				result.Status = StatusOk
+			}
+			break
+		}
+		if cancelRequested(t.ID) {
+			requestCancel(reply.ID)
+		}
+		time.Sleep(releasePollInterval)
+	}
+	if deployReply, ok := Tracker.Result(reply.ID).(*ManagerDeployReply); ok {
+		result.Containers = deployReply.Containers
+	}
+	t.Log("Deploy of %s @ %s: %s", deploy.App, deploy.Sha, result.Status)
+	return result
+}
+
+func (m *ManagerRPC) Release(arg ManagerReleaseArg, reply *AsyncReply) error {
+	executor := &ReleaseExecutor{arg, &ManagerReleaseReply{}}
+	return NewTask("Release", withWebhooks(cancellable(arg.User, executor))).RunAsync(reply)
+}
+
+func (m *ManagerRPC) ReleaseResult(id string, result *ManagerReleaseReply) error {
+	if id == "" {
+		return errors.New("ID empty")
+	}
+	status, err := Tracker.Status(id)
+	if status.Status == StatusUnknown {
+		return errors.New("Unknown ID.")
+	}
+	if status.Name != "Release" {
+		return errors.New("ID is not a Release.")
+	}
+	if !status.Done {
+		return errors.New("Release isn't done.")
+	}
+	// a failed release is returned too, since its result says which deploys were done and why it stopped
+	getResult := Tracker.Result(id)
+	switch r := getResult.(type) {
+	case *ManagerReleaseReply:
+		*result = *r
+	default:
+		if err != nil {
+			return err
+		}
+		// this should never happen
+		return errors.New("Invalid Result Type.")
+	}
+	return nil
+}
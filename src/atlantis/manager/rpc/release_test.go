@@ -0,0 +1,59 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/manager/rpc/types"
+	. "launchpad.net/gocheck"
+)
+
+type ReleaseSuite struct{}
+
+var _ = Suite(&ReleaseSuite{})
+
+func releaseApps(deploys []ReleaseDeploy) []string {
+	apps := make([]string, len(deploys))
+	for i, deploy := range deploys {
+		apps[i] = deploy.App
+	}
+	return apps
+}
+
+func (s *ReleaseSuite) TestReleaseOrder(c *C) {
+	deploys := []ReleaseDeploy{{"frontend", "sha1"}, {"admin", "sha2"}, {"backend", "sha3"}, {"db", "sha4"}}
+	// frontend and admin depend on backend, backend depends on db. search isn't in the bundle.
+	dependers := map[string][]string{
+		"db":       []string{"backend"},
+		"backend":  []string{"frontend", "admin", "search"},
+		"frontend": []string{},
+	}
+	ordered, err := releaseOrder(deploys, dependers)
+	c.Assert(err, IsNil)
+	c.Assert(releaseApps(ordered), DeepEquals, []string{"db", "backend", "frontend", "admin"})
+	c.Assert(ordered[0].Sha, Equals, "sha4")
+
+	// apps without dependencies between them keep their order
+	ordered, err = releaseOrder(deploys[:2], dependers)
+	c.Assert(err, IsNil)
+	c.Assert(releaseApps(ordered), DeepEquals, []string{"frontend", "admin"})
+
+	dependers["frontend"] = []string{"db"}
+	_, err = releaseOrder(deploys, dependers)
+	c.Assert(err, ErrorMatches, "Circular dependency: frontend -> backend -> db -> frontend .*")
+	// admin isn't part of the cycle, it only waits on it
+	_, err = releaseOrder([]ReleaseDeploy{deploys[1], deploys[0], deploys[2], deploys[3]}, dependers)
+	c.Assert(err, ErrorMatches, "Circular dependency: backend -> db -> frontend -> backend .*")
+	// the cycle doesn't matter if one of its apps isn't released
+	ordered, err = releaseOrder(deploys[1:], dependers)
+	c.Assert(err, IsNil)
+	c.Assert(releaseApps(ordered), DeepEquals, []string{"db", "backend", "admin"})
+}
@@ -42,6 +42,24 @@ var (
 	superUserOnly        = false
 )
 
+// deploys wait up to HealthzTimeout for new containers to report OK on /healthz before adding them to the pool. if
+// fewer than MinHealthyPercent of them do, the deploy fails. a HealthzTimeout of 0 (the default) skips the wait, so
+// only managers whose apps all serve /healthz should turn it on.
+var (
+	HealthzTimeout    = time.Duration(0)
+	HealthzInterval   = 5 * time.Second
+	MinHealthyPercent = uint(100)
+)
+
+// a supervisor gets DeployHostTimeout to deploy a container before it counts as failed (0 waits forever). hosts that
+// fail are not used again for the rest of the deploy and a zone retries its failed containers on the hosts left up
+// to DeployHostRetries times, waiting DeployRetryBackoff (doubled every retry) in between.
+var (
+	DeployHostTimeout  = 5 * time.Minute
+	DeployHostRetries  = uint(3)
+	DeployRetryBackoff = 5 * time.Second
+)
+
 func SuperUserOnlyChecker(file string, interval time.Duration) {
 	go func() {
 		for {
@@ -0,0 +1,269 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/datamodel"
+	. "atlantis/manager/rpc/types"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// task id -> true for the deploys started by the scheduler. a schedule doesn't keep the credentials of whoever
+// made it, so these deploys are authorized when they are scheduled instead of when they run.
+var (
+	scheduledTasks     = map[string]bool{}
+	scheduledTasksLock sync.Mutex
+)
+
+func scheduledTask(id string) bool {
+	scheduledTasksLock.Lock()
+	defer scheduledTasksLock.Unlock()
+	return scheduledTasks[id]
+}
+
+// Every manager checks for schedules that are due every interval. ClaimScheduledDeploy makes sure only one of them
+// starts each deploy.
+func DeployScheduler(interval time.Duration) {
+	go func() {
+		for {
+			startDueDeploys(time.Now())
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func startDueDeploys(now time.Time) {
+	if err := checkRole("deploys", "write"); err != nil {
+		return // leave the schedules to a manager that can deploy
+	}
+	schedules, err := datamodel.ListScheduledDeploys("", "", false)
+	if err != nil {
+		log.Printf("[Scheduler] ERROR: could not list scheduled deploys: %s", err.Error())
+		return
+	}
+	for _, schedule := range schedules {
+		if schedule.Time > now.Unix() {
+			continue
+		}
+		claimed, err := datamodel.ClaimScheduledDeploy(schedule.ID, Host, now)
+		if err != nil {
+			log.Printf("[Scheduler] ERROR: could not claim scheduled deploy %s: %s", schedule.ID, err.Error())
+			continue
+		}
+		if claimed != nil {
+			startScheduledDeploy(claimed)
+		}
+	}
+}
+
+// starts a normal Deploy task for schedule and links it to the schedule
+func startScheduledDeploy(schedule *ScheduledDeploy) {
+	var reply AsyncReply
+	executor := &scheduledDeployExecutor{&DeployExecutor{schedule.Arg, &ManagerDeployReply{}}}
+	err := NewTask("Deploy", withWebhooks(cancellable(schedule.Arg.User, executor))).RunAsync(&reply)
+	if err != nil {
+		log.Printf("[Scheduler] ERROR: could not start scheduled deploy %s: %s", schedule.ID, err.Error())
+		schedule.Status = ScheduledDeployFailed
+		schedule.Error = err.Error()
+	} else {
+		log.Printf("[Scheduler] started scheduled deploy %s as task %s", schedule.ID, reply.ID)
+		schedule.TaskID = reply.ID
+	}
+	if err := datamodel.UpdateScheduledDeploy(schedule); err != nil {
+		log.Printf("[Scheduler] ERROR: could not update scheduled deploy %s: %s", schedule.ID, err.Error())
+	}
+}
+
+// a Deploy that was authorized when it was scheduled
+type scheduledDeployExecutor struct {
+	*DeployExecutor
+}
+
+func (e *scheduledDeployExecutor) Authorize() error {
+	return checkRole("deploys", "write")
+}
+
+func (e *scheduledDeployExecutor) Execute(t *Task) error {
+	scheduledTasksLock.Lock()
+	scheduledTasks[t.ID] = true
+	scheduledTasksLock.Unlock()
+	defer func() {
+		scheduledTasksLock.Lock()
+		delete(scheduledTasks, t.ID)
+		scheduledTasksLock.Unlock()
+	}()
+	return e.DeployExecutor.Execute(t)
+}
+
+// ----------------------------------------------------------------------------------------------------------
+// Schedule, Cancel, List Scheduled Deploys
+// ----------------------------------------------------------------------------------------------------------
+
+type ScheduleDeployExecutor struct {
+	arg   ManagerScheduleDeployArg
+	reply *ManagerScheduledDeployReply
+}
+
+func (e *ScheduleDeployExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ScheduleDeployExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ScheduleDeployExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s @ %s in %s at %s", e.arg.App, e.arg.Sha, e.arg.Env,
+		time.Unix(e.arg.Time, 0).UTC().Format(time.RFC3339))
+}
+
+// whoever schedules the deploy has to be allowed to run it, since it runs without their credentials
+func (e *ScheduleDeployExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	if err := AuthorizeApp(&e.arg.ManagerAuthArg, e.arg.App); err != nil {
+		return err
+	}
+	if e.arg.OverrideFreeze != "" {
+		return AuthorizeSuperUser(&e.arg.ManagerAuthArg)
+	}
+	return nil
+}
+
+func (e *ScheduleDeployExecutor) Execute(t *Task) error {
+	if e.arg.App == "" {
+		return errors.New("Please specify an app")
+	}
+	if err := checkDeployArg(&e.arg.ManagerDeployArg); err != nil {
+		return err
+	}
+	if e.arg.DryRun {
+		return errors.New("Dry runs can not be scheduled")
+	}
+	if e.arg.Time <= time.Now().Unix() {
+		return errors.New("Please specify a time in the future")
+	}
+	if _, err := datamodel.GetApp(e.arg.App); err != nil {
+		return errors.New("App " + e.arg.App + " is not registered: " + err.Error())
+	}
+	if _, err := datamodel.GetEnv(e.arg.Env); err != nil {
+		return errors.New(fmt.Sprintf("Environment %s does not exist", e.arg.Env))
+	}
+	arg := e.arg.ManagerDeployArg
+	arg.ManagerAuthArg = ManagerAuthArg{User: e.arg.ManagerAuthArg.User}
+	schedule := &ScheduledDeploy{User: arg.ManagerAuthArg.User, Arg: arg, Time: e.arg.Time}
+	if err := datamodel.AddScheduledDeploy(schedule); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	t.Log("Scheduled deploy %s", schedule.ID)
+	e.reply.Status = StatusOk
+	e.reply.Schedule = schedule
+	return nil
+}
+
+type CancelScheduledDeployExecutor struct {
+	arg   ManagerScheduledDeployArg
+	reply *ManagerScheduledDeployReply
+}
+
+func (e *CancelScheduledDeployExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *CancelScheduledDeployExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *CancelScheduledDeployExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s", e.arg.ID)
+}
+
+// the user that scheduled a deploy or a superuser can cancel it
+func (e *CancelScheduledDeployExecutor) Authorize() error {
+	if AuthorizeSuperUser(&e.arg.ManagerAuthArg) == nil {
+		return nil
+	}
+	if err := SimpleAuthorize(&e.arg.ManagerAuthArg); err != nil {
+		return err
+	}
+	schedule, err := datamodel.GetScheduledDeploy(e.arg.ID)
+	if err != nil {
+		return errors.New("Scheduled deploy " + e.arg.ID + " does not exist")
+	}
+	if schedule.User != e.arg.ManagerAuthArg.User {
+		return errors.New(fmt.Sprintf("Only %s or a superuser can cancel %s", schedule.User, e.arg.ID))
+	}
+	return nil
+}
+
+func (e *CancelScheduledDeployExecutor) Execute(t *Task) error {
+	schedule, err := datamodel.CancelScheduledDeploy(e.arg.ID, e.arg.ManagerAuthArg.User)
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	e.reply.Schedule = schedule
+	return nil
+}
+
+type ListScheduledDeploysExecutor struct {
+	arg   ManagerListScheduledDeploysArg
+	reply *ManagerListScheduledDeploysReply
+}
+
+func (e *ListScheduledDeploysExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ListScheduledDeploysExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ListScheduledDeploysExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] app: %s, env: %s (all: %t)", e.arg.App, e.arg.Env,
+		e.arg.All)
+}
+
+func (e *ListScheduledDeploysExecutor) Authorize() error {
+	return SimpleAuthorize(&e.arg.ManagerAuthArg)
+}
+
+func (e *ListScheduledDeploysExecutor) Execute(t *Task) (err error) {
+	if e.reply.Schedules, err = datamodel.ListScheduledDeploys(e.arg.App, e.arg.Env, e.arg.All); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (m *ManagerRPC) ScheduleDeploy(arg ManagerScheduleDeployArg, reply *ManagerScheduledDeployReply) error {
+	return NewTask("ScheduleDeploy", &ScheduleDeployExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) CancelScheduledDeploy(arg ManagerScheduledDeployArg, reply *ManagerScheduledDeployReply) error {
+	return NewTask("CancelScheduledDeploy", &CancelScheduledDeployExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) ListScheduledDeploys(arg ManagerListScheduledDeploysArg,
+	reply *ManagerListScheduledDeploysReply) error {
+	return NewTask("ListScheduledDeploys", &ListScheduledDeploysExecutor{arg, reply}).Run()
+}
@@ -13,11 +13,104 @@ package rpc
 
 import (
 	. "atlantis/common"
+	. "atlantis/manager/constant"
 	. "atlantis/manager/rpc/types"
 	"errors"
+	"fmt"
 	"sort"
+	"sync"
 )
 
+// a running task that can be cancelled
+type runningTask struct {
+	owner     string // the user that started it
+	requested bool   // whether it has been asked to stop
+	stopped   bool   // whether it gave up at a safe point (see checkCancelled)
+}
+
+// task id -> the tasks that can be cancelled and are running. a task is only in here while cancellable runs it.
+var (
+	runningTasks     = map[string]*runningTask{}
+	runningTasksLock sync.Mutex
+)
+
+type CancelledError string
+
+func (e CancelledError) Error() string {
+	return "Cancelled: " + string(e)
+}
+
+// Wraps the executor of a task that can be cancelled while it runs. a task that stopped because it was cancelled
+// always fails with a CancelledError, so that is what the Tracker keeps for it.
+type cancellableExecutor struct {
+	taskExecutor
+	owner string
+}
+
+func cancellable(owner string, executor taskExecutor) *cancellableExecutor {
+	return &cancellableExecutor{executor, owner}
+}
+
+func (e *cancellableExecutor) Execute(t *Task) error {
+	runningTasksLock.Lock()
+	runningTasks[t.ID] = &runningTask{owner: e.owner}
+	runningTasksLock.Unlock()
+	err := e.taskExecutor.Execute(t)
+	runningTasksLock.Lock()
+	defer runningTasksLock.Unlock()
+	stopped := runningTasks[t.ID].stopped
+	delete(runningTasks, t.ID)
+	if stopped {
+		// executors may have wrapped the CancelledError on the way out
+		return CancelledError(t.ID)
+	}
+	return err
+}
+
+// returns a CancelledError if the task has been asked to stop. executors call this at points where it is safe to
+// give up and are expected to clean up whatever they have created so far before returning the error.
+func checkCancelled(t *Task) error {
+	runningTasksLock.Lock()
+	defer runningTasksLock.Unlock()
+	task, ok := runningTasks[t.ID]
+	if !ok || !task.requested {
+		return nil
+	}
+	task.stopped = true
+	t.Log("Cancelling")
+	return CancelledError(t.ID)
+}
+
+// asks the task to stop at its next safe point. returns false if it isn't a running task that can be cancelled.
+func requestCancel(id string) bool {
+	runningTasksLock.Lock()
+	defer runningTasksLock.Unlock()
+	task, ok := runningTasks[id]
+	if ok {
+		task.requested = true
+	}
+	return ok
+}
+
+// whether the task has been asked to stop (it may still be running)
+func cancelRequested(id string) bool {
+	runningTasksLock.Lock()
+	defer runningTasksLock.Unlock()
+	task, ok := runningTasks[id]
+	return ok && task.requested
+}
+
+// the user that started the task, if it is a running task that can be cancelled
+func taskOwner(id string) (string, bool) {
+	runningTasksLock.Lock()
+	defer runningTasksLock.Unlock()
+	task, ok := runningTasks[id]
+	if !ok {
+		return "", false
+	}
+	return task.owner, true
+}
+
 func (m *ManagerRPC) Status(id string, status *TaskStatus) error {
 	if id == "" {
 		return errors.New("ID empty")
@@ -28,14 +121,81 @@ func (m *ManagerRPC) Status(id string, status *TaskStatus) error {
 	} else {
 		*status = *getStatus
 	}
+	if _, ok := getError.(CancelledError); ok && status.Done {
+		status.Status = StatusCancelled
+	}
 	return getError
 }
 
+type CancelTaskExecutor struct {
+	arg   ManagerCancelTaskArg
+	reply *ManagerCancelTaskReply
+}
+
+func (e *CancelTaskExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *CancelTaskExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *CancelTaskExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s", e.arg.ID)
+}
+
+func (e *CancelTaskExecutor) Authorize() error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	if AuthorizeSuperUser(&e.arg.ManagerAuthArg) == nil {
+		return nil
+	}
+	if err := SimpleAuthorize(&e.arg.ManagerAuthArg); err != nil {
+		return err
+	}
+	// everyone else can only cancel their own tasks. Execute reports the tasks that aren't running.
+	if owner, ok := taskOwner(e.arg.ID); ok && owner != e.arg.ManagerAuthArg.User {
+		return errors.New(fmt.Sprintf("Only %s's owner or a superuser can cancel it", e.arg.ID))
+	}
+	return nil
+}
+
+func (e *CancelTaskExecutor) Execute(t *Task) error {
+	if e.arg.ID == "" {
+		return errors.New("Please specify a task ID")
+	}
+	status, err := Tracker.Status(e.arg.ID)
+	if status == nil || status.Status == StatusUnknown {
+		e.reply.Status = StatusError
+		return errors.New("Unknown ID.")
+	}
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	if status.Done {
+		e.reply.Status = StatusError
+		return errors.New(fmt.Sprintf("%s is already done", e.arg.ID))
+	}
+	if !requestCancel(e.arg.ID) {
+		e.reply.Status = StatusError
+		return errors.New(fmt.Sprintf("%s tasks can not be cancelled", status.Name))
+	}
+	t.Log("Requested cancel of %s", e.arg.ID)
+	e.reply.Status = StatusOk
+	return nil
+}
+
+func (m *ManagerRPC) CancelTask(arg ManagerCancelTaskArg, reply *ManagerCancelTaskReply) error {
+	return NewTask("CancelTask", &CancelTaskExecutor{arg, reply}).Run()
+}
+
 func (m *ManagerRPC) ListTaskIDs(arg ManagerAuthArg, ids *[]string) error {
 	if err := SimpleAuthorize(&arg); err != nil {
 		return err
 	}
-	types := []string{"Deploy", "Teardown"}
+	types := []string{"Deploy", "Release", "Teardown"}
 	if AuthorizeSuperUser(&arg) == nil {
 		// superuser, return all types
 		types = append(types, []string{
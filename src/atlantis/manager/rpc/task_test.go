@@ -0,0 +1,88 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	"errors"
+	. "launchpad.net/gocheck"
+	"time"
+)
+
+type TaskSuite struct{}
+
+var _ = Suite(&TaskSuite{})
+
+// an executor that runs until it has been asked to stop (or gives up after a few checks)
+type cancelTestExecutor struct {
+	checks  int
+	wrapErr bool
+	started chan bool
+}
+
+func (e *cancelTestExecutor) Request() interface{} {
+	return nil
+}
+
+func (e *cancelTestExecutor) Result() interface{} {
+	return nil
+}
+
+func (e *cancelTestExecutor) Description() string {
+	return "cancel test"
+}
+
+func (e *cancelTestExecutor) Authorize() error {
+	return nil
+}
+
+func (e *cancelTestExecutor) Execute(t *Task) error {
+	close(e.started)
+	for i := 0; i < e.checks; i++ {
+		if err := checkCancelled(t); err != nil {
+			if e.wrapErr {
+				return errors.New("Deploy Error: " + err.Error())
+			}
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func (s *TaskSuite) TestCheckCancelled(c *C) {
+	t := &Task{ID: "cancel-test"}
+	// tasks that aren't running can't be cancelled
+	c.Assert(checkCancelled(t), IsNil)
+	c.Assert(requestCancel(t.ID), Equals, false)
+	c.Assert(cancelRequested(t.ID), Equals, false)
+
+	executor := &cancelTestExecutor{checks: 100, wrapErr: true, started: make(chan bool)}
+	done := make(chan error)
+	go func() { done <- cancellable("owner", executor).Execute(t) }()
+	<-executor.started
+	owner, ok := taskOwner(t.ID)
+	c.Assert(ok, Equals, true)
+	c.Assert(owner, Equals, "owner")
+	c.Assert(requestCancel(t.ID), Equals, true)
+	c.Assert(cancelRequested(t.ID), Equals, true)
+	// the wrapped error still ends the task as cancelled
+	c.Assert(<-done, Equals, CancelledError(t.ID))
+	_, ok = taskOwner(t.ID)
+	c.Assert(ok, Equals, false)
+	c.Assert(len(runningTasks), Equals, 0)
+
+	// a task that is done before it is asked to stop isn't cancelled
+	executor = &cancelTestExecutor{checks: 1, started: make(chan bool)}
+	c.Assert(cancellable("owner", executor).Execute(t), IsNil)
+	c.Assert(len(runningTasks), Equals, 0)
+}
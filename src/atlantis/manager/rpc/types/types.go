@@ -30,6 +30,35 @@ type App struct {
 	Root            string // atlantis apps only
 	DependerEnvData map[string]*DependerEnvData
 	DependerAppData map[string]*DependerAppData
+	Canaries        map[string]*Canary `json:",omitempty"` // env -> canary
+	// env -> the rule a sha has to pass to be promoted to env
+	Promotions map[string]*PromotionRule `json:",omitempty"`
+	// how its containers are placed on supervisors (spread, binpack or cost). empty means spread.
+	Placement string `json:",omitempty"`
+	// the labels a supervisor has to have to run its containers, e.g. "ssd=true,tier!=batch"
+	SupervisorSelector string `json:",omitempty"`
+	// other app -> where the app's containers go relative to the other app's in the same env
+	Affinities map[string]*AffinityRule `json:",omitempty"`
+}
+
+type Canary struct {
+	Sha    string
+	Weight uint // percent of the app+env's traffic sent to Sha
+}
+
+// Keeps an app's containers on the supervisors that have containers of App in the same env (or, if Anti, away
+// from them). Hard rules rule supervisors out, soft ones only make them more or less likely to be picked. App
+// can be the app itself for an anti-affinity rule: no two containers of a deploy on one supervisor.
+type AffinityRule struct {
+	App  string
+	Anti bool
+	Hard bool
+}
+
+// Only shas that have served From for at least MinTime seconds may be promoted to the env of the rule
+type PromotionRule struct {
+	From    string
+	MinTime int64
 }
 
 type DependerEnvData struct {
@@ -90,6 +119,8 @@ type SupervisorUsage struct {
 	TotalMemory     uint
 	TotalPrice      float64
 	Containers      map[string]*ContainerUsage
+	State           string // up, down or unknown (no heartbeat recorded yet), see SupervisorInventory
+	LastSeen        int64
 }
 
 type ContainerUsage struct {
@@ -103,6 +134,43 @@ type ContainerUsage struct {
 	MemPrice  float64
 }
 
+// What the manager knows about a supervisor from its heartbeats. a supervisor is down once it has missed too many
+// heartbeats in a row, and up again once it answers one.
+type SupervisorInventory struct {
+	Host             string
+	State            string // up or down
+	Zone             string
+	Status           string // what the last heartbeat the supervisor answered said (OK, FULL, ...)
+	Price            float64
+	TotalContainers  uint
+	TotalCPUShares   uint
+	TotalMemory      uint   // MBytes
+	LastSeen         int64  // unix time of the last heartbeat the supervisor answered
+	LastHeartbeat    int64  // unix time of the last heartbeat
+	MissedHeartbeats uint   // in a row
+	LastError        string // why the last missed heartbeat was missed
+	// what the containers we put on the supervisor used as of the last heartbeat (answered or not)
+	UsedContainers uint
+	UsedCPUShares  uint
+	UsedMemory     uint                                 // MBytes
+	Running        map[string]map[string]map[string]int `json:",omitempty"` // env -> app -> sha -> # of containers
+}
+
+func (i *SupervisorInventory) CountAppShaEnv(app, sha, env string) int {
+	return i.Running[env][app][sha]
+}
+
+// app -> # of containers the supervisor has of it in env
+func (i *SupervisorInventory) AppEnvCounts(env string) map[string]int {
+	counts := map[string]int{}
+	for app, shas := range i.Running[env] {
+		for _, count := range shas {
+			counts[app] += count
+		}
+	}
+	return counts
+}
+
 // Manager RPC Types
 
 // ------------ Health Check ------------
@@ -130,7 +198,8 @@ type ManagerUsageReply struct {
 // Used to register an Supervisor
 type ManagerRegisterSupervisorArg struct {
 	ManagerAuthArg
-	Host string
+	Host   string
+	Labels map[string]string // only used when registering
 }
 
 type ManagerRegisterSupervisorReply struct {
@@ -197,6 +266,9 @@ type ManagerRegisterAppArg struct {
 	Repo        string
 	Root        string
 	Email       string
+	Placement   string // see App.Placement
+	// see App.SupervisorSelector
+	SupervisorSelector string
 }
 
 type ManagerRegisterAppReply struct {
@@ -404,13 +476,30 @@ type ManagerListRoutersReply struct {
 // Used to list available Supervisors
 type ManagerListSupervisorsArg struct {
 	ManagerAuthArg
+	Selector string // only list the supervisors matching this label selector
 }
 
 type ManagerListSupervisorsReply struct {
 	Supervisors []string
+	Labels      map[string]map[string]string    // supervisor -> label -> value
+	Inventory   map[string]*SupervisorInventory // supervisor -> inventory (if it has had a heartbeat)
 	Status      string
 }
 
+// ------------ Label Supervisor ------------
+// Used to set and remove the labels of a Supervisor
+type ManagerLabelSupervisorArg struct {
+	ManagerAuthArg
+	Host   string
+	Labels map[string]string
+	Remove []string
+}
+
+type ManagerLabelSupervisorReply struct {
+	Labels map[string]string
+	Status string
+}
+
 // ------------ List Managers ------------
 // Used to list available Managers
 type ManagerListManagersArg struct {
@@ -447,6 +536,33 @@ type ManagerEnvReply struct {
 	Status string
 }
 
+// ------------ Freezes ------------
+// A window in which Deploy, DeployContainer and Teardown are rejected in an env unless a superuser overrides
+// the freeze. Start and End bound the freeze (0 for unbounded) and, if WeeklyStart and WeeklyEnd are set, it
+// is only in effect between them each week.
+type Freeze struct {
+	Name        string
+	Reason      string
+	User        string // who added the freeze
+	Start       int64  // unix time
+	End         int64  // unix time
+	WeeklyStart string // e.g. "Fri 16:00" (UTC)
+	WeeklyEnd   string // e.g. "Mon 09:00" (UTC)
+}
+
+// Used to add (or replace), delete and list the freezes of an env. only Freeze.Name is needed to delete.
+type ManagerFreezeArg struct {
+	ManagerAuthArg
+	Env    string
+	Freeze Freeze
+}
+
+type ManagerFreezeReply struct {
+	Status  string
+	Freezes []*Freeze
+	Active  []string // the names of the freezes in effect now
+}
+
 // ------------ Deploy ------------
 // Used to deploy an app+sha+env
 type ManagerDeployArg struct {
@@ -458,19 +574,197 @@ type ManagerDeployArg struct {
 	CPUShares   uint // relative shares
 	MemoryLimit uint // MBytes
 	Dev         bool // if true, only install 1 instance in 1 zone
+	Replace     bool // if true, swap traffic to this sha and tear down the other shas in the env
+	DrainTime   uint // seconds to wait between swapping traffic and tearing down (Replace only)
+	WatchTime   uint // seconds to keep checking the new containers after they go live (0 to not watch)
+	MaxFailPct  uint // roll back if more than this percent of the new containers are unhealthy while watching
+	DryRun      bool // if true, only return where the containers would go
+	// zone -> # of instances. if empty, Instances are deployed in every available zone
+	Zones map[string]uint
+	// # of zones that have to succeed for the deploy to keep the ones that did (0 means every zone, or only the
+	// RequiredZones if there are any)
+	MinZones uint
+	// zones that have to succeed no matter what MinZones is
+	RequiredZones []string
+	// only deploy what the zones are missing, e.g. to retry the zones that failed in an earlier deploy
+	OnlyMissing bool
+	// build the manifest even if one was already built (and cached) for the sha
+	Rebuild bool
+	// the reason a superuser is deploying while the env is frozen
+	OverrideFreeze string
+	// how to choose supervisors (spread, binpack or cost). empty means the app's placement.
+	Placement string
+	// a label selector the supervisors have to match on top of the app's SupervisorSelector
+	Selector string
 }
 
 type ManagerDeployReply struct {
 	Status     string
+	Error      string // why the deploy failed (DeployResult only)
 	Containers []*Container
+	RolledBack bool
+	Plan       map[string][]*PlannedHost // zone -> hosts (DryRun only)
+	Zones      map[string]*ZoneStatus    // zone -> how the deploy went there
+	// the deploy request waiting for approval if the env is protected
+	PendingRequest string
+}
+
+// How a deploy went in one zone
+type ZoneStatus struct {
+	Status     string
+	Error      string
+	Containers uint // # of containers deployed (and kept) in the zone
+}
+
+// A host that a dry run deploy would use. Free and Weight are what ChooseSupervisorsList saw for the host.
+type PlannedHost struct {
+	Host      string
+	Free      uint    // # of containers of this size the host can still fit
+	Weight    float64 // lower is used first
+	Instances uint    // # of containers the deploy would put on the host
+}
+
+// ------------ Deploy Requests ------------
+// A deploy to a protected env that waits for a team admin of the app to approve it
+type DeployRequest struct {
+	ID        string
+	User      string           // who asked for the deploy
+	Arg       ManagerDeployArg // what to deploy (without credentials)
+	Time      int64            // unix time the deploy was requested
+	Status    string           // DeployRequestPending, DeployRequestApproved or DeployRequestDenied
+	DecidedBy string
+	Reason    string // why the deploy was denied
+	TaskID    string // the task that ran the approved deploy
+}
+
+// Used to approve or deny a deploy request
+type ManagerDeployRequestArg struct {
+	ManagerAuthArg
+	ID     string
+	Reason string // deny only
+}
+
+type ManagerDeployRequestReply struct {
+	Status  string
+	Request *DeployRequest
+}
+
+// ApproveDeploy uses ManagerDeployReply
+
+// Used to list the deploy requests of an app and/or env (empty for all)
+type ManagerListDeployRequestsArg struct {
+	ManagerAuthArg
+	App string
+	Env string
+	All bool // include the approved and denied requests
+}
+
+type ManagerListDeployRequestsReply struct {
+	Status   string
+	Requests []*DeployRequest
+}
+
+// ------------ ScheduleDeploy ------------
+// A deploy that one of the managers starts at Time
+type ScheduledDeploy struct {
+	ID          string
+	User        string           // who scheduled the deploy
+	Arg         ManagerDeployArg // what to deploy (without credentials)
+	Time        int64            // unix time to start the deploy at
+	Status      string           // ScheduledDeployPending, Started, Failed or Cancelled
+	Manager     string           // the manager that started the deploy
+	TaskID      string           // the Deploy task that was started
+	Error       string           // why the deploy could not be started
+	CancelledBy string
+}
+
+type ManagerScheduleDeployArg struct {
+	ManagerDeployArg
+	Time int64 // unix time to start the deploy at
+}
+
+// Used to cancel a scheduled deploy
+type ManagerScheduledDeployArg struct {
+	ManagerAuthArg
+	ID string
+}
+
+type ManagerScheduledDeployReply struct {
+	Status   string
+	Schedule *ScheduledDeploy
+}
+
+type ManagerListScheduledDeploysArg struct {
+	ManagerAuthArg
+	App string // "" for any
+	Env string // "" for any
+	All bool   // include the deploys that were started or cancelled
+}
+
+type ManagerListScheduledDeploysReply struct {
+	Status    string
+	Schedules []*ScheduledDeploy
+}
+
+// ------------ Webhooks ------------
+// A URL that is POSTed a JSON event when a Deploy, Teardown, RegisterApp, ... task starts, finishes or fails
+type Webhook struct {
+	ID   string
+	URL  string
+	App  string // only tasks for this app ("" for every task)
+	User string // who added the webhook
+}
+
+// Used to add a webhook (ID is filled in) or delete one (only ID is needed)
+type ManagerWebhookArg struct {
+	ManagerAuthArg
+	Webhook Webhook
+}
+
+type ManagerWebhookReply struct {
+	Status  string
+	Webhook *Webhook
+}
+
+type ManagerListWebhooksArg struct {
+	ManagerAuthArg
+	App string // "" for every webhook
 }
 
+type ManagerListWebhooksReply struct {
+	Status   string
+	Webhooks []*Webhook
+}
+
+// ------------ ProtectEnv ------------
+// Used to require (or stop requiring) approval for deploys to an env
+type ManagerProtectEnvArg struct {
+	ManagerAuthArg
+	Env       string
+	Protected bool
+}
+
+// ProtectEnv uses ManagerEnvReply
+
+// ------------ DeployManifest ------------
+// Used to deploy a manifest that was built outside of the manager (in the format the builder produces). App is
+// taken from the manifest if it is empty.
+type ManagerDeployManifestArg struct {
+	ManagerDeployArg
+	Manifest string
+}
+
+// DeployManifest uses ManagerDeployReply
+
 // ------------ DeployContainer ------------
 // Used to deploy by replicating a container 1+ times to arbitrary hosts in every zone
 type ManagerDeployContainerArg struct {
 	ManagerAuthArg
 	ContainerID string
 	Instances   uint
+	DryRun      bool
+	// the reason a superuser is deploying while the env is frozen
+	OverrideFreeze string
 }
 
 // DeployContainer uses ManagerDeployReply
@@ -482,6 +776,9 @@ type ManagerCopyContainerArg struct {
 	ContainerID string
 	ToHost      string
 	PostCopy    int
+	DryRun      bool
+	// the reason a superuser is copying while the env is frozen
+	OverrideFreeze string
 }
 
 const (
@@ -506,6 +803,174 @@ type ManagerResolveDepsReply struct {
 	Deps   map[string]DepsType
 }
 
+// ------------ CancelTask ------------
+// Used to stop a running async task at its next safe point
+type ManagerCancelTaskArg struct {
+	ManagerAuthArg
+	ID string
+}
+
+type ManagerCancelTaskReply struct {
+	Status string
+}
+
+// ------------ CanarySetWeight ------------
+// Used to send a percentage of an app+env's traffic to a canary sha (0 to stop the canary)
+type ManagerCanaryArg struct {
+	ManagerAuthArg
+	App    string
+	Sha    string
+	Env    string
+	Weight uint // percent
+}
+
+type ManagerCanaryReply struct {
+	Status string
+	Canary *Canary
+}
+
+// ------------ ListDeployHistory ------------
+// Used to list the deploys and teardowns of an app in an environment, newest first
+type DeployRecord struct {
+	ID          string
+	TaskID      string
+	Action      string // Deploy, Rollback, Scale or Teardown
+	User        string
+	App         string
+	Sha         string
+	Env         string
+	Time        int64 // unix seconds
+	CPUShares   uint
+	MemoryLimit uint
+	Instances   uint
+	Zones       map[string]uint `json:",omitempty"` // zone -> instances, if the action recorded its layout
+	Status      string
+	Error       string
+}
+
+type ManagerListDeployHistoryArg struct {
+	ManagerAuthArg
+	App   string
+	Env   string
+	Limit uint // 0 for all
+}
+
+type ManagerListDeployHistoryReply struct {
+	Status  string
+	History []*DeployRecord
+}
+
+// ------------ Scale ------------
+// Used to add or remove containers of an app+sha+env until each zone has the target # of them
+type ManagerScaleArg struct {
+	ManagerAuthArg
+	App       string
+	Sha       string
+	Env       string
+	Instances uint            // target # of containers in each zone the app+sha+env was deployed to
+	Zones     map[string]uint // zone -> target # of containers (0 removes the zone). overrides Instances
+	// the reason a superuser is scaling while the env is frozen
+	OverrideFreeze string
+}
+
+type ManagerScaleReply struct {
+	Status  string
+	Added   []*Container
+	Removed []string
+}
+
+// ------------ Rollback ------------
+// Used to redeploy a sha that previously served an app+env and swap traffic back to it
+type Release struct {
+	Sha      string
+	Time     int64 // unix seconds
+	Manifest *Manifest
+}
+
+type ManagerRollbackArg struct {
+	ManagerAuthArg
+	App      string
+	Env      string
+	Sha      string // the release to roll back to (empty for the one before the current one)
+	Teardown bool   // if true, tear down the current sha once traffic is swapped
+	// the reason a superuser is rolling back while the env is frozen
+	OverrideFreeze string
+}
+
+// Rollback uses ManagerDeployReply
+
+// ------------ Promote ------------
+// Used to deploy the sha serving From (with the same manifest) to To
+type ManagerPromoteArg struct {
+	ManagerAuthArg
+	App     string
+	From    string
+	To      string
+	Replace bool // swap traffic to the sha and tear down the other shas in To
+	DryRun  bool
+	// the reason a superuser is deploying while To is frozen
+	OverrideFreeze string
+}
+
+// Promote uses ManagerDeployReply
+
+// Used to set (or with an empty From, delete) the promotion rule of an app+env
+type ManagerPromotionRuleArg struct {
+	ManagerAuthArg
+	App  string
+	Env  string
+	Rule PromotionRule
+}
+
+type ManagerPromotionRuleReply struct {
+	Status string
+	Rules  map[string]*PromotionRule // env -> rule
+}
+
+// ------------ Release ------------
+// One app+sha of a release bundle
+type ReleaseDeploy struct {
+	App string
+	Sha string
+}
+
+// Used to deploy several apps to one env in the order their dependencies need. Every app is deployed with the
+// settings of the embedded ManagerDeployArg (its App and Sha are ignored).
+type ManagerReleaseArg struct {
+	ManagerDeployArg
+	Deploys []ReleaseDeploy
+}
+
+// What happened to one app of a release bundle
+type ReleaseDeployResult struct {
+	ReleaseDeploy
+	TaskID     string // the Deploy task of the app
+	Status     string
+	Error      string
+	Containers []*Container
+}
+
+type ManagerReleaseReply struct {
+	Status  string
+	Error   string                 // why the release stopped
+	Order   []string               // the apps in the order they are deployed
+	Deploys []*ReleaseDeployResult // the deploys that were started, in order
+}
+
+// ------------ Affinity Rules ------------
+// Used to set (or with Remove, delete) the affinity rule of App with Rule.App
+type ManagerAffinityRuleArg struct {
+	ManagerAuthArg
+	App    string
+	Rule   AffinityRule
+	Remove bool
+}
+
+type ManagerAffinityRuleReply struct {
+	Status string
+	Rules  map[string]*AffinityRule // other app -> rule
+}
+
 // ------------ Teardown ------------
 // Teardown containers by app, app+sha, app+sha+container, or just simply all
 type ManagerTeardownArg struct {
@@ -515,6 +980,8 @@ type ManagerTeardownArg struct {
 	Env         string
 	ContainerID string
 	All         bool
+	// the reason a superuser is tearing down while the env is frozen
+	OverrideFreeze string
 }
 
 type ManagerTeardownReply struct {
@@ -0,0 +1,287 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package rpc
+
+import (
+	. "atlantis/common"
+	. "atlantis/manager/constant"
+	"atlantis/manager/datamodel"
+	. "atlantis/manager/rpc/types"
+	"atlantis/manager/webhook"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// what every executor passed to NewTask implements
+type taskExecutor interface {
+	Request() interface{}
+	Result() interface{}
+	Description() string
+	Authorize() error
+	Execute(t *Task) error
+}
+
+// Wraps an executor so the webhooks for its app are told when its task starts, finishes or fails
+type webhookExecutor struct {
+	taskExecutor
+}
+
+func withWebhooks(executor taskExecutor) *webhookExecutor {
+	return &webhookExecutor{executor}
+}
+
+func (e *webhookExecutor) Execute(t *Task) error {
+	app := taskApp(e.Request())
+	sendWebhooks(app, &webhook.Event{
+		Event:       webhook.EventStarted,
+		TaskID:      t.ID,
+		Name:        t.Name,
+		Description: e.Description(),
+		Time:        time.Now().Unix(),
+	})
+	err := e.taskExecutor.Execute(t)
+	event := &webhook.Event{
+		Event:       webhook.EventFinished,
+		TaskID:      t.ID,
+		Name:        t.Name,
+		Description: e.Description(),
+		Status:      StatusOk,
+		Result:      e.Result(),
+		Time:        time.Now().Unix(),
+	}
+	if err != nil {
+		event.Event = webhook.EventFailed
+		event.Status = StatusError
+		event.Error = err.Error()
+		if _, ok := err.(CancelledError); ok {
+			event.Status = StatusCancelled
+		}
+	}
+	if app == "" {
+		// e.g. RegisterApp creates the app, so it may only be known now
+		app = taskApp(e.Request())
+	}
+	sendWebhooks(app, event)
+	return err
+}
+
+// the app a task acts on, or "" if there isn't one (or it can't be found)
+func taskApp(request interface{}) string {
+	switch arg := request.(type) {
+	case ManagerDeployArg:
+		return arg.App
+	case ManagerDeployManifestArg:
+		return arg.App
+	case ManagerScaleArg:
+		return arg.App
+	case ManagerRollbackArg:
+		return arg.App
+	case ManagerPromoteArg:
+		return arg.App
+	case ManagerRegisterAppArg:
+		return arg.Name
+	case ManagerTeardownArg:
+		if arg.ContainerID == "" {
+			return arg.App
+		}
+		return instanceApp(arg.ContainerID)
+	case ManagerDeployContainerArg:
+		return instanceApp(arg.ContainerID)
+	case ManagerCopyContainerArg:
+		return instanceApp(arg.ContainerID)
+	case ManagerDeployRequestArg:
+		if request, err := datamodel.GetDeployRequest(arg.ID); err == nil {
+			return request.Arg.App
+		}
+	}
+	return ""
+}
+
+func instanceApp(id string) string {
+	if inst, err := datamodel.GetInstance(id); err == nil {
+		return inst.App
+	}
+	return ""
+}
+
+// Sends event to the global webhooks and the ones for app
+func sendWebhooks(app string, event *webhook.Event) {
+	webhooks, err := datamodel.ListWebhooks(app, true)
+	if err != nil {
+		log.Printf("[Webhook] ERROR: could not list webhooks for %s: %s", event.TaskID, err.Error())
+		return
+	}
+	for _, hook := range webhooks {
+		webhook.Send(hook.URL, event)
+	}
+}
+
+// ----------------------------------------------------------------------------------------------------------
+// Add, Delete, List Webhooks
+// ----------------------------------------------------------------------------------------------------------
+
+// webhooks for every app can only be managed by superusers, the ones for an app by anyone who can access it
+func authorizeWebhook(auth *ManagerAuthArg, app string) error {
+	if err := checkRole("deploys", "write"); err != nil {
+		return err
+	}
+	if app == "" {
+		return AuthorizeSuperUser(auth)
+	}
+	return AuthorizeApp(auth, app)
+}
+
+type AddWebhookExecutor struct {
+	arg   ManagerWebhookArg
+	reply *ManagerWebhookReply
+}
+
+func (e *AddWebhookExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *AddWebhookExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *AddWebhookExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s for app '%s'", e.arg.Webhook.URL, e.arg.Webhook.App)
+}
+
+func (e *AddWebhookExecutor) Execute(t *Task) error {
+	hook := e.arg.Webhook
+	if !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://") {
+		return errors.New("Please specify an http:// or https:// URL")
+	}
+	if hook.App != "" {
+		if _, err := datamodel.GetApp(hook.App); err != nil {
+			return errors.New(fmt.Sprintf("App %s is not registered", hook.App))
+		}
+	}
+	hook.User = e.arg.ManagerAuthArg.User
+	if err := datamodel.AddWebhook(&hook); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	e.reply.Webhook = &hook
+	return nil
+}
+
+func (e *AddWebhookExecutor) Authorize() error {
+	return authorizeWebhook(&e.arg.ManagerAuthArg, e.arg.Webhook.App)
+}
+
+type DeleteWebhookExecutor struct {
+	arg   ManagerWebhookArg
+	reply *ManagerWebhookReply
+}
+
+func (e *DeleteWebhookExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *DeleteWebhookExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *DeleteWebhookExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] %s", e.arg.Webhook.ID)
+}
+
+func (e *DeleteWebhookExecutor) Execute(t *Task) error {
+	hook, err := datamodel.GetWebhook(e.arg.Webhook.ID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Webhook %s does not exist", e.arg.Webhook.ID))
+	}
+	if err := datamodel.DeleteWebhook(hook.ID); err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	stopUnusedWebhook(hook.URL)
+	e.reply.Status = StatusOk
+	e.reply.Webhook = hook
+	return nil
+}
+
+// stops the delivery queue for url unless another webhook still uses it
+func stopUnusedWebhook(url string) {
+	webhooks, err := datamodel.ListWebhooks("", true)
+	if err != nil {
+		return
+	}
+	for _, hook := range webhooks {
+		if hook.URL == url {
+			return
+		}
+	}
+	webhook.Stop(url)
+}
+
+func (e *DeleteWebhookExecutor) Authorize() error {
+	if e.arg.Webhook.ID == "" {
+		return errors.New("Please specify a webhook ID")
+	}
+	hook, err := datamodel.GetWebhook(e.arg.Webhook.ID)
+	if err != nil {
+		// let Execute report the missing webhook
+		return SimpleAuthorize(&e.arg.ManagerAuthArg)
+	}
+	return authorizeWebhook(&e.arg.ManagerAuthArg, hook.App)
+}
+
+type ListWebhooksExecutor struct {
+	arg   ManagerListWebhooksArg
+	reply *ManagerListWebhooksReply
+}
+
+func (e *ListWebhooksExecutor) Request() interface{} {
+	return e.arg
+}
+
+func (e *ListWebhooksExecutor) Result() interface{} {
+	return e.reply
+}
+
+func (e *ListWebhooksExecutor) Description() string {
+	return fmt.Sprintf("["+e.arg.ManagerAuthArg.User+"] app '%s'", e.arg.App)
+}
+
+func (e *ListWebhooksExecutor) Execute(t *Task) error {
+	webhooks, err := datamodel.ListWebhooks(e.arg.App, e.arg.App == "")
+	if err != nil {
+		e.reply.Status = StatusError
+		return err
+	}
+	e.reply.Status = StatusOk
+	e.reply.Webhooks = webhooks
+	return nil
+}
+
+func (e *ListWebhooksExecutor) Authorize() error {
+	return authorizeWebhook(&e.arg.ManagerAuthArg, e.arg.App)
+}
+
+func (m *ManagerRPC) AddWebhook(arg ManagerWebhookArg, reply *ManagerWebhookReply) error {
+	return NewTask("AddWebhook", &AddWebhookExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) DeleteWebhook(arg ManagerWebhookArg, reply *ManagerWebhookReply) error {
+	return NewTask("DeleteWebhook", &DeleteWebhookExecutor{arg, reply}).Run()
+}
+
+func (m *ManagerRPC) ListWebhooks(arg ManagerListWebhooksArg, reply *ManagerListWebhooksReply) error {
+	return NewTask("ListWebhooks", &ListWebhooksExecutor{arg, reply}).Run()
+}
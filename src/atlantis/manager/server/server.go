@@ -22,6 +22,7 @@ import (
 	"atlantis/manager/ldap"
 	"atlantis/manager/rpc"
 	"atlantis/manager/smtp"
+	"atlantis/manager/webhook"
 	iconst "atlantis/supervisor/constant"
 	"errors"
 	"fmt"
@@ -31,6 +32,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -74,6 +76,17 @@ type ServerConfig struct {
 	SMTPAddr                   string `toml:"smtp_addr"`
 	SMTPFrom                   string `toml:"smtp_from"`
 	SMTPCC                     string `toml:"smtp_cc"`
+	HealthzTimeout             string `toml:"healthz_timeout"`
+	MinHealthyPercent          uint   `toml:"min_healthy_percent"`
+	DeployHostTimeout          string `toml:"deploy_host_timeout"`
+	DeployHostRetries          uint   `toml:"deploy_host_retries"`
+	DeployRetryBackoff         string `toml:"deploy_retry_backoff"`
+	WebhookRetries             uint   `toml:"webhook_retries"`
+	WebhookRetryBackoff        string `toml:"webhook_retry_backoff"`
+	WebhookDeadLetterFile      string `toml:"webhook_dead_letter_file"`
+	ScheduleCheckInterval      string `toml:"schedule_check_interval"`
+	HeartbeatInterval          string `toml:"heartbeat_interval"`
+	MaxMissedHeartbeats        uint   `toml:"max_missed_heartbeats"`
 }
 
 type ServerOpts struct {
@@ -102,6 +115,17 @@ type ServerOpts struct {
 	SMTPAddr                   string `long:"smtp-addr"`
 	SMTPFrom                   string `long:"smtp-from"`
 	SMTPCC                     string `long:"smtp-cc"`
+	HealthzTimeout             string `long:"healthz-timeout" description:"how long a deploy waits for containers to be healthy (0 to not wait)"`
+	MinHealthyPercent          string `long:"min-healthy-percent" description:"percent of containers that must be healthy for a deploy to succeed"`
+	DeployHostTimeout          string `long:"deploy-host-timeout" description:"how long to wait for a supervisor to deploy a container in whole seconds (0 to wait forever)"`
+	DeployHostRetries          uint   `long:"deploy-host-retries" description:"how many times a deploy retries the containers that failed in a zone"`
+	DeployRetryBackoff         string `long:"deploy-retry-backoff" description:"how long to wait before the first retry (doubles every retry)"`
+	WebhookRetries             uint   `long:"webhook-retries" description:"how many times a webhook delivery is retried"`
+	WebhookRetryBackoff        string `long:"webhook-retry-backoff" description:"how long to wait before the first webhook retry (doubles every retry)"`
+	WebhookDeadLetterFile      string `long:"webhook-dead-letter-file" description:"where webhooks that could not be delivered are written"`
+	ScheduleCheckInterval      string `long:"schedule-check-interval" description:"the interval to check for scheduled deploys that are due"`
+	HeartbeatInterval          string `long:"heartbeat-interval" description:"the interval to health check every supervisor and record its inventory"`
+	MaxMissedHeartbeats        uint   `long:"max-missed-heartbeats" description:"how many heartbeats in a row a supervisor can miss before it is down"`
 }
 
 type ManagerServer struct {
@@ -138,6 +162,17 @@ func New() *ManagerServer {
 			SMTPAddr:                   "",
 			SMTPFrom:                   "",
 			SMTPCC:                     "",
+			HealthzTimeout:             DefaultHealthzTimeout,
+			MinHealthyPercent:          DefaultMinHealthyPercent,
+			DeployHostTimeout:          DefaultDeployHostTimeout,
+			DeployHostRetries:          DefaultDeployHostRetries,
+			DeployRetryBackoff:         DefaultDeployRetryBackoff,
+			WebhookRetries:             DefaultWebhookRetries,
+			WebhookRetryBackoff:        DefaultWebhookRetryBackoff,
+			WebhookDeadLetterFile:      "",
+			ScheduleCheckInterval:      DefaultScheduleCheckInterval,
+			HeartbeatInterval:          DefaultHeartbeatInterval,
+			MaxMissedHeartbeats:        DefaultMaxMissedHeartbeats,
 		},
 	}
 	manager.parser.Parse()
@@ -178,6 +213,31 @@ func (m *ManagerServer) Run(bldr builder.Builder) {
 	if err != nil {
 		panic(fmt.Sprintf("Could not parse Result Duration: %s", err.Error()))
 	}
+	if rpc.HealthzTimeout, err = time.ParseDuration(m.Config.HealthzTimeout); err != nil {
+		panic(fmt.Sprintf("Could not parse Healthz Timeout: %s", err.Error()))
+	}
+	if m.Config.MinHealthyPercent > 100 {
+		panic(fmt.Sprintf("Min Healthy Percent must be between 0 and 100: %d", m.Config.MinHealthyPercent))
+	}
+	rpc.MinHealthyPercent = m.Config.MinHealthyPercent
+	if rpc.DeployHostTimeout, err = time.ParseDuration(m.Config.DeployHostTimeout); err != nil {
+		panic(fmt.Sprintf("Could not parse Deploy Host Timeout: %s", err.Error()))
+	}
+	if rpc.DeployHostTimeout%time.Second != 0 {
+		// supervisors take the timeout in seconds
+		panic(fmt.Sprintf("Deploy Host Timeout must be 0 or a whole number of seconds: %s",
+			m.Config.DeployHostTimeout))
+	}
+	if rpc.DeployRetryBackoff, err = time.ParseDuration(m.Config.DeployRetryBackoff); err != nil {
+		panic(fmt.Sprintf("Could not parse Deploy Retry Backoff: %s", err.Error()))
+	}
+	rpc.DeployHostRetries = m.Config.DeployHostRetries
+	rpc.MaxMissedHeartbeats = m.Config.MaxMissedHeartbeats
+	webhookRetryBackoff, err := time.ParseDuration(m.Config.WebhookRetryBackoff)
+	if err != nil {
+		panic(fmt.Sprintf("Could not parse Webhook Retry Backoff: %s", err.Error()))
+	}
+	webhook.Init(m.Config.WebhookRetries, webhookRetryBackoff, m.Config.WebhookDeadLetterFile)
 	handleError(rpc.Init(m.Config.RpcAddr, m.Config.SupervisorPort, m.Config.CPUSharesIncrement,
 		m.Config.MemoryLimitIncrement, resultDuration))
 	handleError(api.Init(m.Config.ApiAddr))
@@ -193,8 +253,18 @@ func (m *ManagerServer) Run(bldr builder.Builder) {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	scheduleCheckInterval, err := time.ParseDuration(m.Config.ScheduleCheckInterval)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	heartbeatInterval, err := time.ParseDuration(m.Config.HeartbeatInterval)
+	if err != nil {
+		log.Fatalln(err)
+	}
 	MaintenanceChecker(m.Config.MaintenanceFile, maintenanceCheckInterval)
 	rpc.SuperUserOnlyChecker(m.Config.SuperUserOnlyFile, superUserCheckInterval)
+	rpc.DeployScheduler(scheduleCheckInterval)
+	rpc.SupervisorHeartbeats(heartbeatInterval)
 	go signalListener()
 	go rpc.Listen()
 	api.Listen()
@@ -283,6 +353,44 @@ func (m *ManagerServer) overlayConfig() {
 	if m.Opts.SMTPCC != "" {
 		m.Config.SMTPCC = m.Opts.SMTPCC
 	}
+	if m.Opts.HealthzTimeout != "" {
+		m.Config.HealthzTimeout = m.Opts.HealthzTimeout
+	}
+	if m.Opts.MinHealthyPercent != "" {
+		// parsed here instead of as a uint flag so that 0 can be set
+		percent, err := strconv.ParseUint(m.Opts.MinHealthyPercent, 10, 0)
+		if err != nil {
+			panic(fmt.Sprintf("Could not parse Min Healthy Percent: %s", err.Error()))
+		}
+		m.Config.MinHealthyPercent = uint(percent)
+	}
+	if m.Opts.DeployHostTimeout != "" {
+		m.Config.DeployHostTimeout = m.Opts.DeployHostTimeout
+	}
+	if m.Opts.DeployHostRetries != 0 {
+		m.Config.DeployHostRetries = m.Opts.DeployHostRetries
+	}
+	if m.Opts.DeployRetryBackoff != "" {
+		m.Config.DeployRetryBackoff = m.Opts.DeployRetryBackoff
+	}
+	if m.Opts.WebhookRetries != 0 {
+		m.Config.WebhookRetries = m.Opts.WebhookRetries
+	}
+	if m.Opts.WebhookRetryBackoff != "" {
+		m.Config.WebhookRetryBackoff = m.Opts.WebhookRetryBackoff
+	}
+	if m.Opts.WebhookDeadLetterFile != "" {
+		m.Config.WebhookDeadLetterFile = m.Opts.WebhookDeadLetterFile
+	}
+	if m.Opts.ScheduleCheckInterval != "" {
+		m.Config.ScheduleCheckInterval = m.Opts.ScheduleCheckInterval
+	}
+	if m.Opts.HeartbeatInterval != "" {
+		m.Config.HeartbeatInterval = m.Opts.HeartbeatInterval
+	}
+	if m.Opts.MaxMissedHeartbeats != 0 {
+		m.Config.MaxMissedHeartbeats = m.Opts.MaxMissedHeartbeats
+	}
 }
 
 func (m *ManagerServer) LDAPInit() error {
@@ -12,9 +12,9 @@
 package status
 
 import (
+	. "atlantis/manager/constant"
 	"atlantis/manager/datamodel"
 	. "atlantis/manager/rpc/types"
-	"atlantis/manager/supervisor"
 	"strconv"
 )
 
@@ -25,8 +25,8 @@ func toPrice(price float64) float64 {
 
 func GetUsage() (map[string]*SupervisorUsage, error) {
 	// for each supervisor
-	//   get health check to figure out total CPUShares, Memory, Price
-	//   get list of containers
+	//   get its inventory to figure out total CPUShares, Memory, Price (all unknown without one)
+	//   get the containers we put on it
 	//   fill in data in SupervisorUsage
 	supers, err := datamodel.ListSupervisors()
 	if err != nil {
@@ -35,33 +35,44 @@ func GetUsage() (map[string]*SupervisorUsage, error) {
 	usageMap := map[string]*SupervisorUsage{}
 	for _, super := range supers {
 		usage := &SupervisorUsage{Containers: map[string]*ContainerUsage{}}
-		hreply, err := supervisor.HealthCheck(super)
+		inventory, err := datamodel.GetSupervisorInventory(super)
 		if err != nil {
-			return nil, err
+			// no heartbeat recorded yet
+			inventory = &SupervisorInventory{State: SupervisorUnknown}
 		}
 		usage.Host = super
-		price := hreply.Price
-		total_cpu := hreply.CPUShares.Total
-		total_mem := hreply.Memory.Total
+		usage.State = inventory.State
+		usage.LastSeen = inventory.LastSeen
+		price := inventory.Price
+		total_cpu := inventory.TotalCPUShares
+		total_mem := inventory.TotalMemory
 		usage.TotalPrice = price
-		usage.TotalContainers = hreply.Containers.Total
+		usage.TotalContainers = inventory.TotalContainers
 		usage.TotalCPUShares = total_cpu
 		usage.TotalMemory = total_mem
-		lreply, err := supervisor.List(super)
+		info, err := datamodel.Supervisor(super).Info()
 		if err != nil {
-			return nil, err
+			usageMap[super] = usage // its containers are unknown too
+			continue
 		}
 		var conts uint = 0
 		var cpu uint = 0
 		var mem uint = 0
 		var cpu_price float64 = 0.0
 		var mem_price float64 = 0.0
-		for id, cont := range lreply.Containers {
+		for id, _ := range info.PortMap {
+			cont, err := datamodel.GetInstance(id)
+			if err != nil || cont.Manifest == nil {
+				continue
+			}
 			conts += 1
 			cpu += cont.Manifest.CPUShares
 			mem += cont.Manifest.MemoryLimit
-			c := price * (float64(cont.Manifest.CPUShares) / float64(total_cpu))
-			m := price * (float64(cont.Manifest.MemoryLimit) / float64(total_mem))
+			c, m := 0.0, 0.0
+			if total_cpu > 0 && total_mem > 0 {
+				c = price * (float64(cont.Manifest.CPUShares) / float64(total_cpu))
+				m = price * (float64(cont.Manifest.MemoryLimit) / float64(total_mem))
+			}
 			cpu_price += c
 			mem_price += m
 			usage.Containers[id] = &ContainerUsage{
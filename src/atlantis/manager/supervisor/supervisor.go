@@ -14,10 +14,22 @@ package supervisor
 import (
 	. "atlantis/supervisor/rpc/client"
 	. "atlantis/supervisor/rpc/types"
+	"errors"
+	"fmt"
+	"time"
 )
 
 var Port string
 
+// how many supervisors HealthCheckAll checks at once
+var HealthCheckConcurrency = 20
+
+// how long HealthCheckAll waits for all of its supervisors to answer
+var HealthCheckDeadline = 15 * time.Second
+
+// the health check HealthCheckAll makes (swapped out by tests)
+var healthCheck = HealthCheck
+
 func Init(port string) {
 	Port = port
 }
@@ -28,6 +40,14 @@ func Deploy(host, app, sha, env, container string, man *Manifest) (*SupervisorDe
 	return &reply, NewSupervisorRPCClient(host+":"+Port).Call("Deploy", args, &reply)
 }
 
+// like Deploy but gives up after timeout seconds
+func DeployWithTimeout(host, app, sha, env, container string, man *Manifest,
+	timeout int) (*SupervisorDeployReply, error) {
+	args := SupervisorDeployArg{Host: host, App: app, Sha: sha, Env: env, ContainerID: container, Manifest: man}
+	var reply SupervisorDeployReply
+	return &reply, NewSupervisorRPCClient(host+":"+Port).CallWithTimeout("Deploy", args, &reply, timeout)
+}
+
 func Teardown(host string, containerIDs []string, all bool) (*SupervisorTeardownReply, error) {
 	args := SupervisorTeardownArg{containerIDs, all}
 	var reply SupervisorTeardownReply
@@ -40,6 +60,54 @@ func HealthCheck(host string) (*SupervisorHealthCheckReply, error) {
 	return &reply, NewSupervisorRPCClient(host+":"+Port).CallWithTimeout("HealthCheck", args, &reply, 5)
 }
 
+// The health of one supervisor from HealthCheckAll
+type HealthCheckResult struct {
+	Reply *SupervisorHealthCheckReply
+	Err   error
+}
+
+// Checks hosts in parallel, HealthCheckConcurrency at a time. the hosts that have not answered by
+// HealthCheckDeadline get an error.
+func HealthCheckAll(hosts []string) map[string]*HealthCheckResult {
+	type answer struct {
+		host   string
+		result *HealthCheckResult
+	}
+	answers := make(chan answer, len(hosts))
+	slots := make(chan bool, HealthCheckConcurrency)
+	stop := make(chan bool)
+	for _, host := range hosts {
+		go func(host string) {
+			select {
+			case slots <- true:
+			case <-stop:
+				return // too late to bother
+			}
+			reply, err := healthCheck(host)
+			<-slots
+			answers <- answer{host, &HealthCheckResult{reply, err}}
+		}(host)
+	}
+	results := make(map[string]*HealthCheckResult, len(hosts))
+	deadline := time.After(HealthCheckDeadline)
+	for received := 0; received < len(hosts); received++ {
+		select {
+		case a := <-answers:
+			results[a.host] = a.result
+		case <-deadline:
+			close(stop)
+			err := errors.New(fmt.Sprintf("no answer within %s", HealthCheckDeadline))
+			for _, host := range hosts {
+				if _, ok := results[host]; !ok {
+					results[host] = &HealthCheckResult{Err: err}
+				}
+			}
+			return results
+		}
+	}
+	return results
+}
+
 func GetZone(host string) (string, error) {
 	hReply, err := HealthCheck(host)
 	return hReply.Zone, err
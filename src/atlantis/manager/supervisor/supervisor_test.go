@@ -0,0 +1,70 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package supervisor
+
+import (
+	. "atlantis/supervisor/rpc/types"
+	"errors"
+	"fmt"
+	. "launchpad.net/gocheck"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSupervisor(t *testing.T) { TestingT(t) }
+
+type SupervisorSuite struct{}
+
+var _ = Suite(&SupervisorSuite{})
+
+// a health check that counts how often each host is asked, takes delay[host] to answer and fails for "dead"
+type fakeHealthCheck struct {
+	sync.Mutex
+	calls map[string]int
+	delay map[string]time.Duration
+}
+
+func (f *fakeHealthCheck) check(host string) (*SupervisorHealthCheckReply, error) {
+	f.Lock()
+	f.calls[host]++
+	delay := f.delay[host]
+	f.Unlock()
+	time.Sleep(delay)
+	if host == "dead" {
+		return nil, errors.New("connection refused")
+	}
+	return &SupervisorHealthCheckReply{Zone: host + "-zone"}, nil
+}
+
+func (s *SupervisorSuite) TearDownTest(c *C) {
+	healthCheck = HealthCheck
+	HealthCheckDeadline = 15 * time.Second
+}
+
+func (s *SupervisorSuite) TestHealthCheckAll(c *C) {
+	fake := &fakeHealthCheck{calls: map[string]int{}, delay: map[string]time.Duration{"hung": time.Second}}
+	healthCheck = fake.check
+	HealthCheckDeadline = 100 * time.Millisecond
+	hosts := []string{"dead", "hung"}
+	for i := 0; i < 50; i++ {
+		hosts = append(hosts, fmt.Sprintf("host%d", i))
+	}
+	start := time.Now()
+	results := HealthCheckAll(hosts)
+	c.Assert(time.Since(start) < time.Second, Equals, true)
+	c.Assert(len(results), Equals, len(hosts))
+	c.Assert(results["host0"].Err, IsNil)
+	c.Assert(results["host0"].Reply.Zone, Equals, "host0-zone")
+	c.Assert(results["dead"].Err, Not(IsNil))
+	c.Assert(results["hung"].Err, Not(IsNil))
+}
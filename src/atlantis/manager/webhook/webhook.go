@@ -0,0 +1,166 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EventStarted  = "started"
+	EventFinished = "finished"
+	EventFailed   = "failed"
+)
+
+// what is POSTed (as JSON) to a webhook
+type Event struct {
+	Event       string // EventStarted, EventFinished or EventFailed
+	TaskID      string
+	Name        string // the task's name, e.g. Deploy
+	Description string
+	Status      string
+	Error       string      `json:",omitempty"`
+	Result      interface{} `json:",omitempty"`
+	Time        int64       // unix time of the event
+}
+
+// what is written to the dead letter file (one JSON object per line) when a webhook can't be delivered
+type DeadLetter struct {
+	URL   string
+	Error string
+	Event *Event
+}
+
+var (
+	// deliveries are retried this many times, waiting RetryBackoff (doubled every retry) in between
+	Retries      = uint(3)
+	RetryBackoff = 5 * time.Second
+	Timeout      = 10 * time.Second
+	// if empty, dead letters are only logged
+	DeadLetterFile = ""
+	// the # of events that can wait to be delivered to one URL before new ones go to the dead letters
+	QueueSize = 1000
+
+	queues     = map[string]chan *Event{}
+	queueMutex = &sync.Mutex{}
+	deadMutex  = &sync.Mutex{}
+)
+
+func Init(retries uint, retryBackoff time.Duration, deadLetterFile string) {
+	Retries = retries
+	RetryBackoff = retryBackoff
+	DeadLetterFile = deadLetterFile
+}
+
+// Queues event for delivery to url. events to the same url are delivered in the order they were sent.
+func Send(url string, event *Event) {
+	queueMutex.Lock()
+	queue, ok := queues[url]
+	if !ok {
+		queue = make(chan *Event, QueueSize)
+		queues[url] = queue
+		go deliverQueue(url, queue)
+	}
+	// sent while locked so Stop can't close the queue out from under us
+	full := false
+	select {
+	case queue <- event:
+	default:
+		full = true
+	}
+	queueMutex.Unlock()
+	if full {
+		deadLetter(url, event, errors.New("too many events waiting to be delivered"))
+	}
+}
+
+// Stops delivering to url once the events already queued for it are delivered. call it when no webhook uses url.
+func Stop(url string) {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	if queue, ok := queues[url]; ok {
+		close(queue)
+		delete(queues, url)
+	}
+}
+
+func deliverQueue(url string, queue chan *Event) {
+	for event := range queue {
+		if err := Deliver(url, event); err != nil {
+			deadLetter(url, event, err)
+		}
+	}
+}
+
+// POSTs event to url, retrying on errors and non-2xx responses. returns the last error if every try failed.
+func Deliver(url string, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	backoff := RetryBackoff
+	for try := uint(0); ; try++ {
+		if err = post(url, body); err == nil {
+			return nil
+		}
+		if try >= Retries {
+			return err
+		}
+		log.Printf("[Webhook] %s for task %s to %s failed (retrying in %s): %s", event.Event, event.TaskID, url,
+			backoff, err.Error())
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func post(url string, body []byte) error {
+	client := &http.Client{Timeout: Timeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New(fmt.Sprintf("%s responded %s", url, resp.Status))
+	}
+	return nil
+}
+
+func deadLetter(url string, event *Event, err error) {
+	log.Printf("[Webhook] ERROR: could not deliver %s for task %s to %s: %s", event.Event, event.TaskID, url,
+		err.Error())
+	if DeadLetterFile == "" {
+		return
+	}
+	line, jsonErr := json.Marshal(&DeadLetter{URL: url, Error: err.Error(), Event: event})
+	if jsonErr != nil {
+		log.Printf("[Webhook] ERROR: could not encode dead letter: %s", jsonErr.Error())
+		return
+	}
+	deadMutex.Lock()
+	defer deadMutex.Unlock()
+	file, fileErr := os.OpenFile(DeadLetterFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if fileErr != nil {
+		log.Printf("[Webhook] ERROR: could not open dead letter file %s: %s", DeadLetterFile, fileErr.Error())
+		return
+	}
+	defer file.Close()
+	file.Write(append(line, '\n'))
+}
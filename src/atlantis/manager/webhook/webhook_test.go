@@ -0,0 +1,150 @@
+/* Copyright 2014 Ooyala, Inc. All rights reserved.
+ *
+ * This file is licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is
+ * distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and limitations under the License.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	. "launchpad.net/gocheck"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) { TestingT(t) }
+
+type WebhookSuite struct{}
+
+var _ = Suite(&WebhookSuite{})
+
+// a receiver that fails the first failures requests and records the events it accepts
+type receiver struct {
+	sync.Mutex
+	failures int
+	requests int
+	events   []*Event
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	r.requests++
+	if r.requests <= r.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	event := &Event{}
+	if err := json.NewDecoder(req.Body).Decode(event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.events = append(r.events, event)
+}
+
+func (r *receiver) received() []*Event {
+	r.Lock()
+	defer r.Unlock()
+	return r.events
+}
+
+func (s *WebhookSuite) SetUpTest(c *C) {
+	Retries = 2
+	RetryBackoff = time.Millisecond
+	DeadLetterFile = ""
+}
+
+func (s *WebhookSuite) TestDeliverRetries(c *C) {
+	recv := &receiver{failures: 2}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	event := &Event{Event: EventFinished, TaskID: "task", Name: "Deploy", Status: "OK",
+		Result: map[string]interface{}{"Containers": []string{"cont"}}}
+	c.Assert(Deliver(server.URL, event), IsNil)
+	c.Assert(recv.requests, Equals, 3)
+	c.Assert(len(recv.received()), Equals, 1)
+	c.Assert(recv.received()[0].TaskID, Equals, "task")
+	c.Assert(recv.received()[0].Event, Equals, EventFinished)
+
+	recv = &receiver{failures: 3}
+	server2 := httptest.NewServer(recv)
+	defer server2.Close()
+	c.Assert(Deliver(server2.URL, event), Not(IsNil))
+	c.Assert(recv.requests, Equals, 3)
+}
+
+func (s *WebhookSuite) TestSendInOrder(c *C) {
+	recv := &receiver{failures: 1}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	Send(server.URL, &Event{Event: EventStarted, TaskID: "task"})
+	Send(server.URL, &Event{Event: EventFinished, TaskID: "task"})
+	for i := 0; i < 100 && len(recv.received()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(len(recv.received()), Equals, 2)
+	c.Assert(recv.received()[0].Event, Equals, EventStarted)
+	c.Assert(recv.received()[1].Event, Equals, EventFinished)
+}
+
+func (s *WebhookSuite) TestStop(c *C) {
+	recv := &receiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	Send(server.URL, &Event{Event: EventStarted, TaskID: "task"})
+	Stop(server.URL)
+	queueMutex.Lock()
+	_, ok := queues[server.URL]
+	queueMutex.Unlock()
+	c.Assert(ok, Equals, false)
+	for i := 0; i < 100 && len(recv.received()) < 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// what was queued before Stop is still delivered
+	c.Assert(len(recv.received()), Equals, 1)
+	Stop(server.URL) // no-op
+	Send(server.URL, &Event{Event: EventFinished, TaskID: "task"})
+	for i := 0; i < 100 && len(recv.received()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(len(recv.received()), Equals, 2)
+	Stop(server.URL)
+}
+
+func (s *WebhookSuite) TestDeadLetter(c *C) {
+	file, err := ioutil.TempFile("", "webhook-dead-letters")
+	c.Assert(err, IsNil)
+	file.Close()
+	defer os.Remove(file.Name())
+	DeadLetterFile = file.Name()
+	recv := &receiver{failures: 100}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	Send(server.URL, &Event{Event: EventFailed, TaskID: "task", Error: "boom"})
+	var data []byte
+	for i := 0; i < 100 && len(data) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, err = ioutil.ReadFile(file.Name())
+		c.Assert(err, IsNil)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	c.Assert(len(lines), Equals, 1)
+	letter := &DeadLetter{}
+	c.Assert(json.Unmarshal([]byte(lines[0]), letter), IsNil)
+	c.Assert(letter.URL, Equals, server.URL)
+	c.Assert(letter.Event.TaskID, Equals, "task")
+	c.Assert(letter.Event.Error, Equals, "boom")
+	c.Assert(recv.requests, Equals, 3)
+}